@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -35,6 +36,71 @@ const (
 	// - if False, it's a cloud test run and it is yet to be finalized
 	// - if True, it's a cloud test run that has been finalized already
 	CloudTestRunFinalized = "CloudTestRunFinalized"
+
+	// PartialFailure indicates if only some of the runners have failed.
+	// - if empty / Unknown, the runners haven't finished yet
+	// - if False, either all runners have succeeded or all of them have failed
+	// - if True, some but not all of the runners have failed
+	PartialFailure = "PartialFailure"
+
+	// K6VersionMismatch indicates if the archive was created by a newer k6
+	// than the one in the image.
+	// - if empty / Unknown, the script is not an archive or it wasn't checked yet
+	// - if False, the version of k6 in the image is fit for the archive
+	// - if True, the archive was created by a newer version of k6
+	K6VersionMismatch = "K6VersionMismatch"
+
+	// RunnerCountMismatch indicates if the number of runners differs from parallelism.
+	// - if empty / Unknown, the runners weren't checked yet
+	// - if False, the number of runners matched parallelism when the test was started
+	// - if True, there are fewer or more runners than parallelism
+	RunnerCountMismatch = "RunnerCountMismatch"
+
+	// ClockSkew indicates if clocks of the runners differ from the clock of the operator.
+	// - if empty / Unknown, the runners weren't checked yet
+	// - if False, the clocks were in sync within a threshold when the test was started
+	// - if True, some runner's clock differs by more than the threshold
+	ClockSkew = "ClockSkew"
+
+	// RunnerBackoff indicates if runner containers can't be started.
+	// - if empty / Unknown, no runner container was in backoff
+	// - if False, runner containers have recovered from backoff
+	// - if True, a runner container is in ImagePullBackOff, CrashLoopBackOff or the like
+	RunnerBackoff = "RunnerBackoff"
+
+	// WebhookDelivered indicates if the result of the test run was posted to the webhook.
+	// - if empty / Unknown, there is no webhook or the test run is not over yet
+	// - if False, the webhook failed on all attempts
+	// - if True, the webhook has received the result
+	WebhookDelivered = "WebhookDelivered"
+
+	// TestRunAborted indicates if the test run was aborted on request.
+	// - if empty / Unknown, the test run wasn't aborted
+	// - if True, the test run was aborted with k6.io/abort annotation and its runners
+	// have stopped: the reason is in phaseReason
+	TestRunAborted = "TestRunAborted"
+
+	// StartupFailed indicates if the runners couldn't be started.
+	// - if empty / Unknown, the runners have started or are yet to start
+	// - if True, the test run has failed on startup and is being stopped: the reason is in phaseReason
+	StartupFailed = "StartupFailed"
+
+	// CloudFinalizeFailed indicates if finalization of k6 Cloud test run has
+	// failed on all attempts.
+	// - if empty / Unknown, it's not a cloud test run or it's not over yet
+	// - if True, the operator has given up on finalization
+	CloudFinalizeFailed = "CloudFinalizeFailed"
+
+	// ThresholdsCollected indicates if thresholds of the runners were read.
+	// - if empty / Unknown, the runners haven't finished yet
+	// - if True, thresholdsPassed and summary are set and are not read again,
+	// even if the runners are still reachable
+	ThresholdsCollected = "ThresholdsCollected"
+
+	// CloudTokenMissing indicates if k6 Cloud token was not found in time.
+	// - if empty / Unknown, it's not a cloud test run or the token was found
+	// - if True, the test run has waited for the token for too long
+	CloudTokenMissing = "CloudTokenMissing"
 )
 
 var reasons = map[string]string{
@@ -53,6 +119,44 @@ var reasons = map[string]string{
 	"CloudTestRunFinalizedUnknown": "CloudTestRunFinalizedUnknown",
 	"CloudTestRunFinalizedTrue":    "CloudTestRunFinalizedTrue",
 	"CloudTestRunFinalizedFalse":   "CloudTestRunFinalizedFalse",
+
+	"PartialFailureTrue":  "PartialFailureTrue",
+	"PartialFailureFalse": "PartialFailureFalse",
+
+	"K6VersionMismatchTrue":  "K6VersionMismatchTrue",
+	"K6VersionMismatchFalse": "K6VersionMismatchFalse",
+
+	"RunnerCountMismatchTrue":  "RunnerCountMismatchTrue",
+	"RunnerCountMismatchFalse": "RunnerCountMismatchFalse",
+
+	"ClockSkewTrue":  "ClockSkewTrue",
+	"ClockSkewFalse": "ClockSkewFalse",
+
+	"RunnerBackoffTrue":  "RunnerBackoffTrue",
+	"RunnerBackoffFalse": "RunnerBackoffFalse",
+
+	"WebhookDeliveredTrue":  "WebhookDeliveredTrue",
+	"WebhookDeliveredFalse": "WebhookDeliveredFalse",
+
+	"CloudFinalizeFailedTrue": "CloudFinalizeFailedTrue",
+
+	"CloudTokenMissingTrue": "CloudTokenMissingTrue",
+
+	"TestRunAbortedTrue": "TestRunAbortedTrue",
+
+	"StartupFailedTrue": "StartupFailedTrue",
+
+	"ThresholdsCollectedTrue": "ThresholdsCollectedTrue",
+}
+
+// phaseOrder ranks Failed above Succeeded, so that a failure found late,
+// e.g. by another writer, is never overwritten by success.
+var phaseOrder = map[Phase]int{
+	"":          0,
+	"Pending":   1,
+	"Running":   2,
+	"Succeeded": 3,
+	"Failed":    4,
 }
 
 // InitializeConditions defines only conditions common to all test runs.
@@ -145,6 +249,7 @@ func (k6status *K6Status) SetIfNewer(proposedStatus K6Status) (isNewer bool) {
 			len(k6status.TestRunID) == 0 &&
 			len(proposedStatus.TestRunID) > 0 {
 			k6status.TestRunID = proposedStatus.TestRunID
+			k6status.TestRunURL = proposedStatus.TestRunURL
 			isNewer = true
 		}
 		// log if proposedStatus.TestRunID is empty here?
@@ -155,6 +260,145 @@ func (k6status *K6Status) SetIfNewer(proposedStatus K6Status) (isNewer bool) {
 		}
 	}
 
+	// Run ID is generated only once per run of the test.
+	if len(proposedStatus.RunID) > 0 && len(k6status.RunID) == 0 {
+		k6status.RunID = proposedStatus.RunID
+		isNewer = true
+	}
+
+	// The start time is set only once, when runners are started.
+	if proposedStatus.StartTime != nil && k6status.StartTime == nil {
+		k6status.StartTime = proposedStatus.StartTime
+		isNewer = true
+	}
+
+	// The completion time is set only once, when the test run has finished.
+	if proposedStatus.CompletionTime != nil && k6status.CompletionTime == nil {
+		k6status.CompletionTime = proposedStatus.CompletionTime
+		k6status.Duration = proposedStatus.Duration
+		isNewer = true
+	}
+
+	// The runners are asked to stop only once.
+	if proposedStatus.StopDeadline != nil && k6status.StopDeadline == nil {
+		k6status.StopDeadline = proposedStatus.StopDeadline
+		isNewer = true
+	}
+
+	// Thresholds are evaluated only once, when the runners have finished.
+	if proposedStatus.ThresholdsPassed != nil && k6status.ThresholdsPassed == nil {
+		k6status.ThresholdsPassed = proposedStatus.ThresholdsPassed
+		k6status.Summary = proposedStatus.Summary
+		isNewer = true
+	}
+
+	// The script is validated only once, by the initializer.
+	if proposedStatus.Validation != nil && k6status.Validation == nil {
+		k6status.Validation = proposedStatus.Validation
+		isNewer = true
+	}
+
+	// The output volume is set only once, when runners are created.
+	if proposedStatus.OutputVolume != nil && k6status.OutputVolume == nil {
+		k6status.OutputVolume = proposedStatus.OutputVolume
+		isNewer = true
+	}
+
+	// Cloud project and organization are set only once, after creation of test run.
+	if len(proposedStatus.CloudProject) > 0 && len(k6status.CloudProject) == 0 {
+		k6status.CloudProject = proposedStatus.CloudProject
+		k6status.CloudOrg = proposedStatus.CloudOrg
+		isNewer = true
+	}
+
+	// Runner results are known only once, at the end of the test run.
+	if len(proposedStatus.RunnerResults) > 0 && len(k6status.RunnerResults) == 0 {
+		k6status.RunnerResults = proposedStatus.RunnerResults
+		isNewer = true
+	}
+
+	// So is the exit code.
+	if proposedStatus.ExitCode != nil && k6status.ExitCode == nil {
+		k6status.ExitCode = proposedStatus.ExitCode
+		k6status.ExitReason = proposedStatus.ExitReason
+		isNewer = true
+	}
+
+	// The generation is recorded when the test run is initialized.
+	if proposedStatus.ObservedGeneration > k6status.ObservedGeneration {
+		k6status.ObservedGeneration = proposedStatus.ObservedGeneration
+		isNewer = true
+	}
+
+	// Snapshots are only ever appended, so the newer history is the one
+	// with the later last snapshot.
+	if n := len(proposedStatus.Snapshots); n > 0 {
+		if m := len(k6status.Snapshots); m == 0 ||
+			k6status.Snapshots[m-1].Time.Before(&proposedStatus.Snapshots[n-1].Time) {
+			k6status.Snapshots = proposedStatus.Snapshots
+			isNewer = true
+		}
+	}
+
+	// Endpoints are known once runners are up and change only if the
+	// runners are recreated.
+	if len(proposedStatus.RunnerEndpoints) > 0 && !reflect.DeepEqual(proposedStatus.RunnerEndpoints, k6status.RunnerEndpoints) {
+		k6status.RunnerEndpoints = proposedStatus.RunnerEndpoints
+		isNewer = true
+	}
+
+	// Attempts to finalize the cloud test run are only ever counted up.
+	if proposedStatus.CloudFinalizeAttempts > k6status.CloudFinalizeAttempts {
+		k6status.CloudFinalizeAttempts = proposedStatus.CloudFinalizeAttempts
+		isNewer = true
+	}
+
+	// So are attempts to deliver the result to the webhook.
+	if proposedStatus.WebhookAttempts > k6status.WebhookAttempts {
+		k6status.WebhookAttempts = proposedStatus.WebhookAttempts
+		isNewer = true
+	}
+
+	// Waits for k6 Cloud token are only ever counted up.
+	if proposedStatus.CloudTokenAttempts > k6status.CloudTokenAttempts {
+		k6status.CloudTokenAttempts = proposedStatus.CloudTokenAttempts
+		isNewer = true
+	}
+
+	// Runners are started in waves and never stopped.
+	if proposedStatus.StartedRunners > k6status.StartedRunners {
+		k6status.StartedRunners = proposedStatus.StartedRunners
+		k6status.Phase = proposedStatus.Phase
+		k6status.PhaseReason = proposedStatus.PhaseReason
+		isNewer = true
+	}
+
+	// Phase may only move forward, while its reason may change freely
+	// within the same phase.
+	if len(proposedStatus.Phase) > 0 && phaseOrder[proposedStatus.Phase] >= phaseOrder[k6status.Phase] &&
+		(proposedStatus.Phase != k6status.Phase || proposedStatus.PhaseReason != k6status.PhaseReason) {
+		k6status.Phase = proposedStatus.Phase
+		k6status.PhaseReason = proposedStatus.PhaseReason
+		isNewer = true
+	}
+
+	// A retry of the test run starts the flow anew so the stage is
+	// allowed to go back in this case only.
+	if proposedStatus.Retries > k6status.Retries {
+		k6status.Retries = proposedStatus.Retries
+		k6status.RunnerResults = proposedStatus.RunnerResults
+		k6status.ExitCode = proposedStatus.ExitCode
+		k6status.ExitReason = proposedStatus.ExitReason
+		k6status.StartedRunners = proposedStatus.StartedRunners
+		k6status.StartTime = proposedStatus.StartTime
+		k6status.Phase = proposedStatus.Phase
+		k6status.PhaseReason = proposedStatus.PhaseReason
+		if len(proposedStatus.Stage) > 0 {
+			k6status.Stage = proposedStatus.Stage
+		}
+		return true
+	}
+
 	// If a change in stage is proposed, confirm that it is consistent with
 	// expected flow of any test run.
 	if k6status.Stage != proposedStatus.Stage && len(proposedStatus.Stage) > 0 {
@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetIfNewerPhase(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, propose Phase
+		expected         Phase
+	}{
+		{"Forward", "Running", "Succeeded", "Succeeded"},
+		{"Backward", "Running", "Pending", "Running"},
+		{"FailedOverSucceeded", "Succeeded", "Failed", "Failed"},
+		{"SucceededOverFailed", "Failed", "Succeeded", "Failed"},
+	}
+
+	for _, test := range tests {
+		status := K6Status{Phase: test.current}
+		status.SetIfNewer(K6Status{Phase: test.propose})
+		assert.Equal(t, test.expected, status.Phase, test.name)
+	}
+}
+
+func TestSetIfNewerRetry(t *testing.T) {
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+	status := K6Status{Stage: "started", StartTime: &start, StartedRunners: 2}
+
+	// the start time of the failed attempt is dropped on retry
+	assert.True(t, status.SetIfNewer(K6Status{Stage: "initialized", Retries: 1, Phase: "Pending"}))
+	assert.Nil(t, status.StartTime)
+	assert.Equal(t, Stage("initialized"), status.Stage)
+
+	// and set anew once the retry is started
+	restart := metav1.Now()
+	assert.True(t, status.SetIfNewer(K6Status{Stage: "started", Retries: 1, StartTime: &restart}))
+	assert.Equal(t, &restart, status.StartTime)
+}
@@ -15,8 +15,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"net"
+	"strconv"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type PodMetadata struct {
@@ -37,10 +42,96 @@ type Pod struct {
 	Resources                    corev1.ResourceRequirements   `json:"resources,omitempty"`
 	ServiceAccountName           string                        `json:"serviceAccountName,omitempty"`
 	SecurityContext              corev1.PodSecurityContext     `json:"securityContext,omitempty"`
-	EnvFrom                      []corev1.EnvFromSource        `json:"envFrom,omitempty"`
-	ReadinessProbe               *corev1.Probe                 `json:"readinessProbe,omitempty"`
-	LivenessProbe                *corev1.Probe                 `json:"livenessProbe,omitempty"`
-	InitContainers               []InitContainer               `json:"initContainers,omitempty"`
+	// ContainerSecurityContext is set on the containers of the operator in
+	// the pod, including the ones downloading the archive or secrets.
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+	EnvFrom                  []corev1.EnvFromSource  `json:"envFrom,omitempty"`
+	ReadinessProbe           *corev1.Probe           `json:"readinessProbe,omitempty"`
+	LivenessProbe            *corev1.Probe           `json:"livenessProbe,omitempty"`
+	InitContainers           []InitContainer         `json:"initContainers,omitempty"`
+	ConfigFile               *K6ConfigFile           `json:"configFile,omitempty"`
+
+	// ActiveDeadlineSeconds and TTLSecondsAfterFinished are set on the Jobs of
+	// initializer and starter only; runners' lifecycle is driven by the test itself.
+	ActiveDeadlineSeconds   *int64 `json:"activeDeadlineSeconds,omitempty"`
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// BackoffLimit is used by initializer only: it's the number of retries of
+	// a failed `k6 archive` before the test run is set to error. 0 by default.
+	// +kubebuilder:validation:Minimum=0
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// TraceContext is used by runners only.
+	TraceContext *TraceContext `json:"traceContext,omitempty"`
+
+	// SnapshotInterval is used by runners only: if set, aggregated metrics of
+	// runners are recorded in status.snapshots at this interval.
+	SnapshotInterval *metav1.Duration `json:"snapshotInterval,omitempty"`
+
+	// TerminationGracePeriodSeconds is used by runners only, e.g. to give
+	// outputs time to flush. If not set, the default of the cluster applies.
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// InitContainerResources are used by runners only: they override default
+	// requests and limits of the archive download container one by one.
+	InitContainerResources corev1.ResourceRequirements `json:"initContainerResources,omitempty"`
+
+	// TopologySpreadConstraints and SpreadAcrossZones are used by runners
+	// only. With SpreadAcrossZones, runners of the test run prefer to be
+	// scheduled in different zones.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	SpreadAcrossZones         bool                              `json:"spreadAcrossZones,omitempty"`
+
+	// Volumes are added to the pod and VolumeMounts to its k6 container and
+	// to the container downloading the archive, e.g. to mount data files
+	// next to the script. Names starting with k6- are reserved by the operator.
+	Volumes      []corev1.Volume      `json:"volumes,omitempty"`
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// PDB is used by runners only: if set, a PodDisruptionBudget keeps all
+	// runner pods available, so that node drains don't evict them mid-test.
+	PDB bool `json:"pdb,omitempty"`
+
+	// Build replaces k6 of the image with one built with extensions by an
+	// init container, e.g. to use extensions without an image of their own.
+	Build *K6Build `json:"build,omitempty"`
+}
+
+// K6Build is k6 built with xk6 in an init container of the pod. The binary
+// is built into the k6-build volume, under the hash of the version and the
+// extensions.
+type K6Build struct {
+	// Image of xk6, grafana/xk6:latest by default.
+	Image string `json:"image,omitempty"`
+	// Version of k6 to build, the latest by default.
+	Version string `json:"version,omitempty"`
+	// Extensions are passed to xk6 with --with, e.g.
+	// github.com/grafana/xk6-sql@v0.2.1.
+	// +kubebuilder:validation:MinItems=1
+	Extensions []string `json:"extensions"`
+	// CacheClaimName is a PersistentVolumeClaim to keep the binaries in, so
+	// that the same set of extensions is built only once. Without it, k6 is
+	// built on each run.
+	CacheClaimName string `json:"cacheClaimName,omitempty"`
+}
+
+// TraceContext is a header which the script can set on its requests to tell
+// load test traffic apart in tracing backends. It is passed to runners as
+// K6_TRACE_HEADER_NAME and K6_TRACE_HEADER_VALUE env vars. In the value,
+// {index} is replaced with the index of the runner and {name} with the name
+// of the K6 resource.
+type TraceContext struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+}
+
+// K6ConfigFile describes a k6 config file stored in a ConfigMap or a Secret.
+// It is mounted under /etc/k6 and passed to k6 with --config. Command line
+// flags set by the operator always take precedence over the config file.
+type K6ConfigFile struct {
+	ConfigMap string `json:"configMap,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+	File      string `json:"file,omitempty"`
 }
 
 type InitContainer struct {
@@ -65,6 +156,103 @@ type K6Scuttle struct {
 	QuitWithoutEnvoyTimeout string `json:"quitWithoutEnvoyTimeout,omitempty"`
 }
 
+const (
+	// DefaultPollInterval is used for started test runs without spec.pollInterval.
+	DefaultPollInterval = 15 * time.Second
+	// MinPollInterval and MaxPollInterval are the bounds of spec.pollInterval.
+	MinPollInterval = time.Second
+	MaxPollInterval = time.Hour
+)
+
+// GetPollInterval returns the interval of checks of the started test run,
+// within the bounds even if the webhook is not enabled.
+func (spec *K6Spec) GetPollInterval() time.Duration {
+	if spec.PollInterval == nil {
+		return DefaultPollInterval
+	}
+	switch interval := spec.PollInterval.Duration; {
+	case interval < MinPollInterval:
+		return MinPollInterval
+	case interval > MaxPollInterval:
+		return MaxPollInterval
+	default:
+		return interval
+	}
+}
+
+// ToleratedFailedRunners returns the number of runners that may fail as set
+// by spec.maxFailedRunners, 0 if it's not set or not valid.
+func (spec *K6Spec) ToleratedFailedRunners() int {
+	if spec.MaxFailedRunners == nil {
+		return 0
+	}
+	tolerated, err := intstr.GetScaledValueFromIntOrPercent(spec.MaxFailedRunners, int(spec.Parallelism), false)
+	if err != nil || tolerated < 0 {
+		return 0
+	}
+	return tolerated
+}
+
+// DefaultRestAPIPort is the port of k6 REST API of runners by default.
+const DefaultRestAPIPort int32 = 6565
+
+// RestAPI describes k6 REST API of runners
+type RestAPI struct {
+	// Scheme is https if runners serve the REST API over TLS, e.g. with
+	// a custom k6 build. Defaults to http.
+	// +kubebuilder:validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+	// Port the REST API listens on, 6565 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+	// InsecureSkipVerify disables verification of the certificate of the
+	// REST API, e.g. a self-signed one.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// RestAPIScheme returns the scheme of k6 REST API of runners.
+func (spec *K6Spec) RestAPIScheme() string {
+	if spec.RestAPI != nil && len(spec.RestAPI.Scheme) > 0 {
+		return spec.RestAPI.Scheme
+	}
+	return "http"
+}
+
+// RestAPIPort returns the port of k6 REST API of runners.
+func (spec *K6Spec) RestAPIPort() int32 {
+	if spec.RestAPI != nil && spec.RestAPI.Port > 0 {
+		return spec.RestAPI.Port
+	}
+	return DefaultRestAPIPort
+}
+
+// RestAPIURL returns the base URL of k6 REST API of the runner at host.
+func (spec *K6Spec) RestAPIURL(host string) string {
+	return spec.RestAPIScheme() + "://" + net.JoinHostPort(host, strconv.Itoa(int(spec.RestAPIPort())))
+}
+
+// RestAPIInsecureSkipVerify returns true if the certificate of k6 REST API
+// of runners should not be verified.
+func (spec *K6Spec) RestAPIInsecureSkipVerify() bool {
+	return spec.RestAPI != nil && spec.RestAPI.InsecureSkipVerify
+}
+
+// RunnerStages returns the stages in which runners are started: either the
+// configured ones or those of startup batches. Without them, all runners are
+// started at once.
+func (spec *K6Spec) RunnerStages() []RunnerStage {
+	if len(spec.Stages) > 0 || spec.StartupBatchSize <= 0 || spec.StartupDelay == nil {
+		return spec.Stages
+	}
+
+	var stages []RunnerStage
+	for count := spec.StartupBatchSize; count < spec.Parallelism; count += spec.StartupBatchSize {
+		stages = append(stages, RunnerStage{RunnerCount: count, Duration: *spec.StartupDelay})
+	}
+	return stages
+}
+
 // K6Spec defines the desired state of K6
 type K6Spec struct {
 	Script      K6Script               `json:"script"`
@@ -79,13 +267,336 @@ type K6Spec struct {
 	Paused      string                 `json:"paused,omitempty"`
 	Scuttle     K6Scuttle              `json:"scuttle,omitempty"`
 	Cleanup     Cleanup                `json:"cleanup,omitempty"`
+
+	// CleanupTTL delays cleanup post: the K6 resource is deleted only once
+	// this long has passed since the test run has finished or failed, so that
+	// its status can be inspected.
+	CleanupTTL *metav1.Duration `json:"cleanupTTL,omitempty"`
+
+	// CleanupOnError deletes the jobs and services of the test run when it
+	// lands in the error stage. The K6 resource is kept to diagnose the failure.
+	CleanupOnError bool `json:"cleanupOnError,omitempty"`
+
+	// NoUsageReport disables k6 usage report. If not set, the default of
+	// the operator is used: see its --no-usage-report flag.
+	NoUsageReport string `json:"noUsageReport,omitempty"`
+
+	// CheckSetup makes the operator wait for each runner to report the end
+	// of its init stage and to serve /v1/setup before starting the test.
+	CheckSetup bool `json:"checkSetup,omitempty"`
+
+	RetryPolicy  *RetryPolicy  `json:"retryPolicy,omitempty"`
+	Secrets      *K6Secrets    `json:"secrets,omitempty"`
+	OutputVolume *OutputVolume `json:"outputVolume,omitempty"`
+
+	Notify *Notify  `json:"notify,omitempty"`
+	Cloud  *K6Cloud `json:"cloud,omitempty"`
+	Output *Output  `json:"output,omitempty"`
+
+	// AdoptExisting makes the operator discover runner jobs and services
+	// created out-of-band, with labels app=k6, k6_cr=<name> and runner=true,
+	// instead of creating them.
+	AdoptExisting bool `json:"adoptExisting,omitempty"`
+
+	// RestAPI configures how k6 REST API of runners is served and reached.
+	RestAPI *RestAPI `json:"restAPI,omitempty"`
+
+	// MaxLifetime limits the time since creation of the K6 resource during
+	// which the test run must finish. Otherwise, it is stopped and set to error.
+	MaxLifetime *metav1.Duration `json:"maxLifetime,omitempty"`
+
+	// StartupTimeout limits the time the created test run waits for its
+	// runners to be ready. Otherwise, it is set to error.
+	StartupTimeout *metav1.Duration `json:"startupTimeout,omitempty"`
+
+	// PollInterval is how often the operator checks whether the started test
+	// run has finished: between 1s and 1h, 15s by default.
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// PropagationPolicy is used when the operator deletes jobs of the test
+	// run or the K6 resource itself. Background by default.
+	// +kubebuilder:validation:Enum=Background;Foreground
+	PropagationPolicy metav1.DeletionPropagation `json:"propagationPolicy,omitempty"`
+
+	// StopSignal defines how runners are stopped before the operator deletes
+	// them, e.g. on max lifetime, abort or restart: kill deletes them right
+	// away, graceful stops the test over k6 REST API and waits for it to end.
+	// By default, cloud test runs are stopped gracefully and others are killed.
+	// +kubebuilder:validation:Enum=graceful;kill
+	StopSignal string `json:"stopSignal,omitempty"`
+
+	// InheritRunnerScheduling makes the initializer and the starter use node
+	// selector, tolerations and node affinity of the runners, unless they
+	// have their own. It is "true" by default.
+	InheritRunnerScheduling string `json:"inheritRunnerScheduling,omitempty"`
+
+	// RunnerCountPolicy defines what happens when the number of runners
+	// differs from parallelism: Wait (default) or Abort the test run.
+	// +kubebuilder:validation:Enum=Wait;Abort
+	RunnerCountPolicy string `json:"runnerCountPolicy,omitempty"`
+
+	// MaxFailedRunners is the number, or the percentage of parallelism
+	// rounded down, of runners that may fail while the test run is still
+	// considered successful. None by default.
+	MaxFailedRunners *intstr.IntOrString `json:"maxFailedRunners,omitempty"`
+
+	// VersionCheck defines what happens when the script is an archive
+	// created by a newer k6 than the one in the runner image.
+	VersionCheck VersionCheck `json:"versionCheck,omitempty"`
+
+	// Stages start the runners in waves instead of all at once.
+	Stages []RunnerStage `json:"stages,omitempty"`
+
+	// StartupBatchSize and StartupDelay are a shorthand for stages: runners
+	// are started in batches of this size, with the delay between batches.
+	// +kubebuilder:validation:Minimum=1
+	StartupBatchSize int32            `json:"startupBatchSize,omitempty"`
+	StartupDelay     *metav1.Duration `json:"startupDelay,omitempty"`
+
+	// ImagePullSecrets are added to the ones of runner, initializer and
+	// starter pods, e.g. when all images are in the same private registry.
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// Metadata is added to all jobs, pods and services of the test run. The
+	// metadata of runner, initializer and starter takes precedence over it.
+	Metadata PodMetadata `json:"metadata,omitempty"`
+
+	// FailOnThresholdBreach ends the test run in error stage instead of
+	// finished when any of k6 thresholds has failed.
+	FailOnThresholdBreach bool `json:"failOnThresholdBreach,omitempty"`
+
+	// ValidateOnly runs the initializer to validate the script and finishes
+	// the test run right after it, without creating any runners.
+	ValidateOnly bool `json:"validateOnly,omitempty"`
+
+	// SegmentWeights split the test between runners in proportion to the
+	// weights instead of equally, e.g. [2, 1, 1] gives the first of three
+	// runners half of the test. There must be a weight for each runner.
+	SegmentWeights []int32 `json:"segmentWeights,omitempty"`
+}
+
+// VersionCheck is either Warn (default) or Fail
+// +kubebuilder:validation:Enum=Warn;Fail
+type VersionCheck string
+
+// RunnerStage makes the operator keep RunnerCount runners started for
+// the Duration. Runners are only ever added: lower count than in the
+// previous stage has no effect. Once all stages have passed, all runners
+// are started.
+type RunnerStage struct {
+	RunnerCount int32           `json:"runnerCount"`
+	Duration    metav1.Duration `json:"duration"`
 }
 
+// K6Cloud configures the test run with cloud output.
+type K6Cloud struct {
+	// Name of the cloud test run, instead of the one from script options.
+	Name string `json:"name,omitempty"`
+	// Note is attached to the cloud test run.
+	Note string `json:"note,omitempty"`
+	// SkipFinalize marks the cloud test run as finalized without a call to
+	// k6 Cloud, e.g. for tokens that are not permitted to finalize test runs.
+	SkipFinalize bool `json:"skipFinalize,omitempty"`
+}
+
+// Output configures k6 outputs of the runners. Outputs themselves are still
+// enabled with --out in arguments.
+type Output struct {
+	InfluxDB              *InfluxDBOutput              `json:"influxdb,omitempty"`
+	PrometheusRemoteWrite *PrometheusRemoteWriteOutput `json:"prometheusRemoteWrite,omitempty"`
+	Statsd                *StatsdOutput                `json:"statsd,omitempty"`
+
+	// ScrapeMetrics creates a ServiceMonitor of Prometheus Operator for
+	// the metrics of the runners in Prometheus format, served on the port of
+	// the REST API. k6 itself serves only JSON on /v1/metrics, so it needs an
+	// output extension in the runner image. It's ignored on clusters without
+	// Prometheus Operator.
+	ScrapeMetrics bool `json:"scrapeMetrics,omitempty"`
+	// ScrapeMetricsPath is the path scraped by the ServiceMonitor, /metrics
+	// if unset.
+	ScrapeMetricsPath string `json:"scrapeMetricsPath,omitempty"`
+}
+
+// ScrapePath returns the path of the metrics scraped from the runners.
+func (o *Output) ScrapePath() string {
+	if len(o.ScrapeMetricsPath) > 0 {
+		return o.ScrapeMetricsPath
+	}
+	return "/metrics"
+}
+
+// InfluxDBOutput tunes buffering of the InfluxDB output.
+type InfluxDBOutput struct {
+	// PushInterval is how often buffered metrics are written. Longer intervals
+	// ride out short outages of InfluxDB but keep more metrics in memory of runners.
+	PushInterval *metav1.Duration `json:"pushInterval,omitempty"`
+	// ConcurrentWrites limits the number of writes in flight.
+	// +kubebuilder:validation:Minimum=1
+	ConcurrentWrites int32 `json:"concurrentWrites,omitempty"`
+	// PayloadSize is the maximum number of metrics in a single write.
+	// +kubebuilder:validation:Minimum=1
+	PayloadSize int32 `json:"payloadSize,omitempty"`
+
+	// Address of InfluxDB, e.g. http://influxdb:8086. When it's set, the
+	// output is enabled by the operator, without --out in arguments.
+	Address string `json:"address,omitempty"`
+	// Database of InfluxDB v1, or the bucket of InfluxDB v2.
+	Database string `json:"database,omitempty"`
+	// Version is v1 (default), authenticated with BasicAuth, or v2,
+	// authenticated with Organization and TokenSecret. InfluxDB v2 is written
+	// to by the xk6-output-influxdb extension, which must be in the runner image.
+	// +kubebuilder:validation:Enum=v1;v2
+	Version      string                    `json:"version,omitempty"`
+	BasicAuth    *BasicAuth                `json:"basicAuth,omitempty"`
+	Organization string                    `json:"organization,omitempty"`
+	TokenSecret  *corev1.SecretKeySelector `json:"tokenSecret,omitempty"`
+}
+
+// PrometheusRemoteWriteOutput tunes buffering of the Prometheus remote-write output.
+type PrometheusRemoteWriteOutput struct {
+	// PushInterval is how often buffered metrics are sent, all of them in one
+	// batch. Longer intervals mean bigger batches and more memory of runners.
+	PushInterval *metav1.Duration `json:"pushInterval,omitempty"`
+
+	// URL of the remote-write endpoint. When it's set, the output is enabled
+	// by the operator, without --out in arguments.
+	URL string `json:"url,omitempty"`
+	// BasicAuth or BearerTokenSecret authenticate runners to the endpoint.
+	BasicAuth         *BasicAuth                `json:"basicAuth,omitempty"`
+	BearerTokenSecret *corev1.SecretKeySelector `json:"bearerTokenSecret,omitempty"`
+}
+
+// BasicAuth is a username with the password kept in a secret.
+type BasicAuth struct {
+	Username       string                   `json:"username"`
+	PasswordSecret corev1.SecretKeySelector `json:"passwordSecret"`
+}
+
+// StatsdOutput tunes buffering of the StatsD output.
+type StatsdOutput struct {
+	// PushInterval is how often buffered metrics are sent.
+	PushInterval *metav1.Duration `json:"pushInterval,omitempty"`
+	// BufferSize is the number of metrics buffered before they are sent.
+	// +kubebuilder:validation:Minimum=1
+	BufferSize int32 `json:"bufferSize,omitempty"`
+}
+
+// Notify configures notifications about the test run sent by the operator.
+// Failures to notify never fail the test run.
+type Notify struct {
+	GrafanaAnnotation *GrafanaAnnotation `json:"grafanaAnnotation,omitempty"`
+	Webhook           *Webhook           `json:"webhook,omitempty"`
+}
+
+// Webhook makes the operator post the result of the test run as JSON to the
+// URL once the test run is finished or has failed.
+type Webhook struct {
+	URL string `json:"url"`
+	// Authorization refers to a key of a Secret in the namespace of the K6
+	// resource with the value of Authorization header, e.g. "Bearer <token>".
+	Authorization *corev1.SecretKeySelector `json:"authorization,omitempty"`
+	// MaxAttempts to deliver the result on server errors, 3 by default.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+}
+
+// GrafanaAnnotation makes the operator create annotations in Grafana when
+// the test run starts and finishes.
+type GrafanaAnnotation struct {
+	URL string `json:"url"`
+	// APIKey refers to a key of a Secret in the namespace of the K6 resource.
+	APIKey       corev1.SecretKeySelector `json:"apiKey"`
+	DashboardUID string                   `json:"dashboardUID,omitempty"`
+	PanelID      int64                    `json:"panelId,omitempty"`
+	Tags         []string                 `json:"tags,omitempty"`
+}
+
+// OutputVolume is a shared PersistentVolumeClaim mounted by all runners for
+// files they write, like CSV or JSON output. Each runner gets its own
+// sub-path of the volume, named runner-<index>.
+type OutputVolume struct {
+	ClaimName string `json:"claimName"`
+	// MountPath is /output by default.
+	MountPath string `json:"mountPath,omitempty"`
+}
+
+// OutputVolumeStatus records where the files of the runners are kept.
+type OutputVolumeStatus struct {
+	ClaimName string   `json:"claimName"`
+	Paths     []string `json:"paths,omitempty"`
+}
+
+// K6Secrets references a Secret whose entries are made available to the
+// script via k6/secrets module as a file secret source.
+type K6Secrets struct {
+	Name string `json:"name"`
+}
+
+// RetryPolicy defines when the test should be re-run automatically.
+// Failures reported by k6 itself, like breached thresholds or script
+// errors, are a real test result and are never retried.
+type RetryPolicy struct {
+	MaxRetries int32       `json:"maxRetries,omitempty"`
+	RetryOn    RetryReason `json:"retryOn,omitempty"`
+}
+
+// RetryReason describes which failures of the test run can be retried
+// +kubebuilder:validation:Enum=InfraFailure
+type RetryReason string
+
 // K6Script describes where the script to execute the tests is found
 type K6Script struct {
 	VolumeClaim K6VolumeClaim `json:"volumeClaim,omitempty"`
 	ConfigMap   K6Configmap   `json:"configMap,omitempty"`
 	LocalFile   string        `json:"localFile,omitempty"`
+
+	// ArchiveDownload fetches the script, packed with k6 archive, when pods start.
+	ArchiveDownload *ArchiveDownload `json:"archiveDownload,omitempty"`
+
+	// RequiredFiles must exist next to the script, e.g. its local imports.
+	// Relative paths are resolved against the directory of the script.
+	// The initializer fails early if any of them or the script is missing.
+	RequiredFiles []string `json:"requiredFiles,omitempty"`
+}
+
+// ArchiveDownloadMode is a way to download the test archive from S3
+type ArchiveDownloadMode string
+
+const (
+	ArchiveDownloadCurl ArchiveDownloadMode = "curl"
+	ArchiveDownloadSDK  ArchiveDownloadMode = "sdk"
+)
+
+// ArchiveDownload describes where to download the test archive from
+type ArchiveDownload struct {
+	// URI of the archive: gs://<bucket>/<object> for Google Cloud Storage,
+	// az://<account>/<container>/<blob> or a blob.core.windows.net URL for
+	// Azure Blob Storage, s3://<bucket>/<key> for S3 in sdk mode, otherwise
+	// an HTTP(S) URL like a presigned URL of S3.
+	URI string `json:"uri"`
+	// Mode of S3 download: curl fetches a URL, sdk uses the AWS CLI with
+	// credentials of the pod, e.g. IAM roles for service accounts.
+	// Defaults to sdk for s3:// URIs and to curl otherwise.
+	// +kubebuilder:validation:Enum=curl;sdk
+	Mode ArchiveDownloadMode `json:"mode,omitempty"`
+	// Image of the download container, defaults depend on the kind of URI.
+	Image string `json:"image,omitempty"`
+	// CredentialsSecret is a key of service account JSON for Google Cloud
+	// Storage, without it workload identity of the pod is used. For Azure Blob
+	// Storage, it is the account key, without it the URL may have a SAS token.
+	CredentialsSecret *corev1.SecretKeySelector `json:"credentialsSecret,omitempty"`
+
+	// Headers are added to the request for an HTTP(S) URL.
+	Headers map[string]string `json:"headers,omitempty"`
+	// HeaderSecretRef is a key of a secret with more headers, one per line,
+	// e.g. "Authorization: Bearer <token>". It is never put into the command.
+	HeaderSecretRef *corev1.SecretKeySelector `json:"headerSecretRef,omitempty"`
+	// SHA256 is the expected hex digest of the downloaded archive.
+	// +kubebuilder:validation:Pattern=^[a-fA-F0-9]+$
+	// +kubebuilder:validation:MinLength=64
+	// +kubebuilder:validation:MaxLength=64
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 // K6VolumeClaim describes the volume claim script location
@@ -100,31 +611,151 @@ type K6Configmap struct {
 	File string `json:"file,omitempty"`
 }
 
-//TODO: cleanup pre-execution?
-
-// Cleanup allows for automatic cleanup of resources post execution
-// +kubebuilder:validation:Enum=post
+// Cleanup allows for automatic cleanup of resources: post deletes the K6
+// resource after execution, pre deletes resources left by previous test runs
+// with the same name before execution.
+// +kubebuilder:validation:Enum=post;pre
 type Cleanup string
 
 // Stage describes which stage of the test execution lifecycle our runners are in
 // +kubebuilder:validation:Enum=initialization;initialized;created;started;finished;error
 type Stage string
 
+// Phase is a summary of the test run for users, as opposed to Stage used by
+// the operator internally. PhaseReason explains it, e.g. why the test run is
+// still Pending.
+// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed
+type Phase string
+
 // K6Status defines the observed state of K6
 type K6Status struct {
-	Stage           Stage  `json:"stage,omitempty"`
-	TestRunID       string `json:"testRunId,omitempty"`
+	Stage       Stage  `json:"stage,omitempty"`
+	Phase       Phase  `json:"phase,omitempty"`
+	PhaseReason string `json:"phaseReason,omitempty"`
+	TestRunID   string `json:"testRunId,omitempty"`
+	// TestRunURL is the link to the results of the cloud test run.
+	TestRunURL      string `json:"testRunURL,omitempty"`
+	RunID           string `json:"runId,omitempty"`
 	AggregationVars string `json:"aggregationVars,omitempty"`
+	CloudProject    string `json:"cloudProject,omitempty"`
+	CloudOrg        string `json:"cloudOrg,omitempty"`
+	Retries         int32  `json:"retries,omitempty"`
+	StartedRunners  int32  `json:"startedRunners,omitempty"`
+
+	// CloudFinalizeAttempts counts failed attempts to finalize the cloud test run.
+	CloudFinalizeAttempts int32 `json:"cloudFinalizeAttempts,omitempty"`
+	// WebhookAttempts counts failed attempts to deliver the result to the webhook.
+	WebhookAttempts int32 `json:"webhookAttempts,omitempty"`
+	// CloudTokenAttempts counts the times the test run has waited for k6 Cloud token.
+	CloudTokenAttempts int32 `json:"cloudTokenAttempts,omitempty"`
+
+	// StartTime is when the runners were started and CompletionTime is when
+	// the test run has finished. Duration is the time between them, e.g. "5m3s".
+	StartTime      *metav1.Time `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	Duration       string       `json:"duration,omitempty"`
+
+	// StageTime is when the test run has entered the current stage.
+	StageTime *metav1.Time `json:"stageTime,omitempty"`
+
+	// StopDeadline is set when the runners were asked to stop gracefully:
+	// they are deleted once they have stopped or the deadline has passed.
+	StopDeadline *metav1.Time `json:"stopDeadline,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the test run was started with.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	RunnerResults []RunnerResult `json:"runnerResults,omitempty"`
+	// ExitCode is the most severe exit code of k6 across the runners, e.g. 99
+	// if thresholds have failed, and ExitReason is its description.
+	ExitCode     *int32              `json:"exitCode,omitempty"`
+	ExitReason   string              `json:"exitReason,omitempty"`
+	OutputVolume *OutputVolumeStatus `json:"outputVolume,omitempty"`
+	Snapshots    []MetricSnapshot    `json:"snapshots,omitempty"`
+
+	// RunnerEndpoints are addresses of k6 REST API of the runners, e.g. to
+	// scrape /v1/metrics of each of them.
+	RunnerEndpoints []RunnerEndpoint `json:"runnerEndpoints,omitempty"`
+
+	// ThresholdsPassed and Summary are set once the runners have finished:
+	// thresholds passed unless any of them has failed on any runner.
+	ThresholdsPassed *bool           `json:"thresholdsPassed,omitempty"`
+	Summary          *TestRunSummary `json:"summary,omitempty"`
+
+	// Validation is the result of the initializer in validate-only mode.
+	Validation *ValidationResult `json:"validation,omitempty"`
 
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// RunnerResult describes how a single runner has finished
+type RunnerResult struct {
+	Index    int32            `json:"index"`
+	Result   RunnerResultType `json:"result"`
+	ExitCode int32            `json:"exitCode,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+}
+
+// TestRunSummary counts runners and k6 thresholds of the finished test run.
+// Thresholds are counted per metric, across all runners which could be reached.
+type TestRunSummary struct {
+	Runners          int32 `json:"runners"`
+	FailedRunners    int32 `json:"failedRunners,omitempty"`
+	Thresholds       int32 `json:"thresholds,omitempty"`
+	FailedThresholds int32 `json:"failedThresholds,omitempty"`
+	// FailedMetrics are the names of metrics with failed thresholds.
+	FailedMetrics []string `json:"failedMetrics,omitempty"`
+	HTTPReqs      int64    `json:"httpReqs,omitempty"`
+	// ErrorRate is the ratio of failed HTTP requests, e.g. "0.0125".
+	ErrorRate string `json:"errorRate,omitempty"`
+	// HTTPReqDurationP95 is the 95th percentile of http_req_duration in
+	// milliseconds, e.g. "210.5". Percentiles of runners can't be merged, so
+	// it's the highest one of them.
+	HTTPReqDurationP95 string `json:"httpReqDurationP95,omitempty"`
+}
+
+// ValidationResult tells whether the script is valid, with the options
+// inferred from it by the initializer.
+type ValidationResult struct {
+	Valid bool `json:"valid"`
+	// Reason explains why the script is not valid.
+	Reason string `json:"reason,omitempty"`
+	// Duration is the total duration of the test, e.g. "5m30s", if it's
+	// known without running the test.
+	Duration string `json:"duration,omitempty"`
+	MaxVUs   int64  `json:"maxVUs,omitempty"`
+}
+
+// RunnerEndpoint is an address of k6 REST API of a single runner
+type RunnerEndpoint struct {
+	Index    int32  `json:"index"`
+	Endpoint string `json:"endpoint"`
+}
+
+// MetricSnapshot is a point in time of metrics aggregated over all runners
+type MetricSnapshot struct {
+	Time       metav1.Time `json:"time"`
+	VUs        int64       `json:"vus"`
+	Iterations int64       `json:"iterations"`
+	HTTPReqs   int64       `json:"httpReqs"`
+	// ErrorRate is the ratio of failed HTTP requests, e.g. "0.0125".
+	ErrorRate string `json:"errorRate,omitempty"`
+}
+
+// RunnerResultType is an outcome of a single runner
+// +kubebuilder:validation:Enum=Completed;Failed
+type RunnerResultType string
+
 // K6 is the Schema for the k6s API
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Stage",type="string",JSONPath=".status.stage",description="Stage"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Phase"
+// +kubebuilder:printcolumn:name="Parallelism",type="integer",JSONPath=".spec.parallelism",description="Parallelism"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="TestRunID",type="string",JSONPath=".status.testRunId"
+// +kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".status.duration",priority=1
+// +kubebuilder:printcolumn:name="Thresholds Passed",type="boolean",JSONPath=".status.thresholdsPassed",priority=1
 type K6 struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,351 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// AllowRestartAnnotation permits changes of the spec during an active test
+// run: the operator then stops the test run and starts it anew.
+const AllowRestartAnnotation = "k6.io/allow-restart"
+
+// webhookReader looks up objects referenced by K6 resources on creation. The
+// checks are skipped if it's not set.
+var webhookReader client.Reader
+
+// WebhookOptions tell the validating webhook how the operator is run.
+// +kubebuilder:object:generate=false
+type WebhookOptions struct {
+	// TokenNamespace is where the operator looks for the secret with k6
+	// Cloud token.
+	TokenNamespace string
+	// TokenFromFile is true if the operator reads k6 Cloud token from a
+	// file instead of the secret.
+	TokenFromFile bool
+	// HasCloudOutput tells whether k6 is run with cloud output, the way the
+	// operator parses the arguments. The check of the token is skipped if
+	// it's not set.
+	HasCloudOutput func(*K6Spec) bool
+}
+
+var webhookOptions WebhookOptions
+
+// SetupWebhookWithManager registers the validating webhook for K6.
+func (k6 *K6) SetupWebhookWithManager(mgr ctrl.Manager, opts WebhookOptions) error {
+	webhookReader = mgr.GetAPIReader()
+	webhookOptions = opts
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(k6).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-k6-io-v1alpha1-k6,mutating=false,failurePolicy=fail,groups=k6.io,resources=k6s,verbs=create;update,versions=v1alpha1,name=vk6.kb.io,sideEffects=None,admissionReviewVersions=v1
+
+var _ webhook.Validator = &K6{}
+
+// IsActive returns true if the test run is past initialization but not done yet.
+func (k6 *K6) IsActive() bool {
+	switch k6.Status.Stage {
+	case "initialization", "initialized", "created", "started":
+		return true
+	}
+	return false
+}
+
+// AllowsRestart returns true if the test run may be restarted on spec change.
+func (k6 *K6) AllowsRestart() bool {
+	return k6.Annotations[AllowRestartAnnotation] == "true"
+}
+
+// scriptSources returns the ways the script is set in the spec.
+func (k6 *K6) scriptSources() []string {
+	var sources []string
+	if len(k6.Spec.Script.ConfigMap.Name) > 0 {
+		sources = append(sources, "configMap")
+	}
+	if len(k6.Spec.Script.VolumeClaim.Name) > 0 {
+		sources = append(sources, "volumeClaim")
+	}
+	if len(k6.Spec.Script.LocalFile) > 0 {
+		sources = append(sources, "localFile")
+	}
+	if k6.Spec.Script.ArchiveDownload != nil {
+		sources = append(sources, "archiveDownload")
+	}
+	return sources
+}
+
+// hasCloudToken returns true if runners have K6_CLOUD_TOKEN of their own, or
+// may have it from envFrom.
+func (k6 *K6) hasCloudToken() bool {
+	for _, env := range k6.Spec.Runner.Env {
+		if env.Name == "K6_CLOUD_TOKEN" {
+			return true
+		}
+	}
+	return len(k6.Spec.Runner.EnvFrom) > 0
+}
+
+// reservedFlags are set by the operator on each runner to split the test
+// between them.
+var reservedFlags = []string{"--execution-segment", "--execution-segment-sequence"}
+
+// ReservedArgument returns the first of reservedFlags in arguments, if any.
+func (k6 *K6) ReservedArgument() string {
+	for _, arg := range strings.Fields(k6.Spec.Arguments) {
+		flag, _, _ := strings.Cut(arg, "=")
+		for _, reserved := range reservedFlags {
+			if flag == reserved {
+				return reserved
+			}
+		}
+	}
+	return ""
+}
+
+// validateSpec checks the values that can't be validated by the schema.
+func (k6 *K6) validateSpec() error {
+	if k6.Spec.Parallelism < 1 {
+		return fmt.Errorf("parallelism of K6 %s must be at least 1, got %d", k6.Name, k6.Spec.Parallelism)
+	}
+	switch sources := k6.scriptSources(); len(sources) {
+	case 0:
+		return fmt.Errorf("script of K6 %s must be set with one of configMap, volumeClaim, localFile or archiveDownload", k6.Name)
+	case 1:
+	default:
+		return fmt.Errorf("script of K6 %s must be set only once, got %s", k6.Name, strings.Join(sources, " and "))
+	}
+	if weights := k6.Spec.SegmentWeights; len(weights) > 0 {
+		if int32(len(weights)) != k6.Spec.Parallelism {
+			return fmt.Errorf("segmentWeights of K6 %s must have a weight for each of %d runners, got %d", k6.Name, k6.Spec.Parallelism, len(weights))
+		}
+		for _, weight := range weights {
+			if weight < 1 {
+				return fmt.Errorf("segmentWeights of K6 %s must be positive, got %v", k6.Name, weights)
+			}
+		}
+	}
+	if flag := k6.ReservedArgument(); len(flag) > 0 {
+		return fmt.Errorf("arguments of K6 %s can't set %s: execution segments of runners are set by the operator", k6.Name, flag)
+	}
+	if file := k6.Spec.Script.ConfigMap.File; strings.Contains(file, "/") {
+		return fmt.Errorf("file of K6 %s must be a key of the configMap, got %q", k6.Name, file)
+	}
+	if err := k6.validateVolumes("runner", &k6.Spec.Runner); err != nil {
+		return err
+	}
+	if k6.Spec.Initializer != nil {
+		if err := k6.validateVolumes("initializer", k6.Spec.Initializer); err != nil {
+			return err
+		}
+	}
+	if maxFailed := k6.Spec.MaxFailedRunners; maxFailed != nil {
+		if tolerated, err := intstr.GetScaledValueFromIntOrPercent(maxFailed, int(k6.Spec.Parallelism), false); err != nil || tolerated < 0 {
+			return fmt.Errorf("maxFailedRunners of K6 %s must be a non-negative number or percentage, got %q", k6.Name, maxFailed.String())
+		}
+	}
+	if cleanup := k6.Spec.Cleanup; len(cleanup) > 0 && cleanup != "post" && cleanup != "pre" {
+		return fmt.Errorf("cleanup of K6 %s must be post or pre, got %q", k6.Name, cleanup)
+	}
+	if ttl := k6.Spec.CleanupTTL; ttl != nil && (k6.Spec.Cleanup != "post" || ttl.Duration < 0) {
+		return fmt.Errorf("cleanupTTL of K6 %s must be a non-negative duration and requires cleanup post, got %s", k6.Name, ttl.Duration)
+	}
+	if interval := k6.Spec.PollInterval; interval != nil &&
+		(interval.Duration < MinPollInterval || interval.Duration > MaxPollInterval) {
+		return fmt.Errorf("pollInterval of K6 %s must be between %s and %s, got %s",
+			k6.Name, MinPollInterval, MaxPollInterval, interval.Duration)
+	}
+	if len(k6.Spec.Stages) > 0 && (k6.Spec.StartupBatchSize > 0 || k6.Spec.StartupDelay != nil) {
+		return fmt.Errorf("stages of K6 %s can't be combined with startupBatchSize and startupDelay", k6.Name)
+	}
+	if (k6.Spec.StartupBatchSize > 0) != (k6.Spec.StartupDelay != nil) {
+		return fmt.Errorf("startupBatchSize and startupDelay of K6 %s must be set together", k6.Name)
+	}
+	if output := k6.Spec.Output; output != nil && output.InfluxDB != nil {
+		if err := k6.validateInfluxDB(output.InfluxDB); err != nil {
+			return err
+		}
+	}
+	if output := k6.Spec.Output; output != nil && output.PrometheusRemoteWrite != nil {
+		if err := k6.validateOutputURL("prometheusRemoteWrite", output.PrometheusRemoteWrite.URL); err != nil {
+			return err
+		}
+		if output.PrometheusRemoteWrite.BasicAuth != nil && output.PrometheusRemoteWrite.BearerTokenSecret != nil {
+			return fmt.Errorf("prometheusRemoteWrite of K6 %s can't have both basicAuth and bearerTokenSecret", k6.Name)
+		}
+	}
+	return nil
+}
+
+// validateInfluxDB checks that authentication of the InfluxDB output matches
+// its version.
+func (k6 *K6) validateInfluxDB(influxdb *InfluxDBOutput) error {
+	if err := k6.validateOutputURL("influxdb", influxdb.Address); err != nil {
+		return err
+	}
+	if influxdb.Version == "v2" {
+		if influxdb.BasicAuth != nil {
+			return fmt.Errorf("influxdb v2 output of K6 %s must use tokenSecret instead of basicAuth", k6.Name)
+		}
+		if len(influxdb.Address) > 0 && (len(influxdb.Organization) == 0 || len(influxdb.Database) == 0 || influxdb.TokenSecret == nil) {
+			return fmt.Errorf("influxdb v2 output of K6 %s must have organization, database and tokenSecret", k6.Name)
+		}
+		return nil
+	}
+	if len(influxdb.Organization) > 0 || influxdb.TokenSecret != nil {
+		return fmt.Errorf("influxdb v1 output of K6 %s must use basicAuth instead of organization and tokenSecret", k6.Name)
+	}
+	return nil
+}
+
+// validateOutputURL checks that the URL of the output, if any, is an absolute
+// http or https one.
+func (k6 *K6) validateOutputURL(output, rawURL string) error {
+	if len(rawURL) == 0 {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("url of %s output of K6 %s must be an http or https URL, got %q", output, k6.Name, rawURL)
+	}
+	return nil
+}
+
+// validateVolumes checks that volumes of the pod don't collide with the ones
+// of the operator, which are prefixed with k6-, nor hide the script.
+func (k6 *K6) validateVolumes(pod string, spec *Pod) error {
+	for _, volume := range spec.Volumes {
+		if strings.HasPrefix(volume.Name, "k6-") {
+			return fmt.Errorf("volume %s of the %s of K6 %s uses the reserved prefix k6-", volume.Name, pod, k6.Name)
+		}
+	}
+	for _, mount := range spec.VolumeMounts {
+		if strings.HasPrefix(mount.Name, "k6-") {
+			return fmt.Errorf("volumeMount %s of the %s of K6 %s uses the reserved prefix k6-", mount.Name, pod, k6.Name)
+		}
+		if path.Clean(mount.MountPath) == "/test" {
+			return fmt.Errorf("volumeMount %s of the %s of K6 %s can't be mounted at /test, use a sub-path of it", mount.Name, pod, k6.Name)
+		}
+	}
+	return nil
+}
+
+// validateReferences checks that the objects the test run needs exist. It's
+// best-effort: the check is skipped if an object can't be looked up.
+func (k6 *K6) validateReferences(ctx context.Context, reader client.Reader) error {
+	if reader == nil {
+		return nil
+	}
+
+	exists := func(obj client.Object, name, namespace string) bool {
+		err := reader.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj)
+		return !k8sErrors.IsNotFound(err)
+	}
+
+	if name := k6.Spec.Script.ConfigMap.Name; len(name) > 0 {
+		script := &corev1.ConfigMap{}
+		if !exists(script, name, k6.Namespace) {
+			return fmt.Errorf("configMap %s with the script of K6 %s doesn't exist", name, k6.Name)
+		}
+
+		// keys of the configMap are the files of the test: the entrypoint
+		// must be one of them, if the configMap could be read
+		file := k6.Spec.Script.ConfigMap.File
+		if len(file) == 0 {
+			file = "test.js"
+		}
+		_, inData := script.Data[file]
+		_, inBinaryData := script.BinaryData[file]
+		if len(script.Name) > 0 && !inData && !inBinaryData {
+			return fmt.Errorf("configMap %s of K6 %s has no file %s", name, k6.Name, file)
+		}
+	}
+	if k6.Spec.Secrets != nil && !exists(&corev1.Secret{}, k6.Spec.Secrets.Name, k6.Namespace) {
+		return fmt.Errorf("secret %s of K6 %s doesn't exist", k6.Spec.Secrets.Name, k6.Name)
+	}
+
+	// the token of runners is used instead of the one in the secret
+	opts := webhookOptions
+	if !opts.TokenFromFile && opts.HasCloudOutput != nil && opts.HasCloudOutput(&k6.Spec) && !k6.hasCloudToken() {
+		secrets := &corev1.SecretList{}
+		err := reader.List(ctx, secrets, &client.ListOptions{
+			Namespace:     opts.TokenNamespace,
+			LabelSelector: labels.SelectorFromSet(map[string]string{"k6cloud": "token"}),
+		})
+		if err == nil && len(secrets.Items) == 0 {
+			return fmt.Errorf("K6 %s has cloud output but there is no secret with k6 Cloud token in %s", k6.Name, opts.TokenNamespace)
+		}
+	}
+	return nil
+}
+
+// ValidateCreate implements webhook.Validator.
+func (k6 *K6) ValidateCreate() error {
+	if err := k6.validateSpec(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return k6.validateReferences(ctx, webhookReader)
+}
+
+// ValidateUpdate rejects changes of the spec while the test run is active,
+// as they would conflict with already existing jobs, unless restart is allowed.
+// Only changed specs are validated: an object stored before a rule got
+// stricter can still get its finalizer removed or be aborted.
+func (k6 *K6) ValidateUpdate(old runtime.Object) error {
+	oldK6, ok := old.(*K6)
+	if !ok {
+		return fmt.Errorf("expected K6 but got %T", old)
+	}
+
+	if k6.DeletionTimestamp != nil || equality.Semantic.DeepEqual(oldK6.Spec, k6.Spec) {
+		return nil
+	}
+
+	if err := k6.validateSpec(); err != nil {
+		return err
+	}
+
+	if !oldK6.IsActive() || k6.AllowsRestart() {
+		return nil
+	}
+
+	return fmt.Errorf("spec of K6 %s can't be changed while the test run is in stage %q: "+
+		"set annotation %s=true to restart the test run with the new spec", k6.Name, oldK6.Status.Stage, AllowRestartAnnotation)
+}
+
+// ValidateDelete implements webhook.Validator.
+func (k6 *K6) ValidateDelete() error {
+	return nil
+}
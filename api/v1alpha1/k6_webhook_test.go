@@ -0,0 +1,386 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// validSpec returns a minimal spec that passes validation.
+func validSpec() K6Spec {
+	return K6Spec{
+		Parallelism: 1,
+		Script:      K6Script{ConfigMap: K6Configmap{Name: "test", File: "test.js"}},
+	}
+}
+
+func Test_ValidateUpdate(t *testing.T) {
+	k6 := func(stage Stage, parallelism int32, annotations map[string]string) *K6 {
+		spec := validSpec()
+		spec.Parallelism = parallelism
+		return &K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: annotations},
+			Spec:       spec,
+			Status:     K6Status{Stage: stage},
+		}
+	}
+	allowRestart := map[string]string{AllowRestartAnnotation: "true"}
+
+	tests := []struct {
+		name    string
+		old     *K6
+		new     *K6
+		invalid bool
+	}{
+		{"NotStarted", k6("", 1, nil), k6("", 2, nil), false},
+		{"Started", k6("started", 1, nil), k6("started", 2, nil), true},
+		{"Created", k6("created", 1, nil), k6("created", 2, nil), true},
+		{"SameSpec", k6("started", 1, nil), k6("started", 1, allowRestart), false},
+		{"AllowRestart", k6("started", 1, nil), k6("started", 2, allowRestart), false},
+		{"Finished", k6("finished", 1, nil), k6("finished", 2, nil), false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := test.new.ValidateUpdate(test.old)
+			if test.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ValidateUpdateStoredInvalid(t *testing.T) {
+	// stored before the rule on parallelism was there
+	old := &K6{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: validSpec(), Status: K6Status{Stage: "started"}}
+	old.Spec.Parallelism = 0
+
+	// metadata-only changes, like the abort annotation, are accepted
+	k6 := old.DeepCopy()
+	k6.Annotations = map[string]string{"k6.io/abort": "true"}
+	assert.NoError(t, k6.ValidateUpdate(old))
+
+	// so is removal of the finalizer from the deleted object
+	k6 = old.DeepCopy()
+	now := metav1.Now()
+	k6.DeletionTimestamp = &now
+	k6.Finalizers = nil
+	assert.NoError(t, k6.ValidateUpdate(old))
+
+	// but a changed spec is validated
+	k6 = old.DeepCopy()
+	k6.Annotations = map[string]string{AllowRestartAnnotation: "true"}
+	k6.Spec.Arguments = "--vus 10"
+	assert.Error(t, k6.ValidateUpdate(old))
+}
+
+func Test_ValidateCreatePollInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval *metav1.Duration
+		invalid  bool
+	}{
+		{"Default", nil, false},
+		{"Minimum", &metav1.Duration{Duration: time.Second}, false},
+		{"TooShort", &metav1.Duration{Duration: 500 * time.Millisecond}, true},
+		{"TooLong", &metav1.Duration{Duration: 2 * time.Hour}, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			k6 := &K6{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       validSpec(),
+			}
+			k6.Spec.PollInterval = test.interval
+			err := k6.ValidateCreate()
+			if test.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ValidateCreateMaxFailedRunners(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     *intstr.IntOrString
+		invalid bool
+	}{
+		{"Default", nil, false},
+		{"Number", &intstr.IntOrString{Type: intstr.Int, IntVal: 2}, false},
+		{"Percentage", &intstr.IntOrString{Type: intstr.String, StrVal: "10%"}, false},
+		{"Negative", &intstr.IntOrString{Type: intstr.Int, IntVal: -1}, true},
+		{"NotPercentage", &intstr.IntOrString{Type: intstr.String, StrVal: "ten"}, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			k6 := &K6{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       validSpec(),
+			}
+			k6.Spec.MaxFailedRunners = test.max
+			err := k6.ValidateCreate()
+			if test.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_GetPollInterval(t *testing.T) {
+	spec := K6Spec{}
+	assert.Equal(t, DefaultPollInterval, spec.GetPollInterval())
+
+	spec.PollInterval = &metav1.Duration{Duration: 3 * time.Second}
+	assert.Equal(t, 3*time.Second, spec.GetPollInterval())
+
+	spec.PollInterval = &metav1.Duration{Duration: time.Millisecond}
+	assert.Equal(t, MinPollInterval, spec.GetPollInterval())
+}
+
+func Test_RunnerStages(t *testing.T) {
+	delay := &metav1.Duration{Duration: 30 * time.Second}
+
+	spec := K6Spec{Parallelism: 5, StartupBatchSize: 2, StartupDelay: delay}
+	assert.Equal(t, []RunnerStage{
+		{RunnerCount: 2, Duration: *delay},
+		{RunnerCount: 4, Duration: *delay},
+	}, spec.RunnerStages())
+
+	// a single batch is the same as starting all runners at once
+	spec = K6Spec{Parallelism: 2, StartupBatchSize: 2, StartupDelay: delay}
+	assert.Empty(t, spec.RunnerStages())
+
+	stages := []RunnerStage{{RunnerCount: 1, Duration: *delay}}
+	spec = K6Spec{Parallelism: 5, Stages: stages}
+	assert.Equal(t, stages, spec.RunnerStages())
+}
+
+func Test_ValidateCreateStartupBatches(t *testing.T) {
+	delay := &metav1.Duration{Duration: 30 * time.Second}
+	tests := []struct {
+		name    string
+		spec    K6Spec
+		invalid bool
+	}{
+		{"Batches", K6Spec{StartupBatchSize: 2, StartupDelay: delay}, false},
+		{"NoDelay", K6Spec{StartupBatchSize: 2}, true},
+		{"NoBatchSize", K6Spec{StartupDelay: delay}, true},
+		{"WithStages", K6Spec{StartupBatchSize: 2, StartupDelay: delay, Stages: []RunnerStage{{RunnerCount: 1}}}, true},
+	}
+	for i := range tests {
+		tests[i].spec.Parallelism = 4
+		tests[i].spec.Script = validSpec().Script
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			k6 := &K6{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       test.spec,
+			}
+			err := k6.ValidateCreate()
+			if test.invalid {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ValidateCreateSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*K6Spec)
+		err    string
+	}{
+		{"Valid", func(*K6Spec) {}, ""},
+		{"ZeroParallelism", func(spec *K6Spec) { spec.Parallelism = 0 }, "parallelism of K6 test must be at least 1, got 0"},
+		{"NoScript", func(spec *K6Spec) { spec.Script = K6Script{} },
+			"script of K6 test must be set with one of configMap, volumeClaim, localFile or archiveDownload"},
+		{"TwoScripts", func(spec *K6Spec) {
+			spec.Script.ArchiveDownload = &ArchiveDownload{URI: "https://example.com/archive.tar"}
+		},
+			"script of K6 test must be set only once, got configMap and archiveDownload"},
+		{"Arguments", func(spec *K6Spec) { spec.Arguments = "--no-thresholds --tag env=ci --compatibility-mode=base" }, ""},
+		{"ExecutionSegment", func(spec *K6Spec) { spec.Arguments = "--tag env=ci --execution-segment 0:1/2" },
+			"arguments of K6 test can't set --execution-segment: execution segments of runners are set by the operator"},
+		{"ExecutionSegmentSequence", func(spec *K6Spec) { spec.Arguments = "--execution-segment-sequence=0,1/2,1" },
+			"arguments of K6 test can't set --execution-segment-sequence: execution segments of runners are set by the operator"},
+		{"SegmentWeights", func(spec *K6Spec) { spec.Parallelism, spec.SegmentWeights = 3, []int32{2, 1, 1} }, ""},
+		{"SegmentWeightsCount", func(spec *K6Spec) { spec.Parallelism, spec.SegmentWeights = 3, []int32{2, 1} },
+			"segmentWeights of K6 test must have a weight for each of 3 runners, got 2"},
+		{"ZeroSegmentWeight", func(spec *K6Spec) { spec.Parallelism, spec.SegmentWeights = 2, []int32{1, 0} },
+			"segmentWeights of K6 test must be positive, got [1 0]"},
+		{"NestedFile", func(spec *K6Spec) { spec.Script.ConfigMap.File = "scenarios/test.js" },
+			`file of K6 test must be a key of the configMap, got "scenarios/test.js"`},
+		{"ReservedVolume", func(spec *K6Spec) { spec.Runner.Volumes = []corev1.Volume{{Name: "k6-test-volume"}} },
+			"volume k6-test-volume of the runner of K6 test uses the reserved prefix k6-"},
+		{"ReservedVolumeMount", func(spec *K6Spec) {
+			spec.Initializer = &Pod{VolumeMounts: []corev1.VolumeMount{{Name: "k6-output-volume", MountPath: "/data"}}}
+		},
+			"volumeMount k6-output-volume of the initializer of K6 test uses the reserved prefix k6-"},
+		{"ScriptMountPath", func(spec *K6Spec) {
+			spec.Runner.VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: "/test/"}}
+		},
+			"volumeMount data of the runner of K6 test can't be mounted at /test, use a sub-path of it"},
+		{"DataVolume", func(spec *K6Spec) {
+			spec.Runner.Volumes = []corev1.Volume{{Name: "data"}}
+			spec.Runner.VolumeMounts = []corev1.VolumeMount{{Name: "data", MountPath: "/test/data"}}
+		}, ""},
+		{"InvalidCleanup", func(spec *K6Spec) { spec.Cleanup = "always" }, `cleanup of K6 test must be post or pre, got "always"`},
+		{"CleanupTTL", func(spec *K6Spec) {
+			spec.Cleanup = "post"
+			spec.CleanupTTL = &metav1.Duration{Duration: time.Hour}
+		}, ""},
+		{"CleanupTTLWithoutPost", func(spec *K6Spec) { spec.CleanupTTL = &metav1.Duration{Duration: time.Hour} },
+			"cleanupTTL of K6 test must be a non-negative duration and requires cleanup post, got 1h0m0s"},
+		{"NegativePollInterval", func(spec *K6Spec) { spec.PollInterval = &metav1.Duration{Duration: -time.Second} },
+			"pollInterval of K6 test must be between 1s and 1h0m0s, got -1s"},
+		{"InfluxDBv1", func(spec *K6Spec) {
+			spec.Output = &Output{InfluxDB: &InfluxDBOutput{Address: "http://influxdb:8086", Database: "k6", BasicAuth: &BasicAuth{Username: "k6"}}}
+		}, ""},
+		{"InfluxDBv1Token", func(spec *K6Spec) {
+			spec.Output = &Output{InfluxDB: &InfluxDBOutput{Address: "http://influxdb:8086", TokenSecret: &corev1.SecretKeySelector{Key: "token"}}}
+		},
+			"influxdb v1 output of K6 test must use basicAuth instead of organization and tokenSecret"},
+		{"InfluxDBv2", func(spec *K6Spec) {
+			spec.Output = &Output{InfluxDB: &InfluxDBOutput{
+				Address: "http://influxdb:8086", Version: "v2", Database: "k6", Organization: "qa", TokenSecret: &corev1.SecretKeySelector{Key: "token"},
+			}}
+		}, ""},
+		{"InfluxDBv2NoBucket", func(spec *K6Spec) {
+			spec.Output = &Output{InfluxDB: &InfluxDBOutput{
+				Address: "http://influxdb:8086", Version: "v2", Organization: "qa", TokenSecret: &corev1.SecretKeySelector{Key: "token"},
+			}}
+		},
+			"influxdb v2 output of K6 test must have organization, database and tokenSecret"},
+		{"InfluxDBAddress", func(spec *K6Spec) { spec.Output = &Output{InfluxDB: &InfluxDBOutput{Address: "influxdb"}} },
+			`url of influxdb output of K6 test must be an http or https URL, got "influxdb"`},
+		{"RemoteWriteURL", func(spec *K6Spec) {
+			spec.Output = &Output{PrometheusRemoteWrite: &PrometheusRemoteWriteOutput{URL: "https://prometheus:9090/api/v1/write"}}
+		}, ""},
+		{"RelativeRemoteWriteURL", func(spec *K6Spec) {
+			spec.Output = &Output{PrometheusRemoteWrite: &PrometheusRemoteWriteOutput{URL: "prometheus:9090/api/v1/write"}}
+		},
+			`url of prometheusRemoteWrite output of K6 test must be an http or https URL, got "prometheus:9090/api/v1/write"`},
+		{"RemoteWriteAuth", func(spec *K6Spec) {
+			spec.Output = &Output{PrometheusRemoteWrite: &PrometheusRemoteWriteOutput{
+				URL:               "http://prometheus:9090/api/v1/write",
+				BasicAuth:         &BasicAuth{Username: "k6"},
+				BearerTokenSecret: &corev1.SecretKeySelector{Key: "token"},
+			}}
+		},
+			"prometheusRemoteWrite of K6 test can't have both basicAuth and bearerTokenSecret"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			k6 := &K6{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: validSpec()}
+			test.modify(&k6.Spec)
+			err := k6.ValidateCreate()
+			if len(test.err) == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func Test_ValidateReferences(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	script := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Data:       map[string]string{"test.js": "import { check } from './utils.js';", "utils.js": ""},
+	}
+	token := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: "token", Namespace: "k6-operator", Labels: map[string]string{"k6cloud": "token"},
+	}}
+
+	defer func(opts WebhookOptions) { webhookOptions = opts }(webhookOptions)
+	webhookOptions = WebhookOptions{
+		TokenNamespace: "k6-operator",
+		// the operator parses arguments with types.ParseCLI, which can't be
+		// imported here
+		HasCloudOutput: func(spec *K6Spec) bool { return strings.Contains(spec.Arguments, "cloud") },
+	}
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		modify  func(*K6Spec)
+		err     string
+	}{
+		{"Exist", []client.Object{script, token}, func(spec *K6Spec) { spec.Arguments = "--out cloud" }, ""},
+		{"NoConfigMap", nil, func(*K6Spec) {}, "configMap test with the script of K6 test doesn't exist"},
+		{"SiblingEntrypoint", []client.Object{script}, func(spec *K6Spec) { spec.Script.ConfigMap.File = "utils.js" }, ""},
+		{"NoFile", []client.Object{script}, func(spec *K6Spec) { spec.Script.ConfigMap.File = "main.js" },
+			"configMap test of K6 test has no file main.js"},
+		{"NoSecret", []client.Object{script}, func(spec *K6Spec) { spec.Secrets = &K6Secrets{Name: "env"} },
+			"secret env of K6 test doesn't exist"},
+		{"NoToken", []client.Object{script}, func(spec *K6Spec) { spec.Arguments = "--tag a=b -o cloud" },
+			"K6 test has cloud output but there is no secret with k6 Cloud token in k6-operator"},
+		{"RunnerToken", []client.Object{script}, func(spec *K6Spec) {
+			spec.Arguments = "-o cloud"
+			spec.Runner.Env = []corev1.EnvVar{{Name: "K6_CLOUD_TOKEN", Value: "token"}}
+		}, ""},
+		{"RunnerTokenFrom", []client.Object{script}, func(spec *K6Spec) {
+			spec.Arguments = "-o cloud"
+			spec.Runner.EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "cloud"},
+			}}}
+		}, ""},
+	}
+
+	for _, test := range tests {
+		k6 := &K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}, Spec: validSpec()}
+		test.modify(&k6.Spec)
+		reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(test.objects...).Build()
+
+		err := k6.validateReferences(context.Background(), reader)
+		if len(test.err) == 0 {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.EqualError(t, err, test.err, test.name)
+		}
+	}
+
+	// no secret is needed with the token read from a file
+	webhookOptions.TokenFromFile = true
+	k6 := &K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}, Spec: validSpec()}
+	k6.Spec.Arguments = "-o cloud"
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(script).Build()
+	assert.NoError(t, k6.validateReferences(context.Background(), reader))
+}
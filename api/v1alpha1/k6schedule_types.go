@@ -0,0 +1,88 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConcurrencyPolicy tells what to do when a test run is due while the
+// previous one is still active, as in batch CronJob: Allow runs them at the
+// same time, Forbid skips the new one and Replace deletes the active one.
+// +kubebuilder:validation:Enum=Allow;Forbid;Replace
+type ConcurrencyPolicy string
+
+const (
+	AllowConcurrent   ConcurrencyPolicy = "Allow"
+	ForbidConcurrent  ConcurrencyPolicy = "Forbid"
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// K6ScheduleSpec defines the desired state of K6Schedule
+type K6ScheduleSpec struct {
+	// Schedule in cron format, e.g. "0 3 * * *", evaluated in UTC.
+	Schedule          string            `json:"schedule"`
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	Suspend           bool              `json:"suspend,omitempty"`
+
+	// Number of finished and failed K6 resources to keep, 3 and 1 by default.
+	// +kubebuilder:validation:Minimum=0
+	SuccessfulRunsHistoryLimit *int32 `json:"successfulRunsHistoryLimit,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	FailedRunsHistoryLimit *int32 `json:"failedRunsHistoryLimit,omitempty"`
+
+	Template K6Template `json:"template"`
+}
+
+// K6Template is the K6 resource created on schedule.
+type K6Template struct {
+	Metadata PodMetadata `json:"metadata,omitempty"`
+	Spec     K6Spec      `json:"spec"`
+}
+
+// K6ScheduleStatus defines the observed state of K6Schedule
+type K6ScheduleStatus struct {
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// Active are the names of K6 resources created on schedule which are not
+	// finished yet.
+	Active []string `json:"active,omitempty"`
+}
+
+// K6Schedule is the Schema for the k6schedules API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule",description="Schedule"
+// +kubebuilder:printcolumn:name="Suspend",type="boolean",JSONPath=".spec.suspend",description="Suspend"
+// +kubebuilder:printcolumn:name="Last Schedule",type="date",JSONPath=".status.lastScheduleTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type K6Schedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   K6ScheduleSpec   `json:"spec,omitempty"`
+	Status K6ScheduleStatus `json:"status,omitempty"`
+}
+
+// K6ScheduleList contains a list of K6Schedule
+// +kubebuilder:object:root=true
+type K6ScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []K6Schedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&K6Schedule{}, &K6ScheduleList{})
+}
@@ -24,9 +24,109 @@ package v1alpha1
 import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArchiveDownload) DeepCopyInto(out *ArchiveDownload) {
+	*out = *in
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HeaderSecretRef != nil {
+		in, out := &in.HeaderSecretRef, &out.HeaderSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArchiveDownload.
+func (in *ArchiveDownload) DeepCopy() *ArchiveDownload {
+	if in == nil {
+		return nil
+	}
+	out := new(ArchiveDownload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BasicAuth) DeepCopyInto(out *BasicAuth) {
+	*out = *in
+	in.PasswordSecret.DeepCopyInto(&out.PasswordSecret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BasicAuth.
+func (in *BasicAuth) DeepCopy() *BasicAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(BasicAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaAnnotation) DeepCopyInto(out *GrafanaAnnotation) {
+	*out = *in
+	in.APIKey.DeepCopyInto(&out.APIKey)
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAnnotation.
+func (in *GrafanaAnnotation) DeepCopy() *GrafanaAnnotation {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaAnnotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InfluxDBOutput) DeepCopyInto(out *InfluxDBOutput) {
+	*out = *in
+	if in.PushInterval != nil {
+		in, out := &in.PushInterval, &out.PushInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TokenSecret != nil {
+		in, out := &in.TokenSecret, &out.TokenSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InfluxDBOutput.
+func (in *InfluxDBOutput) DeepCopy() *InfluxDBOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(InfluxDBOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InitContainer) DeepCopyInto(out *InitContainer) {
 	*out = *in
@@ -93,6 +193,56 @@ func (in *K6) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6Build) DeepCopyInto(out *K6Build) {
+	*out = *in
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Build.
+func (in *K6Build) DeepCopy() *K6Build {
+	if in == nil {
+		return nil
+	}
+	out := new(K6Build)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6Cloud) DeepCopyInto(out *K6Cloud) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Cloud.
+func (in *K6Cloud) DeepCopy() *K6Cloud {
+	if in == nil {
+		return nil
+	}
+	out := new(K6Cloud)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6ConfigFile) DeepCopyInto(out *K6ConfigFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6ConfigFile.
+func (in *K6ConfigFile) DeepCopy() *K6ConfigFile {
+	if in == nil {
+		return nil
+	}
+	out := new(K6ConfigFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K6Configmap) DeepCopyInto(out *K6Configmap) {
 	*out = *in
@@ -140,11 +290,130 @@ func (in *K6List) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6Schedule) DeepCopyInto(out *K6Schedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Schedule.
+func (in *K6Schedule) DeepCopy() *K6Schedule {
+	if in == nil {
+		return nil
+	}
+	out := new(K6Schedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *K6Schedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6ScheduleList) DeepCopyInto(out *K6ScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]K6Schedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6ScheduleList.
+func (in *K6ScheduleList) DeepCopy() *K6ScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(K6ScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *K6ScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6ScheduleSpec) DeepCopyInto(out *K6ScheduleSpec) {
+	*out = *in
+	if in.SuccessfulRunsHistoryLimit != nil {
+		in, out := &in.SuccessfulRunsHistoryLimit, &out.SuccessfulRunsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailedRunsHistoryLimit != nil {
+		in, out := &in.FailedRunsHistoryLimit, &out.FailedRunsHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6ScheduleSpec.
+func (in *K6ScheduleSpec) DeepCopy() *K6ScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(K6ScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6ScheduleStatus) DeepCopyInto(out *K6ScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6ScheduleStatus.
+func (in *K6ScheduleStatus) DeepCopy() *K6ScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(K6ScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K6Script) DeepCopyInto(out *K6Script) {
 	*out = *in
 	out.VolumeClaim = in.VolumeClaim
 	out.ConfigMap = in.ConfigMap
+	if in.ArchiveDownload != nil {
+		in, out := &in.ArchiveDownload, &out.ArchiveDownload
+		*out = new(ArchiveDownload)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequiredFiles != nil {
+		in, out := &in.RequiredFiles, &out.RequiredFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Script.
@@ -172,10 +441,25 @@ func (in *K6Scuttle) DeepCopy() *K6Scuttle {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6Secrets) DeepCopyInto(out *K6Secrets) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Secrets.
+func (in *K6Secrets) DeepCopy() *K6Secrets {
+	if in == nil {
+		return nil
+	}
+	out := new(K6Secrets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K6Spec) DeepCopyInto(out *K6Spec) {
 	*out = *in
-	out.Script = in.Script
+	in.Script.DeepCopyInto(&out.Script)
 	if in.Ports != nil {
 		in, out := &in.Ports, &out.Ports
 		*out = make([]v1.ContainerPort, len(*in))
@@ -189,6 +473,87 @@ func (in *K6Spec) DeepCopyInto(out *K6Spec) {
 	in.Starter.DeepCopyInto(&out.Starter)
 	in.Runner.DeepCopyInto(&out.Runner)
 	out.Scuttle = in.Scuttle
+	if in.CleanupTTL != nil {
+		in, out := &in.CleanupTTL, &out.CleanupTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RetryPolicy != nil {
+		in, out := &in.RetryPolicy, &out.RetryPolicy
+		*out = new(RetryPolicy)
+		**out = **in
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = new(K6Secrets)
+		**out = **in
+	}
+	if in.OutputVolume != nil {
+		in, out := &in.OutputVolume, &out.OutputVolume
+		*out = new(OutputVolume)
+		**out = **in
+	}
+	if in.Notify != nil {
+		in, out := &in.Notify, &out.Notify
+		*out = new(Notify)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cloud != nil {
+		in, out := &in.Cloud, &out.Cloud
+		*out = new(K6Cloud)
+		**out = **in
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(Output)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RestAPI != nil {
+		in, out := &in.RestAPI, &out.RestAPI
+		*out = new(RestAPI)
+		**out = **in
+	}
+	if in.MaxLifetime != nil {
+		in, out := &in.MaxLifetime, &out.MaxLifetime
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.StartupTimeout != nil {
+		in, out := &in.StartupTimeout, &out.StartupTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.PollInterval != nil {
+		in, out := &in.PollInterval, &out.PollInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxFailedRunners != nil {
+		in, out := &in.MaxFailedRunners, &out.MaxFailedRunners
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]RunnerStage, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartupDelay != nil {
+		in, out := &in.StartupDelay, &out.StartupDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	if in.SegmentWeights != nil {
+		in, out := &in.SegmentWeights, &out.SegmentWeights
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Spec.
@@ -204,6 +569,64 @@ func (in *K6Spec) DeepCopy() *K6Spec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K6Status) DeepCopyInto(out *K6Status) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StageTime != nil {
+		in, out := &in.StageTime, &out.StageTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StopDeadline != nil {
+		in, out := &in.StopDeadline, &out.StopDeadline
+		*out = (*in).DeepCopy()
+	}
+	if in.RunnerResults != nil {
+		in, out := &in.RunnerResults, &out.RunnerResults
+		*out = make([]RunnerResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.OutputVolume != nil {
+		in, out := &in.OutputVolume, &out.OutputVolume
+		*out = new(OutputVolumeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]MetricSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RunnerEndpoints != nil {
+		in, out := &in.RunnerEndpoints, &out.RunnerEndpoints
+		*out = make([]RunnerEndpoint, len(*in))
+		copy(*out, *in)
+	}
+	if in.ThresholdsPassed != nil {
+		in, out := &in.ThresholdsPassed, &out.ThresholdsPassed
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(TestRunSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ValidationResult)
+		**out = **in
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -223,6 +646,23 @@ func (in *K6Status) DeepCopy() *K6Status {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *K6Template) DeepCopyInto(out *K6Template) {
+	*out = *in
+	in.Metadata.DeepCopyInto(&out.Metadata)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new K6Template.
+func (in *K6Template) DeepCopy() *K6Template {
+	if in == nil {
+		return nil
+	}
+	out := new(K6Template)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *K6VolumeClaim) DeepCopyInto(out *K6VolumeClaim) {
 	*out = *in
@@ -238,6 +678,112 @@ func (in *K6VolumeClaim) DeepCopy() *K6VolumeClaim {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricSnapshot) DeepCopyInto(out *MetricSnapshot) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricSnapshot.
+func (in *MetricSnapshot) DeepCopy() *MetricSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notify) DeepCopyInto(out *Notify) {
+	*out = *in
+	if in.GrafanaAnnotation != nil {
+		in, out := &in.GrafanaAnnotation, &out.GrafanaAnnotation
+		*out = new(GrafanaAnnotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(Webhook)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notify.
+func (in *Notify) DeepCopy() *Notify {
+	if in == nil {
+		return nil
+	}
+	out := new(Notify)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Output) DeepCopyInto(out *Output) {
+	*out = *in
+	if in.InfluxDB != nil {
+		in, out := &in.InfluxDB, &out.InfluxDB
+		*out = new(InfluxDBOutput)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrometheusRemoteWrite != nil {
+		in, out := &in.PrometheusRemoteWrite, &out.PrometheusRemoteWrite
+		*out = new(PrometheusRemoteWriteOutput)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Statsd != nil {
+		in, out := &in.Statsd, &out.Statsd
+		*out = new(StatsdOutput)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Output.
+func (in *Output) DeepCopy() *Output {
+	if in == nil {
+		return nil
+	}
+	out := new(Output)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputVolume) DeepCopyInto(out *OutputVolume) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputVolume.
+func (in *OutputVolume) DeepCopy() *OutputVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputVolumeStatus) DeepCopyInto(out *OutputVolumeStatus) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputVolumeStatus.
+func (in *OutputVolumeStatus) DeepCopy() *OutputVolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputVolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Pod) DeepCopyInto(out *Pod) {
 	*out = *in
@@ -275,6 +821,11 @@ func (in *Pod) DeepCopyInto(out *Pod) {
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	in.SecurityContext.DeepCopyInto(&out.SecurityContext)
+	if in.ContainerSecurityContext != nil {
+		in, out := &in.ContainerSecurityContext, &out.ContainerSecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.EnvFrom != nil {
 		in, out := &in.EnvFrom, &out.EnvFrom
 		*out = make([]v1.EnvFromSource, len(*in))
@@ -299,6 +850,68 @@ func (in *Pod) DeepCopyInto(out *Pod) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ConfigFile != nil {
+		in, out := &in.ConfigFile, &out.ConfigFile
+		*out = new(K6ConfigFile)
+		**out = **in
+	}
+	if in.ActiveDeadlineSeconds != nil {
+		in, out := &in.ActiveDeadlineSeconds, &out.ActiveDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TraceContext != nil {
+		in, out := &in.TraceContext, &out.TraceContext
+		*out = new(TraceContext)
+		**out = **in
+	}
+	if in.SnapshotInterval != nil {
+		in, out := &in.SnapshotInterval, &out.SnapshotInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	in.InitContainerResources.DeepCopyInto(&out.InitContainerResources)
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(K6Build)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Pod.
@@ -339,3 +952,199 @@ func (in *PodMetadata) DeepCopy() *PodMetadata {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusRemoteWriteOutput) DeepCopyInto(out *PrometheusRemoteWriteOutput) {
+	*out = *in
+	if in.PushInterval != nil {
+		in, out := &in.PushInterval, &out.PushInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.BasicAuth != nil {
+		in, out := &in.BasicAuth, &out.BasicAuth
+		*out = new(BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BearerTokenSecret != nil {
+		in, out := &in.BearerTokenSecret, &out.BearerTokenSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusRemoteWriteOutput.
+func (in *PrometheusRemoteWriteOutput) DeepCopy() *PrometheusRemoteWriteOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusRemoteWriteOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestAPI) DeepCopyInto(out *RestAPI) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestAPI.
+func (in *RestAPI) DeepCopy() *RestAPI {
+	if in == nil {
+		return nil
+	}
+	out := new(RestAPI)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerEndpoint) DeepCopyInto(out *RunnerEndpoint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerEndpoint.
+func (in *RunnerEndpoint) DeepCopy() *RunnerEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerResult) DeepCopyInto(out *RunnerResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerResult.
+func (in *RunnerResult) DeepCopy() *RunnerResult {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RunnerStage) DeepCopyInto(out *RunnerStage) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RunnerStage.
+func (in *RunnerStage) DeepCopy() *RunnerStage {
+	if in == nil {
+		return nil
+	}
+	out := new(RunnerStage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatsdOutput) DeepCopyInto(out *StatsdOutput) {
+	*out = *in
+	if in.PushInterval != nil {
+		in, out := &in.PushInterval, &out.PushInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatsdOutput.
+func (in *StatsdOutput) DeepCopy() *StatsdOutput {
+	if in == nil {
+		return nil
+	}
+	out := new(StatsdOutput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TestRunSummary) DeepCopyInto(out *TestRunSummary) {
+	*out = *in
+	if in.FailedMetrics != nil {
+		in, out := &in.FailedMetrics, &out.FailedMetrics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TestRunSummary.
+func (in *TestRunSummary) DeepCopy() *TestRunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(TestRunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TraceContext) DeepCopyInto(out *TraceContext) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TraceContext.
+func (in *TraceContext) DeepCopy() *TraceContext {
+	if in == nil {
+		return nil
+	}
+	out := new(TraceContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValidationResult) DeepCopyInto(out *ValidationResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValidationResult.
+func (in *ValidationResult) DeepCopy() *ValidationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ValidationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Webhook) DeepCopyInto(out *Webhook) {
+	*out = *in
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Webhook.
+func (in *Webhook) DeepCopy() *Webhook {
+	if in == nil {
+		return nil
+	}
+	out := new(Webhook)
+	in.DeepCopyInto(out)
+	return out
+}
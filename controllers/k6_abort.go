@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// abortAnnotation aborts the test run when set on the K6 resource, with its
+// value as the reason.
+const abortAnnotation = "k6.io/abort"
+
+// AbortTestRun stops the test run on request, whatever the stage it is in,
+// and sets it to error with the reason from the annotation. TestRunAborted is
+// set once the runners have stopped, so that they are stopped only once; the
+// cloud test run, if any, is then finalized as aborted by the user.
+func AbortTestRun(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	reason := "test run was aborted"
+	if value := k6.Annotations[abortAnnotation]; len(value) > 0 {
+		reason = fmt.Sprintf("%s: %s", reason, value)
+	}
+	log.Info(fmt.Sprintf("Stopping the test run in stage %q, %s", k6.Status.Stage, reason))
+
+	if !k6.IsTrue(v1alpha1.TestRunAborted) {
+		if err := deleteInitializer(ctx, log, k6, r); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if stopping, err := stopRunners(ctx, log, k6, r); err != nil || stopping > 0 {
+			return ctrl.Result{RequeueAfter: stopping}, err
+		}
+
+		k6.UpdateCondition(v1alpha1.TestRunAborted, metav1.ConditionTrue)
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if retryAfter, err := FinalizeCloudTestRun(ctx, log, k6, r); err != nil {
+		return ctrl.Result{}, err
+	} else if retryAfter > 0 {
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+
+	k6.Status.Stage = "error"
+	k6.Status.Phase = "Failed"
+	k6.Status.PhaseReason = reason
+	if k6.IsTrue(v1alpha1.TestRunRunning) {
+		k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionFalse)
+	}
+
+	if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.recordEvent(k6, corev1.EventTypeWarning, "Aborted", reason)
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	batchv1 "k8s.io/api/batch/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_AbortAnnotation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, timeout time.Duration) {
+		finalizeCloudTestRunFn, gracefulStopTimeout = finalize, timeout
+	}(finalizeCloudTestRunFn, gracefulStopTimeout)
+	// runners of cloud test runs are stopped gracefully, but these never end
+	gracefulStopTimeout = 0
+
+	tests := []struct {
+		name      string
+		k6        *v1alpha1.K6
+		finalized []string
+	}{
+		{"Local", newCloudK6(), nil},
+		{"Cloud", newCloudK6(), []string{"123"}},
+	}
+	// a local test run has no cloud conditions
+	tests[0].k6.Status.TestRunID = ""
+	tests[0].k6.InitializeConditions()
+
+	for _, test := range tests {
+		k6 := test.k6
+		k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionTrue)
+		k6.Annotations = map[string]string{abortAnnotation: "wrong target"}
+
+		runner := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-1", Namespace: "test", Labels: map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"},
+		}}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), runner).Build()
+		r := &K6Reconciler{Client: c, Scheme: scheme, Log: logr.Discard()}
+
+		var finalized []string
+		finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, runStatus cloudapi.RunStatus) error {
+			assert.Equal(t, cloudapi.RunStatusAbortedUser, runStatus, test.name)
+			finalized = append(finalized, refID)
+			return nil
+		}
+
+		_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(k6)})
+		trackedStages.forget(client.ObjectKeyFromObject(k6))
+		assert.NoError(t, err, test.name)
+
+		err = c.Get(context.Background(), client.ObjectKeyFromObject(runner), &batchv1.Job{})
+		assert.True(t, k8sErrors.IsNotFound(err), "%s: expected runner to be deleted, got: %v", test.name, err)
+		assert.Equal(t, test.finalized, finalized, test.name)
+
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+		assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage, test.name)
+		assert.Equal(t, "test run was aborted: wrong target", k6.Status.PhaseReason, test.name)
+		assert.True(t, k6.IsTrue(v1alpha1.TestRunAborted), test.name)
+		assert.True(t, k6.IsFalse(v1alpha1.TestRunRunning), test.name)
+	}
+}
+
+func Test_AbortStopsRunnersOnce(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	// the runners were stopped on an earlier reconcile, before finalization
+	// of the cloud test run failed
+	k6 := newCloudK6()
+	k6.Annotations = map[string]string{abortAnnotation: ""}
+	k6.UpdateCondition(v1alpha1.TestRunAborted, metav1.ConditionTrue)
+	k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+	runner := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-1", Namespace: "test", Labels: map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), runner).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, Log: logr.Discard()}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	_, err := AbortTestRun(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(runner), &batchv1.Job{}))
+	assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage)
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// adoptedRunnerLabels are the labels that runner jobs, their pods and
+// services created out-of-band must have to be adopted by the operator.
+func adoptedRunnerLabels(k6 *v1alpha1.K6) map[string]string {
+	return map[string]string{
+		"app":    "k6",
+		"k6_cr":  k6.Name,
+		"runner": "true",
+	}
+}
+
+// validateAdoptedRunners checks that the runners created out-of-band can be
+// driven by the operator the same way as the ones it creates on its own.
+func validateAdoptedRunners(k6 *v1alpha1.K6, jl []batchv1.Job, sl []corev1.Service) error {
+	// jobs and their pods are selected by the same labels
+	for _, job := range jl {
+		for k, v := range adoptedRunnerLabels(k6) {
+			if job.Labels[k] != v {
+				return fmt.Errorf("runner job %s must have label %s=%s", job.Name, k, v)
+			}
+			if job.Spec.Template.Labels[k] != v {
+				return fmt.Errorf("pods of runner job %s must have label %s=%s", job.Name, k, v)
+			}
+		}
+	}
+
+	indices := map[int32]bool{}
+	for _, service := range sl {
+		index, err := runnerIndex(k6, service.Name)
+		if err != nil || index < 1 || index > k6.Spec.Parallelism {
+			return fmt.Errorf("runner service %s must be named %s-service-<index>, with index between 1 and %d",
+				service.Name, k6.Name, k6.Spec.Parallelism)
+		}
+		if indices[index] {
+			return fmt.Errorf("there is more than one runner service with index %d", index)
+		}
+		indices[index] = true
+
+		var hasAPIPort bool
+		for _, port := range service.Spec.Ports {
+			if port.Port == k6.Spec.RestAPIPort() {
+				hasAPIPort = true
+			}
+		}
+		if !hasAPIPort {
+			return fmt.Errorf("runner service %s must expose k6 REST API at port %d", service.Name, k6.Spec.RestAPIPort())
+		}
+	}
+
+	return nil
+}
+
+// AdoptJobs discovers runner jobs and services created out-of-band instead
+// of creating them, so that the rest of the test run is driven as usual.
+func AdoptJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	log.Info("Adopting existing runner jobs")
+
+	opts := runnerListOptions(k6)
+
+	jl := &batchv1.JobList{}
+	if err := listSelected(ctx, r, jl, opts); err != nil {
+		log.Error(err, "Could not list jobs")
+		return ctrl.Result{}, err
+	}
+
+	sl := &corev1.ServiceList{}
+	if err := listSelected(ctx, r, sl, opts); err != nil {
+		log.Error(err, "Could not list services")
+		return ctrl.Result{}, err
+	}
+
+	if len(jl.Items) < int(k6.Spec.Parallelism) || len(sl.Items) < int(k6.Spec.Parallelism) {
+		setPhase(ctx, log, k6, r, "Pending", fmt.Sprintf("waiting for runners to adopt (%d/%d jobs, %d/%d services)",
+			len(jl.Items), k6.Spec.Parallelism, len(sl.Items), k6.Spec.Parallelism))
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+
+	if err := validateAdoptedRunners(k6, jl.Items, sl.Items); err != nil {
+		log.Error(err, "Runners can't be adopted")
+		r.recordEvent(k6, corev1.EventTypeWarning, "AdoptionFailed", err.Error())
+
+		k6.Status.Stage = "error"
+		k6.Status.Phase = "Failed"
+		k6.Status.PhaseReason = err.Error()
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	log.Info(fmt.Sprintf("Adopted %d runner jobs", len(jl.Items)))
+
+	log.Info("Changing stage of K6 status to created")
+	k6.Status.Stage = "created"
+
+	if updateHappened, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		return ctrl.Result{}, err
+	} else if updateHappened {
+		return ctrl.Result{Requeue: true}, nil
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_validateAdoptedRunners(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2, AdoptExisting: true},
+	}
+
+	job := func(labels map[string]string) batchv1.Job {
+		job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "custom-runner", Labels: map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}}}
+		job.Spec.Template.Labels = labels
+		return job
+	}
+	unlabeled := func(job batchv1.Job) batchv1.Job {
+		job.Labels = map[string]string{"app": "k6"}
+		return job
+	}
+	service := func(name string, port int32) corev1.Service {
+		return corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: port}}},
+		}
+	}
+	labels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+
+	tests := []struct {
+		name     string
+		jobs     []batchv1.Job
+		services []corev1.Service
+		valid    bool
+	}{
+		{
+			"Valid",
+			[]batchv1.Job{job(labels), job(labels)},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-2", 6565)},
+			true,
+		},
+		{
+			"MissingPodLabels",
+			[]batchv1.Job{job(labels), job(map[string]string{"app": "k6"})},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-2", 6565)},
+			false,
+		},
+		{
+			"MissingJobLabels",
+			[]batchv1.Job{job(labels), unlabeled(job(labels))},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-2", 6565)},
+			false,
+		},
+		{
+			"InvalidServiceName",
+			[]batchv1.Job{job(labels), job(labels)},
+			[]corev1.Service{service("test-service-1", 6565), service("test-api", 6565)},
+			false,
+		},
+		{
+			"IndexOutOfRange",
+			[]batchv1.Job{job(labels), job(labels)},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-3", 6565)},
+			false,
+		},
+		{
+			"DuplicateIndex",
+			[]batchv1.Job{job(labels), job(labels)},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-1", 6565)},
+			false,
+		},
+		{
+			"NoAPIPort",
+			[]batchv1.Job{job(labels), job(labels)},
+			[]corev1.Service{service("test-service-1", 6565), service("test-service-2", 8080)},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateAdoptedRunners(k6, test.jobs, test.services)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
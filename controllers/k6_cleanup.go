@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isLeftover returns true if the object was created for a previous K6
+// resource with the same name. Objects of the current one are never leftovers.
+// With requireOwner, only objects owned by a K6 resource are considered:
+// this is for kinds that users may label on their own, like config maps.
+func isLeftover(object metav1.Object, k6 *v1alpha1.K6, requireOwner bool) bool {
+	var owned bool
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.Kind != "K6" {
+			continue
+		}
+		if ref.UID == k6.UID {
+			return false
+		}
+		owned = true
+	}
+	return owned || !requireOwner
+}
+
+// CleanupLeftovers deletes jobs, services and config maps left by previous
+// test runs of a K6 resource with the same name, before the new one starts.
+// The garbage collector may remove them at the same time, so objects that are
+// already gone are skipped.
+func CleanupLeftovers(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	opts := &client.ListOptions{
+		Namespace:     k6.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{"app": "k6", "k6_cr": k6.Name}),
+	}
+	// runners created out-of-band are not owned by anyone
+	requireOwner := k6.Spec.AdoptExisting
+
+	var leftovers []client.Object
+
+	jl := &batchv1.JobList{}
+	if err := r.List(ctx, jl, opts); err != nil {
+		log.Error(err, "Could not list jobs")
+		return err
+	}
+	for i := range jl.Items {
+		if isLeftover(&jl.Items[i], k6, requireOwner) {
+			leftovers = append(leftovers, &jl.Items[i])
+		}
+	}
+
+	sl := &corev1.ServiceList{}
+	if err := r.List(ctx, sl, opts); err != nil {
+		log.Error(err, "Could not list services")
+		return err
+	}
+	for i := range sl.Items {
+		if isLeftover(&sl.Items[i], k6, requireOwner) {
+			leftovers = append(leftovers, &sl.Items[i])
+		}
+	}
+
+	// ConfigMaps are read from the API server, like Secrets and ConfigMaps
+	// of the spec, so that the operator doesn't cache all of them.
+	cl := &corev1.ConfigMapList{}
+	if err := r.apiReader().List(ctx, cl, opts); err != nil {
+		log.Error(err, "Could not list config maps")
+		return err
+	}
+	for i := range cl.Items {
+		if isLeftover(&cl.Items[i], k6, true) {
+			leftovers = append(leftovers, &cl.Items[i])
+		}
+	}
+
+	for _, object := range leftovers {
+		if err := r.Delete(ctx, object, propagationPolicy(k6)); err != nil && !k8sErrors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("Could not delete leftover %s", object.GetName()))
+			return err
+		}
+	}
+
+	if len(leftovers) > 0 {
+		log.Info(fmt.Sprintf("Deleted %d resources left by previous test runs", len(leftovers)))
+	}
+	return nil
+}
+
+// CleanupAfterError deletes the initializer, the runners and the starter of
+// the failed test run, so that they don't hold on to the quota. The K6
+// resource itself is kept with the failure in its status. It returns the time
+// to check again while the runners are being stopped.
+func CleanupAfterError(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	if err := deleteInitializer(ctx, log, k6, r); err != nil {
+		return 0, err
+	}
+	return stopRunners(ctx, log, k6, r)
+}
+
+// CleanupFinished deletes the finished or failed K6 resource with cleanup
+// post. With spec.cleanupTTL, it's deleted only once the TTL has passed since
+// the test run has finished, requeueing until then.
+func CleanupFinished(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	if k6.Spec.Cleanup != "post" {
+		return ctrl.Result{}, nil
+	}
+
+	if ttl := k6.Spec.CleanupTTL; ttl != nil {
+		completed := k6.Status.CompletionTime
+		if completed == nil {
+			// failed test runs don't have completion time
+			completed = k6.Status.StageTime
+		}
+		if completed != nil {
+			if remaining := completed.Add(ttl.Duration).Sub(r.now()); remaining > 0 {
+				log.V(1).Info(fmt.Sprintf("Cleaning up in %s", remaining.Round(time.Second)))
+				return ctrl.Result{RequeueAfter: remaining}, nil
+			}
+		}
+	}
+
+	log.Info("Cleaning up all resources")
+	if err := r.Delete(ctx, k6, propagationPolicy(k6)); err != nil && !k8sErrors.IsNotFound(err) {
+		log.Error(err, "Could not delete K6 resource")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_CleanupLeftovers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "current"},
+		Spec:       v1alpha1.K6Spec{Cleanup: "pre"},
+	}
+	labels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+	meta := func(name, ownerUID string) metav1.ObjectMeta {
+		meta := metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels}
+		if len(ownerUID) > 0 {
+			meta.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "k6.io/v1alpha1",
+				Kind:       "K6",
+				Name:       "test",
+				UID:        types.UID(ownerUID),
+			}}
+		}
+		return meta
+	}
+
+	exists := func(c client.Client, object client.Object, name string) bool {
+		return c.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "test"}, object) == nil
+	}
+
+	t.Run("FreshNamespace", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).Build()
+		r := &K6Reconciler{Client: c}
+		assert.NoError(t, CleanupLeftovers(context.Background(), logr.Discard(), k6, r))
+	})
+
+	t.Run("LeftoverRunners", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&batchv1.Job{ObjectMeta: meta("test-1", "previous")},
+			&batchv1.Job{ObjectMeta: meta("test-2", "")},
+			&batchv1.Job{ObjectMeta: meta("test-initializer", "current")},
+			&corev1.Service{ObjectMeta: meta("test-service-1", "previous")},
+			&corev1.ConfigMap{ObjectMeta: meta("test-config", "previous")},
+			// e.g. the script, labeled by the user
+			&corev1.ConfigMap{ObjectMeta: meta("test-script", "")},
+		).Build()
+		r := &K6Reconciler{Client: c}
+
+		assert.NoError(t, CleanupLeftovers(context.Background(), logr.Discard(), k6, r))
+		assert.False(t, exists(c, &batchv1.Job{}, "test-1"))
+		assert.False(t, exists(c, &batchv1.Job{}, "test-2"))
+		assert.True(t, exists(c, &batchv1.Job{}, "test-initializer"))
+		assert.False(t, exists(c, &corev1.Service{}, "test-service-1"))
+		assert.False(t, exists(c, &corev1.ConfigMap{}, "test-config"))
+		assert.True(t, exists(c, &corev1.ConfigMap{}, "test-script"))
+
+		// running it again is a no-op
+		assert.NoError(t, CleanupLeftovers(context.Background(), logr.Discard(), k6, r))
+	})
+}
+
+func Test_CleanupOnError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	for _, cleanupOnError := range []bool{false, true} {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{CleanupOnError: cleanupOnError},
+			Status:     v1alpha1.K6Status{Stage: "error"},
+		}
+		initializer := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-initializer", Namespace: "test"}}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), initializer).Build()
+		r := &K6Reconciler{Client: c}
+
+		_, err := r.reconcileStage(context.Background(), logr.Discard(), k6)
+		assert.NoError(t, err)
+
+		current := &v1alpha1.K6{}
+		assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test", Namespace: "test"}, current))
+		assert.Equal(t, v1alpha1.Phase("Failed"), current.Status.Phase, "the failure must be recorded in status")
+
+		err = c.Get(context.Background(), types.NamespacedName{Name: "test-initializer", Namespace: "test"}, &batchv1.Job{})
+		if cleanupOnError {
+			assert.Error(t, err, "initializer should be deleted with cleanupOnError")
+		} else {
+			assert.NoError(t, err, "initializer should be kept without cleanupOnError")
+		}
+	}
+}
+
+func Test_CleanupFinishedTTL(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	completed := time.Date(2023, time.March, 15, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		stage v1alpha1.Stage
+		// completion time is set for finished test runs only
+		completionTime *metav1.Time
+		stageTime      *metav1.Time
+	}{
+		{"Finished", "finished", &metav1.Time{Time: completed}, &metav1.Time{Time: completed}},
+		{"Error", "error", nil, &metav1.Time{Time: completed}},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{Cleanup: "post", CleanupTTL: &metav1.Duration{Duration: 10 * time.Minute}},
+			Status:     v1alpha1.K6Status{Stage: test.stage, CompletionTime: test.completionTime, StageTime: test.stageTime},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+		clock := clocktesting.NewFakePassiveClock(completed.Add(5 * time.Minute))
+		r := &K6Reconciler{Client: c, Scheme: scheme, Clock: clock}
+
+		// kept before the TTL, with a requeue until it passes
+		res, err := CleanupFinished(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, 5*time.Minute, res.RequeueAfter, test.name)
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), &v1alpha1.K6{}), test.name)
+
+		// and deleted after it
+		clock.SetTime(completed.Add(10 * time.Minute))
+		res, err = CleanupFinished(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err, test.name)
+		assert.Zero(t, res.RequeueAfter, test.name)
+		assert.True(t, k8sErrors.IsNotFound(c.Get(context.Background(), client.ObjectKeyFromObject(k6), &v1alpha1.K6{})), test.name)
+	}
+
+	// without TTL, the resource is deleted right away
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Cleanup: "post"},
+		Status:     v1alpha1.K6Status{Stage: "finished", CompletionTime: &metav1.Time{Time: completed}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, Clock: clocktesting.NewFakePassiveClock(completed)}
+	_, err := CleanupFinished(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, k8sErrors.IsNotFound(c.Get(context.Background(), client.ObjectKeyFromObject(k6), &v1alpha1.K6{})))
+}
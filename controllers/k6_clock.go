@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// clockSkewThreshold is the difference between clocks of the operator and
+// a runner above which timing of the test run can't be trusted.
+const clockSkewThreshold = 5 * time.Second
+
+// clockSkew estimates the difference between the clock of the runner and the
+// clock of the operator from the Date header of k6 REST API response.
+// The header has only a precision of a second so the estimate is off by up
+// to half a second. It returns false if the response has no usable Date header.
+func clockSkew(resp *http.Response, sent, received time.Time) (time.Duration, bool) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, false
+	}
+
+	// The Date is truncated to a second and is set somewhere between sending
+	// the request and receiving the response, so compare the middle points.
+	runnerTime := date.Add(500 * time.Millisecond)
+	operatorTime := sent.Add(received.Sub(sent) / 2)
+	return runnerTime.Sub(operatorTime), true
+}
+
+// absDuration is a helper for comparing skews in both directions.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// reportClockSkew sets the ClockSkew condition depending on the largest skew
+// measured between the operator and the runners.
+func reportClockSkew(log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, skew time.Duration) {
+	if absDuration(skew) <= clockSkewThreshold {
+		k6.UpdateCondition(v1alpha1.ClockSkew, metav1.ConditionFalse)
+		return
+	}
+
+	msg := fmt.Sprintf("clocks of runners and the operator differ by up to %v, timing of the test run may be inaccurate", skew.Round(time.Second))
+	log.Info(msg)
+
+	if !k6.IsTrue(v1alpha1.ClockSkew) {
+		k6.UpdateCondition(v1alpha1.ClockSkew, metav1.ConditionTrue)
+		r.recordEvent(k6, v1.EventTypeWarning, "ClockSkew", msg)
+	}
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_clockSkew(t *testing.T) {
+	sent := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	received := sent.Add(time.Second)
+
+	tests := []struct {
+		name string
+		date string
+		skew time.Duration
+		ok   bool
+	}{
+		{"InSync", "Wed, 01 Mar 2023 12:00:00 GMT", 0, true},
+		{"RunnerAhead", "Wed, 01 Mar 2023 12:00:30 GMT", 30 * time.Second, true},
+		{"RunnerBehind", "Wed, 01 Mar 2023 11:59:00 GMT", -time.Minute, true},
+		{"NoDate", "", 0, false},
+		{"InvalidDate", "yesterday", 0, false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			resp := &http.Response{Header: http.Header{}}
+			if len(test.date) > 0 {
+				resp.Header.Set("Date", test.date)
+			}
+
+			skew, ok := clockSkew(resp, sent, received)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.skew, skew)
+		})
+	}
+}
@@ -25,14 +25,24 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/grafana/k6-operator/api/v1alpha1"
 	"github.com/grafana/k6-operator/pkg/cloud"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -41,11 +51,143 @@ import (
 
 const k6CrLabelName = "k6_cr"
 
+// runnerListOptions selects runner jobs, pods and services of the K6 resource.
+func runnerListOptions(k6 *v1alpha1.K6) *client.ListOptions {
+	selector := jobs.NewRunnerLabels(k6)
+	if k6.Spec.AdoptExisting {
+		// runners created out-of-band can't know the UID or the run ID
+		selector = adoptedRunnerLabels(k6)
+	}
+	return &client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector),
+		Namespace:     k6.Namespace,
+	}
+}
+
+// propagationPolicy returns the propagation policy for deletions made by the
+// operator: with Foreground, jobs are gone only after all their pods are.
+func propagationPolicy(k6 *v1alpha1.K6) client.PropagationPolicy {
+	if len(k6.Spec.PropagationPolicy) > 0 {
+		return client.PropagationPolicy(k6.Spec.PropagationPolicy)
+	}
+	return client.PropagationPolicy(metav1.DeletePropagationBackground)
+}
+
+// initializerListOptions selects the initializer pod of the K6 resource.
+func initializerListOptions(k6 *v1alpha1.K6) *client.ListOptions {
+	selector := jobs.NewLabels(k6)
+	selector["job-name"] = fmt.Sprintf("%s-initializer", k6.Name)
+	return &client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(selector),
+		Namespace:     k6.Namespace,
+	}
+}
+
+// listSelected lists the objects selected by opts. Resources created before
+// the operator labeled them with the UID and the run ID, e.g. by a test run
+// that was in progress while the operator was upgraded, are listed by the
+// other labels if nothing else is found. Only those controlled by the K6
+// resource, or by one of its jobs in case of pods, are kept: the other labels
+// can be shared with resources that aren't created by the operator.
+func listSelected(ctx context.Context, c client.Reader, list client.ObjectList, opts *client.ListOptions) error {
+	if err := c.List(ctx, list, opts); err != nil || meta.LenList(list) > 0 || opts.LabelSelector == nil {
+		return err
+	}
+
+	requirements, _ := opts.LabelSelector.Requirements()
+	legacy := labels.NewSelector()
+	var uid types.UID
+	for _, requirement := range requirements {
+		switch requirement.Key() {
+		case "k6_cr_uid":
+			if values := requirement.Values().List(); len(values) == 1 {
+				uid = types.UID(values[0])
+			}
+		case "k6_run_id":
+		default:
+			legacy = legacy.Add(requirement)
+		}
+	}
+	if len(uid) == 0 {
+		return nil
+	}
+	noUID, err := labels.NewRequirement("k6_cr_uid", selection.DoesNotExist, nil)
+	if err != nil {
+		return err
+	}
+	if err := c.List(ctx, list, &client.ListOptions{LabelSelector: legacy.Add(*noUID), Namespace: opts.Namespace}); err != nil {
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	controlledJobs := map[types.UID]bool{}
+	owned := items[:0]
+	for _, item := range items {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		ref := metav1.GetControllerOf(obj)
+		if ref == nil || (ref.UID != uid && ref.Kind != "Job") {
+			continue
+		}
+		if ref.UID != uid {
+			controlled, ok := controlledJobs[ref.UID]
+			if !ok {
+				job := &batchv1.Job{}
+				err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: obj.GetNamespace()}, job)
+				if err != nil && !k8sErrors.IsNotFound(err) {
+					return err
+				}
+				jobRef := metav1.GetControllerOf(job)
+				controlled = err == nil && job.UID == ref.UID && jobRef != nil && jobRef.UID == uid
+				controlledJobs[ref.UID] = controlled
+			}
+			if !controlled {
+				continue
+			}
+		}
+		owned = append(owned, item)
+	}
+	return meta.SetList(list, owned)
+}
+
 // K6Reconciler reconciles a K6 object
 type K6Reconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// MaxConcurrentReconciles is the number of K6 resources reconciled at
+	// the same time, 1 if unset.
+	MaxConcurrentReconciles int
+	// FailureBaseDelay and FailureMaxDelay bound the backoff of reconciles
+	// that returned an error, 1s and 5m if unset.
+	FailureBaseDelay time.Duration
+	FailureMaxDelay  time.Duration
+	// CloudTokenMaxWait is how long a cloud test run waits for k6 Cloud
+	// token before it's set to error, 10m if unset.
+	CloudTokenMaxWait time.Duration
+	// CloudTokenNamespace is where the secret with k6cloud=token label is
+	// looked for, k6-operator-system if unset.
+	CloudTokenNamespace string
+	// CloudTokenFile is the file k6 Cloud token is read from instead of the
+	// secret with k6cloud=token label, if set.
+	CloudTokenFile *TokenFile
+
+	// APIReader reads from the API server rather than the cache of Client,
+	// where a stale read can't be retried away. Client is used if unset.
+	APIReader client.Reader
+
+	// Clock is the real clock unless it's replaced in tests.
+	Clock clock.PassiveClock
+
+	// cloudClients are the k6 Cloud clients of the tokens in use.
+	cloudClients cloud.Clients
 }
 
 // Reconcile takes a K6 object and takes the appropriate action in the cluster
@@ -54,35 +196,110 @@ type K6Reconciler struct {
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods;pods/log,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;create;delete
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;create;update
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
-func (r *K6Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *K6Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (res ctrl.Result, err error) {
 	log := r.Log.WithValues("namespace", req.Namespace, "name", req.Name, "reconcileID", controller.ReconcileIDFromContext(ctx))
 
 	// Fetch the CRD
 	k6 := &v1alpha1.K6{}
-	err := r.Get(ctx, req.NamespacedName, k6)
+	err = r.Get(ctx, req.NamespacedName, k6)
 	if err != nil {
 		if k8sErrors.IsNotFound(err) {
 			log.Info("Request deleted. Nothing to reconcile.")
+			trackedStages.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Could not fetch request")
-		return ctrl.Result{Requeue: true}, err
+		// requeue is handled by the rate limiter
+		return ctrl.Result{}, err
+	}
+
+	trackedStages.set(k6)
+	stage := k6.Status.Stage
+	defer func() {
+		if err != nil {
+			reconcileErrors.WithLabelValues(stageLabel(stage)).Inc()
+		}
+	}()
+
+	// the stage is the one the reconcile has started in
+	log = log.WithValues("stage", stageLabel(stage))
+	if len(k6.Status.RunID) > 0 {
+		log = log.WithValues("runId", k6.Status.RunID)
+	}
+	if len(k6.Status.TestRunID) > 0 {
+		log = log.WithValues("testRunId", k6.Status.TestRunID)
 	}
 
 	log.Info(fmt.Sprintf("Reconcile(); stage = %s", k6.Status.Stage))
 
+	if done, res, err := r.reconcileCloudFinalizer(ctx, log, k6); done || err != nil {
+		return res, err
+	}
+
+	// The spec can change during an active test run only with restart
+	// allowed, as validated by the webhook.
+	if k6.IsActive() && k6.AllowsRestart() &&
+		k6.Status.ObservedGeneration > 0 && k6.Generation != k6.Status.ObservedGeneration {
+		return RestartTestRun(ctx, log, k6, r)
+	}
+
+	if _, ok := k6.Annotations[abortAnnotation]; ok && k6.Status.Stage != "finished" && k6.Status.Stage != "error" {
+		return AbortTestRun(ctx, log, k6, r)
+	}
+
+	if k6.IsTrue(v1alpha1.StartupFailed) && k6.Status.Stage != "error" {
+		return failStartup(ctx, log, k6, r, k6.Status.PhaseReason)
+	}
+
+	if k6.Spec.MaxLifetime != nil && k6.Status.Stage != "finished" && k6.Status.Stage != "error" {
+		left := k6.Spec.MaxLifetime.Duration - r.now().Sub(k6.CreationTimestamp.Time)
+		if left <= 0 {
+			return ExpireTestRun(ctx, log, k6, r)
+		}
+
+		res, err := r.reconcileStage(ctx, log, k6)
+		// make sure to be back in time to enforce the max lifetime
+		if err == nil && (res.RequeueAfter == 0 || res.RequeueAfter > left) {
+			res.RequeueAfter = left
+		}
+		return res, err
+	}
+
+	return r.reconcileStage(ctx, log, k6)
+}
+
+// reconcileStage takes the action appropriate for the current stage of the test run.
+func (r *K6Reconciler) reconcileStage(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6) (ctrl.Result, error) {
+	var err error
+
 	// Decision making here is now a mix between stages and conditions.
 	// TODO: refactor further.
 
 	switch k6.Status.Stage {
 	case "":
+		if k6.Spec.Cleanup == "pre" {
+			if err := CleanupLeftovers(ctx, log, k6, r); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		log.Info("Initialize test")
 
 		k6.InitializeConditions()
+		k6.Status.ObservedGeneration = k6.Generation
+		if len(k6.Status.RunID) == 0 {
+			k6.Status.RunID = string(uuid.NewUUID())
+		}
+		k6.Status.Phase = "Pending"
+		k6.Status.PhaseReason = "waiting for the initializer to inspect the script"
 
 		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
 			return ctrl.Result{}, err
@@ -155,52 +372,126 @@ func (r *K6Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Re
 			return ctrl.Result{}, nil
 		}
 
+		// Test runs can take a long time and usually they aren't supposed
+		// to be too quick. So check in only periodically.
+		requeueAfter := k6.Spec.GetPollInterval()
+
+		if k6.Status.StartedRunners < k6.Spec.Parallelism {
+			nextStage, err := StartStagedRunners(ctx, log, k6, r)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if nextStage > 0 && nextStage < requeueAfter {
+				requeueAfter = nextStage
+			}
+		}
+
+		if k6.Spec.Runner.SnapshotInterval != nil {
+			nextSnapshot, err := SnapshotMetrics(ctx, log, k6, r)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if nextSnapshot > 0 && nextSnapshot < requeueAfter {
+				requeueAfter = nextSnapshot
+			}
+		}
+
 		// wait for the test to finish
 		if !FinishJobs(ctx, log, k6, r) {
-			// Test runs can take a long time and usually they aren't supposed
-			// to be too quick. So check in only periodically.
-			return ctrl.Result{RequeueAfter: time.Second * 15}, nil
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
 		}
 
 		log.Info("All runner pods are finished")
 
+		if err = CollectRunnerResults(ctx, log, k6, r); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if retried, err := RetryJobs(ctx, log, k6, r); err != nil {
+			return ctrl.Result{}, err
+		} else if retried {
+			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+		}
+
+		// Thresholds are read only once: the runners that could be reached
+		// at first may have exited by the next reconcile.
+		if !k6.IsTrue(v1alpha1.ThresholdsCollected) {
+			if err = CollectThresholds(ctx, log, k6, r); err != nil {
+				return ctrl.Result{}, err
+			}
+			if _, err = r.UpdateStatus(ctx, k6, log); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		// now mark it as finished
 
 		if k6.IsTrue(v1alpha1.TestRunRunning) {
-			k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionFalse)
+			// The cloud test run is finalized first so that the test run is
+			// marked as finished only once it's done or given up on.
+			if retryAfter, err := FinalizeCloudTestRun(ctx, log, k6, r); err != nil {
+				return ctrl.Result{}, err
+			} else if retryAfter > 0 {
+				return ctrl.Result{RequeueAfter: retryAfter}, nil
+			}
 
-			log.Info("Changing stage of K6 status to finished")
-			k6.Status.Stage = "finished"
+			r.recordEvent(k6, v1.EventTypeNormal, "Stopped", fmt.Sprintf("All %d runners have stopped", k6.Spec.Parallelism))
+			summary := r.recordSummary(k6)
 
-			// If this is a test run with cloud output, try to finalize it.
-			if k6.IsTrue(v1alpha1.CloudTestRun) && k6.IsFalse(v1alpha1.CloudTestRunFinalized) {
-				if err = cloud.FinishTestRun(k6.Status.TestRunID); err != nil {
-					log.Error(err, "Failed to finalize the test run with cloud output")
-					return ctrl.Result{}, nil
-				} else {
-					log.Info(fmt.Sprintf("Cloud test run %s was finalized succesfully", k6.Status.TestRunID))
+			k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionFalse)
 
-					k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
-				}
+			if k6.Spec.FailOnThresholdBreach && !*k6.Status.ThresholdsPassed {
+				reason := thresholdsFailure(k6.Status.Summary)
+				r.recordEvent(k6, v1.EventTypeWarning, "ThresholdsFailed", reason)
+
+				log.Info("Changing stage of K6 status to error")
+				k6.Status.Stage = "error"
+				k6.Status.Phase = "Failed"
+				k6.Status.PhaseReason = reason
+			} else {
+				log.Info("Changing stage of K6 status to finished")
+				k6.Status.Stage = "finished"
 			}
 
-			_, err := r.UpdateStatus(ctx, k6, log)
+			updateHappened, err := r.UpdateStatus(ctx, k6, log)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
 			// log.Info(fmt.Sprintf("Debug updating status after finalize %v", updateHappened))
+
+			if updateHappened {
+				annotate(ctx, log, k6, r, fmt.Sprintf("%s/%s: %s", k6.Namespace, k6.Name, summary))
+			}
 		}
 
 		return ctrl.Result{}, nil
 
 	case "error", "finished":
-		// delete if configured
-		if k6.Spec.Cleanup == "post" {
-			log.Info("Cleaning up all resources")
-			r.Delete(ctx, k6)
+		if k6.Status.Phase != "Succeeded" && k6.Status.Phase != "Failed" {
+			if k6.Status.Stage == "error" {
+				setPhase(ctx, log, k6, r, "Failed", "test run has failed, see events and logs of the operator")
+			} else if eventType, _, message := summary(k6, r.now()); eventType == v1.EventTypeNormal {
+				setPhase(ctx, log, k6, r, "Succeeded", message)
+			} else {
+				setPhase(ctx, log, k6, r, "Failed", message)
+			}
 		}
+
 		// notify if configured
-		return ctrl.Result{}, nil
+		if retryAfter, err := notifyWebhook(ctx, log, k6, r); err != nil || retryAfter > 0 {
+			return ctrl.Result{RequeueAfter: retryAfter}, err
+		}
+
+		// failed runs keep their resources unless configured otherwise,
+		// after the failure was recorded in status
+		if k6.Status.Stage == "error" && k6.Spec.CleanupOnError {
+			if stopping, err := CleanupAfterError(ctx, log, k6, r); err != nil || stopping > 0 {
+				return ctrl.Result{RequeueAfter: stopping}, err
+			}
+		}
+
+		// delete if configured
+		return CleanupFinished(ctx, log, k6, r)
 	}
 
 	err = fmt.Errorf("invalid status")
@@ -210,75 +501,185 @@ func (r *K6Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Re
 
 // SetupWithManager sets up a managed controller that will reconcile all events for the K6 CRD
 func (r *K6Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder != nil {
+		r.Recorder = runIDRecorder{r.Recorder}
+	}
+	if r.CloudTokenFile != nil {
+		// clients of a rotated token are not used anymore
+		r.CloudTokenFile.onRotate = r.cloudClients.ForgetToken
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.K6{}).
-		Owns(&batchv1.Job{}).
+		// Jobs are watched by label rather than ownership so that runners
+		// adopted with adoptExisting are watched too.
+		Watches(&source.Kind{Type: &batchv1.Job{}},
+			handler.EnqueueRequestsFromMapFunc(k6Requests),
+			builder.WithPredicates(predicate.NewPredicateFuncs(hasK6Label), jobStatusChanged)).
 		Watches(&source.Kind{Type: &v1.Pod{}},
-			handler.EnqueueRequestsFromMapFunc(
-				func(object client.Object) []reconcile.Request {
-					pod := object.(*v1.Pod)
-					k6CrName, ok := pod.GetLabels()[k6CrLabelName]
-					if !ok {
-						return nil
-					}
-					return []reconcile.Request{
-						{NamespacedName: types.NamespacedName{
-							Name:      k6CrName,
-							Namespace: object.GetNamespace(),
-						}}}
-				}),
-			builder.WithPredicates(predicate.NewPredicateFuncs(
-				func(object client.Object) bool {
-					pod := object.(*v1.Pod)
-					_, ok := pod.GetLabels()[k6CrLabelName]
-					if !ok {
-						return false
-					}
-					return true
-				}))).
-		WithOptions(controller.Options{
-			MaxConcurrentReconciles: 1,
-			// RateLimiter - ?
-		}).
+			handler.EnqueueRequestsFromMapFunc(k6Requests),
+			builder.WithPredicates(predicate.NewPredicateFuncs(hasK6Label))).
+		WithOptions(r.controllerOptions()).
 		Complete(r)
 }
 
-func (r *K6Reconciler) UpdateStatus(ctx context.Context, k6 *v1alpha1.K6, log logr.Logger) (updateHappened bool, err error) {
-	proposedStatus := k6.Status
+// k6Requests maps a job or a pod to the K6 resource it belongs to.
+func k6Requests(object client.Object) []reconcile.Request {
+	k6CrName, ok := object.GetLabels()[k6CrLabelName]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{
+			Name:      k6CrName,
+			Namespace: object.GetNamespace(),
+		}}}
+}
 
-	// re-fetch resource
-	err = r.Get(ctx, types.NamespacedName{Namespace: k6.Namespace, Name: k6.Name}, k6)
-	if err != nil {
-		if k8sErrors.IsNotFound(err) {
-			log.Info("Request deleted. No status to update.")
-			return false, nil
+func hasK6Label(object client.Object) bool {
+	_, ok := object.GetLabels()[k6CrLabelName]
+	return ok
+}
+
+// jobStatusChanged passes updates of jobs only when their status has changed,
+// e.g. a runner has failed after hitting its backoff limit.
+var jobStatusChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldJob, ok := e.ObjectOld.(*batchv1.Job)
+		if !ok {
+			return false
 		}
-		log.Error(err, "Could not fetch request")
-		return false, err
+		newJob, ok := e.ObjectNew.(*batchv1.Job)
+		if !ok {
+			return false
+		}
+		return !equality.Semantic.DeepEqual(oldJob.Status, newJob.Status)
+	},
+}
+
+// controllerOptions returns the options of the controller. Reconciles of the
+// same K6 resource are never concurrent, so any number of them is safe. Failed
+// reconciles back off while the ones requeued after a delay keep their delay.
+func (r *K6Reconciler) controllerOptions() controller.Options {
+	maxConcurrentReconciles := r.MaxConcurrentReconciles
+	if maxConcurrentReconciles < 1 {
+		maxConcurrentReconciles = 1
+	}
+	return controller.Options{
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RateLimiter:             newRateLimiter(r.FailureBaseDelay, r.FailureMaxDelay),
 	}
+}
 
-	cleanObj := k6.DeepCopyObject().(client.Object)
+// apiReader returns the reader that bypasses the cache.
+func (r *K6Reconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
 
-	// Update only if it's truly a newer version of the resource
-	// in comparison to the recently fetched resource.
-	isNewer := k6.Status.SetIfNewer(proposedStatus)
-	if !isNewer {
-		return false, nil
+func (r *K6Reconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
 	}
+	return r.Clock.Now()
+}
 
-	err = r.Client.Status().Patch(ctx, k6, client.MergeFrom(cleanObj))
+// setRunTimes records the time the runners were started when the test run
+// enters started stage and the time it has finished when it enters finished
+// stage. Both are set only once.
+func setRunTimes(k6 *v1alpha1.K6) {
+	switch k6.Status.Stage {
+	case "started":
+		if k6.Status.StartTime == nil {
+			k6.Status.StartTime = k6.Status.StageTime
+		}
+	case "finished":
+		if k6.Status.CompletionTime != nil {
+			return
+		}
+		k6.Status.CompletionTime = k6.Status.StageTime
+		if start := k6.Status.StartTime; start != nil && !k6.Status.CompletionTime.Before(start) {
+			// times are kept with a precision of a second in the resource
+			k6.Status.Duration = k6.Status.CompletionTime.Truncate(time.Second).Sub(start.Truncate(time.Second)).String()
+		}
+	}
+}
 
-	// TODO: look into retry.RetryOnConflict(retry.DefaultRetry, func() error{...})
-	// to have retries of failing update here, in case of conflicts;
-	// with optional retry bool arg probably.
+// UpdateStatus applies the status of k6 to the latest version of the resource
+// if it's newer, retrying on conflicts with other writers.
+func (r *K6Reconciler) UpdateStatus(ctx context.Context, k6 *v1alpha1.K6, log logr.Logger) (updateHappened bool, err error) {
+	return r.updateStatus(ctx, k6, log, true)
+}
 
-	// TODO: what if resource was deleted right before Patch?
-	// Add a check for IsNotFound(err).
+// UpdateStatusNoRetry is UpdateStatus for callers that prefer to handle
+// conflicts on their own.
+func (r *K6Reconciler) UpdateStatusNoRetry(ctx context.Context, k6 *v1alpha1.K6, log logr.Logger) (updateHappened bool, err error) {
+	return r.updateStatus(ctx, k6, log, false)
+}
+
+func (r *K6Reconciler) updateStatus(ctx context.Context, k6 *v1alpha1.K6, log logr.Logger, retryOnConflict bool) (updateHappened bool, err error) {
+	proposedStatus := k6.Status
+	var previous *v1alpha1.K6
+
+	// The cache is likely to be as stale after a conflict as it was before
+	// it, so retries fetch the resource from the API server.
+	var reader client.Reader = r.Client
+	update := func() error {
+		updateHappened = false
+
+		// re-fetch resource
+		err := reader.Get(ctx, types.NamespacedName{Namespace: k6.Namespace, Name: k6.Name}, k6)
+		reader = r.apiReader()
+		if err != nil {
+			return err
+		}
+
+		cleanObj := k6.DeepCopyObject().(client.Object)
+		previous = cleanObj.(*v1alpha1.K6)
+
+		// Update only if it's truly a newer version of the resource
+		// in comparison to the recently fetched resource.
+		if isNewer := k6.Status.SetIfNewer(proposedStatus); !isNewer {
+			return nil
+		}
+		if k6.Status.Stage != previous.Status.Stage {
+			k6.Status.StageTime = &metav1.Time{Time: r.now()}
+			setRunTimes(k6)
+		}
+
+		// The patch fails with a conflict if the resource was changed since
+		// it was fetched, so that SetIfNewer is evaluated again.
+		if err := r.Client.Status().Patch(ctx, k6, client.MergeFromWithOptions(cleanObj, client.MergeFromWithOptimisticLock{})); err != nil {
+			return err
+		}
+		updateHappened = true
+		return nil
+	}
 
+	if retryOnConflict {
+		err = retry.RetryOnConflict(retry.DefaultRetry, update)
+	} else {
+		err = update()
+	}
+
+	if k8sErrors.IsNotFound(err) {
+		log.Info("Request deleted. No status to update.")
+		return false, nil
+	}
 	if err != nil {
 		log.Error(err, "Could not update status of custom resource")
 		return false, err
 	}
 
-	return true, nil
+	if updateHappened {
+		if k6.Status.Stage != previous.Status.Stage {
+			observeStageChange(previous, k6.Status.StageTime.Time)
+			r.recordStageChange(k6)
+		}
+		trackedStages.set(k6)
+	}
+
+	return updateHappened, nil
 }
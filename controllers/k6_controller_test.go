@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// conflictingClient changes the K6 resource right before the first patches
+// of its status, like another writer would.
+type conflictingClient struct {
+	client.Client
+	conflicts int
+}
+
+func (c *conflictingClient) Status() client.SubResourceWriter {
+	return &conflictingStatusWriter{SubResourceWriter: c.Client.Status(), c: c}
+}
+
+type conflictingStatusWriter struct {
+	client.SubResourceWriter
+	c *conflictingClient
+}
+
+func (w *conflictingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if w.c.conflicts > 0 {
+		w.c.conflicts--
+
+		other := &v1alpha1.K6{}
+		if err := w.c.Client.Get(ctx, client.ObjectKeyFromObject(obj), other); err != nil {
+			return err
+		}
+		other.Status.Phase = "Running"
+		if err := w.c.Client.Status().Update(ctx, other); err != nil {
+			return err
+		}
+	}
+	return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+}
+
+// staleClient reads the K6 resource from a cache that never catches up.
+type staleClient struct {
+	client.Client
+	cached *v1alpha1.K6
+}
+
+func (c *staleClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if k6, ok := obj.(*v1alpha1.K6); ok {
+		c.cached.DeepCopyInto(k6)
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func Test_UpdateStatusConflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newReconciler := func(conflicts int) (*K6Reconciler, *v1alpha1.K6) {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Status:     v1alpha1.K6Status{Stage: "created", Phase: "Pending"},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+		return &K6Reconciler{Client: &conflictingClient{Client: c, conflicts: conflicts}}, k6
+	}
+	get := func(r *K6Reconciler) *v1alpha1.K6 {
+		k6 := &v1alpha1.K6{}
+		assert.NoError(t, r.Get(context.Background(), types.NamespacedName{Name: "test", Namespace: "test"}, k6))
+		return k6
+	}
+
+	r, k6 := newReconciler(2)
+	k6.Status.RunID = "run"
+	updated, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	// the change of the other writer is kept
+	status := get(r).Status
+	assert.Equal(t, "run", status.RunID)
+	assert.Equal(t, v1alpha1.Phase("Running"), status.Phase)
+
+	r, k6 = newReconciler(1)
+	k6.Status.RunID = "run"
+	updated, err = r.UpdateStatusNoRetry(context.Background(), k6, logr.Discard())
+	assert.True(t, k8sErrors.IsConflict(err), "expected a conflict, got: %v", err)
+	assert.False(t, updated)
+	assert.Empty(t, get(r).Status.RunID)
+
+	// retries bypass the stale cache
+	r, k6 = newReconciler(0)
+	cached := get(r)
+	other := cached.DeepCopy()
+	other.Status.Phase = "Running"
+	assert.NoError(t, r.Status().Update(context.Background(), other))
+	r.APIReader = r.Client
+	r.Client = &staleClient{Client: r.Client, cached: cached}
+	k6.Status.RunID = "run"
+	updated, err = r.UpdateStatus(context.Background(), k6, logr.Discard())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	latest := &v1alpha1.K6{}
+	assert.NoError(t, r.APIReader.Get(context.Background(), types.NamespacedName{Name: "test", Namespace: "test"}, latest))
+	assert.Equal(t, "run", latest.Status.RunID)
+	assert.Equal(t, v1alpha1.Phase("Running"), latest.Status.Phase)
+}
+
+func Test_StartedRequeueAfter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, batchv1.AddToScheme(scheme))
+
+	tests := []struct {
+		name         string
+		pollInterval *metav1.Duration
+		requeueAfter time.Duration
+	}{
+		{"Default", nil, 15 * time.Second},
+		{"Custom", &metav1.Duration{Duration: 3 * time.Second}, 3 * time.Second},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{Parallelism: 1, PollInterval: test.pollInterval},
+			Status:     v1alpha1.K6Status{Stage: "started", StartedRunners: 1},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+		r := &K6Reconciler{Client: c}
+
+		res, err := r.reconcileStage(context.Background(), logr.Discard(), k6)
+		assert.NoError(t, err)
+		assert.Equal(t, test.requeueAfter, res.RequeueAfter, test.name)
+	}
+}
+
+func Test_UpdateStatusRunTimes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "times", Namespace: "test"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	update := func(stage v1alpha1.Stage) v1alpha1.K6Status {
+		k6.Status.Stage = stage
+		_, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+		assert.NoError(t, err)
+		stored := &v1alpha1.K6{}
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), stored))
+		return stored.Status
+	}
+
+	for _, stage := range []v1alpha1.Stage{"initialization", "initialized", "created"} {
+		status := update(stage)
+		assert.Nil(t, status.StartTime, stage)
+		assert.Nil(t, status.CompletionTime, stage)
+	}
+
+	status := update("started")
+	if assert.NotNil(t, status.StartTime) {
+		assert.Equal(t, status.StageTime, status.StartTime)
+	}
+	assert.Nil(t, status.CompletionTime)
+	start := status.StartTime
+
+	// the test runs for a while
+	k6.Status.StartTime = &metav1.Time{Time: start.Add(-5*time.Minute - 3*time.Second)}
+	assert.NoError(t, c.Status().Update(context.Background(), &v1alpha1.K6{ObjectMeta: k6.ObjectMeta, Status: k6.Status}))
+
+	status = update("finished")
+	if assert.NotNil(t, status.CompletionTime) {
+		assert.False(t, status.CompletionTime.Before(status.StartTime))
+		assert.Equal(t, status.StageTime, status.CompletionTime)
+		duration, err := time.ParseDuration(status.Duration)
+		assert.NoError(t, err)
+		assert.Equal(t, status.CompletionTime.Sub(status.StartTime.Time), duration)
+		assert.GreaterOrEqual(t, duration, 5*time.Minute+3*time.Second)
+	}
+	assert.True(t, status.StartTime.Equal(k6.Status.StartTime))
+
+	// both are set only once
+	proposed := status.DeepCopy()
+	later := metav1.NewTime(status.CompletionTime.Add(time.Hour))
+	proposed.StartTime, proposed.CompletionTime, proposed.Duration = &later, &later, "1h"
+	duration := status.Duration
+	assert.False(t, status.SetIfNewer(*proposed))
+	assert.Equal(t, duration, status.Duration)
+}
+
+func Test_ControllerOptions(t *testing.T) {
+	tests := []struct {
+		maxConcurrentReconciles, expected int
+	}{
+		{0, 1},
+		{1, 1},
+		{4, 4},
+	}
+
+	for _, test := range tests {
+		r := &K6Reconciler{MaxConcurrentReconciles: test.maxConcurrentReconciles}
+		options := r.controllerOptions()
+		assert.Equal(t, test.expected, options.MaxConcurrentReconciles)
+		assert.NotNil(t, options.RateLimiter)
+	}
+
+	r := &K6Reconciler{FailureBaseDelay: 10 * time.Second, FailureMaxDelay: time.Minute}
+	limiter := r.controllerOptions().RateLimiter
+	for _, expected := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute} {
+		delay := limiter.When("k6")
+		assert.GreaterOrEqual(t, delay, expected)
+		assert.LessOrEqual(t, delay, time.Duration(float64(expected)*(1+failureJitter)))
+	}
+}
+
+func Test_JobWatchEnqueuesFailedRunner(t *testing.T) {
+	runnerJob := func(labels map[string]string) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-1", Namespace: "test", Labels: labels},
+			Status:     batchv1.JobStatus{Active: 1},
+		}
+	}
+	failed := func(job *batchv1.Job) *batchv1.Job {
+		job = job.DeepCopy()
+		job.Status.Active = 0
+		job.Status.Failed = 1
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: "True", Reason: "BackoffLimitExceeded"}}
+		return job
+	}
+	relabeled := func(job *batchv1.Job) *batchv1.Job {
+		job = job.DeepCopy()
+		job.Labels["team"] = "qa"
+		return job
+	}
+
+	labeled := runnerJob(map[string]string{k6CrLabelName: "test", "runner": "true"})
+	tests := []struct {
+		name     string
+		old, new *batchv1.Job
+		enqueued []reconcile.Request
+	}{
+		{"Failed", labeled, failed(labeled), []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "test", Namespace: "test"}}}},
+		{"NotLabeled", runnerJob(map[string]string{}), failed(runnerJob(map[string]string{})), nil},
+		{"StatusUnchanged", labeled, relabeled(labeled), nil},
+	}
+
+	predicates := []predicate.Predicate{predicate.NewPredicateFuncs(hasK6Label), jobStatusChanged}
+	for _, test := range tests {
+		queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		e := event.UpdateEvent{ObjectOld: test.old, ObjectNew: test.new}
+
+		passed := true
+		for _, p := range predicates {
+			passed = passed && p.Update(e)
+		}
+		if passed {
+			handler.EnqueueRequestsFromMapFunc(k6Requests).Update(e, queue)
+		}
+
+		var enqueued []reconcile.Request
+		for queue.Len() > 0 {
+			item, _ := queue.Get()
+			enqueued = append(enqueued, item.(reconcile.Request))
+			queue.Done(item)
+		}
+		assert.Equal(t, test.enqueued, enqueued, test.name)
+		queue.ShutDown()
+	}
+}
+
+func Test_listSelectedLegacyLabels(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, batchv1.AddToScheme(scheme))
+	assert.NoError(t, corev1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "uid"}}
+	k6.Status.RunID = "run"
+	controlledBy := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		controller := true
+		return []metav1.OwnerReference{{Kind: kind, Name: name, UID: uid, Controller: &controller}}
+	}
+	runner := func(name string, labels map[string]string, owners []metav1.OwnerReference) *batchv1.Job {
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", UID: types.UID(name), Labels: labels, OwnerReferences: owners}}
+	}
+	legacyLabels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+	legacy := runner("test-1", legacyLabels, controlledBy("K6", "test", "uid"))
+	other := runner("test-2", map[string]string{"app": "k6", "k6_cr": "test", "runner": "true", "k6_cr_uid": "other"}, nil)
+	current := runner("test-3", map[string]string{"app": "k6", "k6_cr": "test", "runner": "true", "k6_cr_uid": "uid", "k6_run_id": "run"}, nil)
+	// not created by the operator but with the same labels
+	unrelated := runner("test-4", legacyLabels, nil)
+	recreated := runner("test-5", legacyLabels, controlledBy("K6", "test", "previous-uid"))
+
+	names := func(objects ...client.Object) []string {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+		jl := &batchv1.JobList{}
+		assert.NoError(t, listSelected(context.Background(), c, jl, runnerListOptions(k6)))
+		var names []string
+		for _, job := range jl.Items {
+			names = append(names, job.Name)
+		}
+		return names
+	}
+
+	// runners created before the upgrade of the operator are still found
+	assert.Equal(t, []string{"test-1"}, names(legacy, other, unrelated, recreated))
+	// but only if there are no runners with the UID
+	assert.Equal(t, []string{"test-3"}, names(legacy, other, current))
+	// and never those of another K6 resource or of nobody
+	assert.Empty(t, names(other, unrelated, recreated))
+
+	// pods are controlled by the jobs of the K6 resource
+	pod := func(name string, owners []metav1.OwnerReference) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: legacyLabels, OwnerReferences: owners}}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		legacy, unrelated,
+		pod("test-1-abcde", controlledBy("Job", "test-1", "test-1")),
+		pod("test-4-abcde", controlledBy("Job", "test-4", "test-4")),
+		pod("standalone", nil),
+	).Build()
+	pl := &corev1.PodList{}
+	assert.NoError(t, listSelected(context.Background(), c, pl, runnerListOptions(k6)))
+	var podNames []string
+	for _, pod := range pl.Items {
+		podNames = append(podNames, pod.Name)
+	}
+	assert.Equal(t, []string{"test-1-abcde"}, podNames)
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -23,28 +25,54 @@ func CreateJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reco
 		token string // only for cloud output tests
 	)
 
-	if k6.IsTrue(v1alpha1.CloudTestRun) && k6.IsTrue(v1alpha1.CloudTestRunCreated) {
-		log = log.WithValues("testRunId", k6.Status.TestRunID)
+	if k6.Spec.AdoptExisting {
+		return AdoptJobs(ctx, log, k6, r)
+	}
 
+	if k6.IsTrue(v1alpha1.CloudTestRun) && k6.IsTrue(v1alpha1.CloudTestRunCreated) {
 		var tokenReady bool
-		token, tokenReady, err = loadToken(ctx, log, r)
+		token, tokenReady, err = cloudToken(ctx, log, k6, r)
 		if err != nil {
 			// An error here means a very likely mis-configuration of the token.
 			// Consider updating status to error to let a user know quicker?
 			log.Error(err, "A problem while getting token.")
+			r.recordEvent(k6, corev1.EventTypeWarning, "TokenNotFound", err.Error())
 			return ctrl.Result{}, nil
 		}
 		if !tokenReady {
-			return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+			return waitForToken(ctx, log, k6, r)
 		}
 	}
 
 	log.Info("Creating test jobs")
 
-	if res, err = createJobSpecs(ctx, log, k6, r, token); err != nil {
+	if res, err = createJobSpecs(ctx, log, k6, r, token); err != nil || !res.IsZero() {
 		return res, err
 	}
 
+	if err = createRunnersService(ctx, log, k6, r); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if k6.Spec.Runner.PDB {
+		if err = createRunnerPDB(ctx, log, k6, r); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if k6.Spec.Output != nil && k6.Spec.Output.ScrapeMetrics {
+		if err = createServiceMonitor(ctx, log, k6, r); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if k6.Spec.OutputVolume != nil {
+		k6.Status.OutputVolume = &v1alpha1.OutputVolumeStatus{ClaimName: k6.Spec.OutputVolume.ClaimName}
+		for i := 1; i <= int(k6.Spec.Parallelism); i++ {
+			k6.Status.OutputVolume.Paths = append(k6.Status.OutputVolume.Paths, jobs.OutputSubPath(i))
+		}
+	}
+
 	log.Info("Changing stage of K6 status to created")
 	k6.Status.Stage = "created"
 
@@ -63,19 +91,41 @@ func createJobSpecs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 		Namespace: k6.Namespace,
 	}
 
-	if err := r.Get(ctx, namespacedName, found); err == nil || !errors.IsNotFound(err) {
+	err := r.Get(ctx, namespacedName, found)
+	if err == nil && found.DeletionTimestamp != nil {
+		// jobs of the previous attempt are still being removed
+		log.Info("Waiting for the runners of the previous attempt to be deleted")
+		setPhase(ctx, log, k6, r, "Pending", "waiting for the runners of the previous attempt to be deleted")
+		return ctrl.Result{RequeueAfter: time.Second * 2}, nil
+	}
+	if err == nil || !errors.IsNotFound(err) {
 		log.Info("Could not start a new test, Make sure you've deleted your previous run.")
 		return ctrl.Result{}, err
 	}
 
 	for i := 1; i <= int(k6.Spec.Parallelism); i++ {
-		if err := launchTest(ctx, k6, i, log, r, token); err != nil {
+		if err := launchTest(ctx, k6, i, log, r, token); stderrors.Is(err, errRunnerSpec) {
+			// retrying won't fix the spec so fail the test run
+			r.recordEvent(k6, corev1.EventTypeWarning, "InvalidRunnerSpec", err.Error())
+
+			k6.Status.Stage = "error"
+			k6.Status.Phase = "Failed"
+			k6.Status.PhaseReason = err.Error()
+			if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		} else if err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 	return ctrl.Result{}, nil
 }
 
+// errRunnerSpec is returned by launchTest when a runner job can't be
+// generated from the spec of K6.
+var errRunnerSpec = stderrors.New("invalid spec of runners")
+
 func launchTest(ctx context.Context, k6 *v1alpha1.K6, index int, log logr.Logger, r *K6Reconciler, token string) error {
 	var job *batchv1.Job
 	var service *corev1.Service
@@ -86,7 +136,7 @@ func launchTest(ctx context.Context, k6 *v1alpha1.K6, index int, log logr.Logger
 
 	if job, err = jobs.NewRunnerJob(k6, index, token); err != nil {
 		log.Error(err, "Failed to generate k6 test job")
-		return err
+		return fmt.Errorf("%w: %v", errRunnerSpec, err)
 	}
 
 	log.Info(fmt.Sprintf("Runner job is ready to start with image `%s` and command `%s`",
@@ -99,6 +149,7 @@ func launchTest(ctx context.Context, k6 *v1alpha1.K6, index int, log logr.Logger
 
 	if err = r.Create(ctx, job); err != nil {
 		log.Error(err, "Failed to launch k6 test")
+		r.recordEvent(k6, corev1.EventTypeWarning, "JobCreationFailed", fmt.Sprintf("Failed to create runner job %s: %v", job.Name, err))
 		return err
 	}
 
@@ -114,8 +165,73 @@ func launchTest(ctx context.Context, k6 *v1alpha1.K6, index int, log logr.Logger
 
 	if err = r.Create(ctx, service); err != nil {
 		log.Error(err, "Failed to launch k6 test services")
+		r.recordEvent(k6, corev1.EventTypeWarning, "JobCreationFailed", fmt.Sprintf("Failed to create runner service %s: %v", service.Name, err))
 		return err
 	}
 
 	return nil
 }
+
+// createRunnersService creates the headless service of all runners, unless
+// it exists already, e.g. from the previous attempt. It's deleted with the K6
+// resource.
+func createRunnersService(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	service := jobs.NewRunnersService(k6)
+	if err := ctrl.SetControllerReference(k6, service, r.Scheme); err != nil {
+		log.Error(err, "Failed to set controller reference for the headless service")
+		return err
+	}
+
+	if err := r.Create(ctx, service); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "Failed to create the headless service of runners")
+		r.recordEvent(k6, corev1.EventTypeWarning, "JobCreationFailed", fmt.Sprintf("Failed to create runner service %s: %v", service.Name, err))
+		return err
+	}
+	return nil
+}
+
+// createRunnerPDB creates the PodDisruptionBudget of runner pods, unless it
+// exists already, e.g. from the previous attempt. It's deleted with the K6
+// resource.
+func createRunnerPDB(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	pdb := jobs.NewRunnerPDB(k6)
+	if err := ctrl.SetControllerReference(k6, pdb, r.Scheme); err != nil {
+		log.Error(err, "Failed to set controller reference for the pod disruption budget")
+		return err
+	}
+
+	if err := r.Create(ctx, pdb); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "Failed to create the pod disruption budget of runners")
+		r.recordEvent(k6, corev1.EventTypeWarning, "PDBCreationFailed", fmt.Sprintf("Failed to create pod disruption budget %s: %v", pdb.Name, err))
+		return err
+	}
+	return nil
+}
+
+// createServiceMonitor creates the ServiceMonitor of runner services, unless
+// it exists already. On clusters without Prometheus Operator, there is
+// nothing to create.
+func createServiceMonitor(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	gvk := jobs.ServiceMonitorGVK
+	if _, err := r.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.Info("ServiceMonitor is not available in the cluster, runner metrics won't be scraped")
+			return nil
+		}
+		log.Error(err, "Failed to look up ServiceMonitor in the cluster")
+		return err
+	}
+
+	serviceMonitor := jobs.NewRunnerServiceMonitor(k6)
+	if err := ctrl.SetControllerReference(k6, serviceMonitor, r.Scheme); err != nil {
+		log.Error(err, "Failed to set controller reference for the service monitor")
+		return err
+	}
+
+	if err := r.Create(ctx, serviceMonitor); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "Failed to create the service monitor of runners")
+		r.recordEvent(k6, corev1.EventTypeWarning, "ServiceMonitorCreationFailed", fmt.Sprintf("Failed to create service monitor %s: %v", serviceMonitor.GetName(), err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_CreateRunnerPDB(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2, Runner: v1alpha1.Pod{PDB: true}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	assert.NoError(t, createRunnerPDB(context.Background(), logr.Discard(), k6, r))
+	// the one of the previous attempt is kept
+	assert.NoError(t, createRunnerPDB(context.Background(), logr.Discard(), k6, r))
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test-runners", Namespace: "test"}, pdb))
+	assert.True(t, metav1.IsControlledBy(pdb, k6))
+	assert.Equal(t, 2, pdb.Spec.MinAvailable.IntValue())
+}
+
+func Test_CreateServiceMonitor(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2, Output: &v1alpha1.Output{ScrapeMetrics: true}},
+	}
+	key := types.NamespacedName{Name: "test-runners", Namespace: "test"}
+
+	// without Prometheus Operator, there is nothing to do
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+	assert.NoError(t, createServiceMonitor(context.Background(), logr.Discard(), k6, r))
+
+	serviceMonitor := &unstructured.Unstructured{}
+	serviceMonitor.SetGroupVersionKind(jobs.ServiceMonitorGVK)
+	err := c.Get(context.Background(), key, serviceMonitor)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected no service monitor, got: %v", err)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(jobs.ServiceMonitorGVK, meta.RESTScopeNamespace)
+	c = fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(k6).Build()
+	r = &K6Reconciler{Client: c, Scheme: scheme}
+	assert.NoError(t, createServiceMonitor(context.Background(), logr.Discard(), k6, r))
+	// the one of the previous attempt is kept
+	assert.NoError(t, createServiceMonitor(context.Background(), logr.Discard(), k6, r))
+
+	assert.NoError(t, c.Get(context.Background(), key, serviceMonitor))
+	assert.True(t, metav1.IsControlledBy(serviceMonitor, k6))
+}
+
+func Test_CreateRunnersService(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	assert.NoError(t, createRunnersService(context.Background(), logr.Discard(), k6, r))
+	// the one of the previous attempt is kept
+	assert.NoError(t, createRunnersService(context.Background(), logr.Discard(), k6, r))
+
+	service := &corev1.Service{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test-runners", Namespace: "test"}, service))
+	assert.True(t, metav1.IsControlledBy(service, k6))
+	assert.Equal(t, corev1.ClusterIPNone, service.Spec.ClusterIP)
+
+	// it's not one of the runner services
+	sl := &corev1.ServiceList{}
+	assert.NoError(t, c.List(context.Background(), sl, runnerListOptions(k6)))
+	assert.Empty(t, sl.Items)
+}
+
+func Test_CreateJobsInvalidRunnerSpec(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 2,
+			Script:      v1alpha1.K6Script{ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"}},
+			Arguments:   "--execution-segment=0:1/2",
+		},
+		Status: v1alpha1.K6Status{Stage: "initialized"},
+	}
+	k6.InitializeConditions()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	res, err := CreateJobs(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, res.IsZero())
+
+	stored := &v1alpha1.K6{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test", Namespace: "test"}, stored))
+	assert.Equal(t, v1alpha1.Stage("error"), stored.Status.Stage)
+	assert.Equal(t, v1alpha1.Phase("Failed"), stored.Status.Phase)
+	assert.Contains(t, stored.Status.PhaseReason, "--execution-segment")
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"go.k6.io/k6/cloudapi"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// cloudFinalizer keeps a deleted K6 resource until its cloud test run is
+// finalized, so that the test run doesn't show as running in k6 Cloud forever.
+const cloudFinalizer = "k6.io/cloud-test-run"
+
+var (
+	// cloudFinalizeTimeout bounds the retries of finalization on deletion:
+	// once it has passed, the K6 resource is deleted anyway.
+	cloudFinalizeTimeout = 5 * time.Minute
+	cloudFinalizeRetry   = 10 * time.Second
+
+	// cloudFinalizeAttempts bounds the attempts to finalize the cloud test
+	// run at the end of the test, made with exponential backoff starting at
+	// cloudFinalizeBackoff.
+	cloudFinalizeAttempts int32 = 5
+	cloudFinalizeBackoff        = 5 * time.Second
+
+	// finalizeCloudTestRunFn and checkCloudTestRun are replaced in tests.
+	finalizeCloudTestRunFn = finishCloudTestRun
+	checkCloudTestRun      = isCloudTestRunFinished
+)
+
+// cloudRunStatus returns the run status to finalize the cloud test run with:
+// test runs aborted on request are marked as such in k6 Cloud.
+func cloudRunStatus(k6 *v1alpha1.K6) cloudapi.RunStatus {
+	if k6.IsTrue(v1alpha1.TestRunAborted) {
+		return cloudapi.RunStatusAbortedUser
+	}
+	return cloudapi.RunStatusFinished
+}
+
+// needsCloudFinalization returns true if the cloud test run was created and
+// neither finalized nor given up on yet.
+func needsCloudFinalization(k6 *v1alpha1.K6) bool {
+	return k6.IsTrue(v1alpha1.CloudTestRun) && k6.IsTrue(v1alpha1.CloudTestRunCreated) &&
+		!k6.IsTrue(v1alpha1.CloudTestRunFinalized) && !k6.IsTrue(v1alpha1.CloudFinalizeFailed) &&
+		len(k6.Status.TestRunID) > 0
+}
+
+// reconcileCloudFinalizer adds the finalizer while the cloud test run needs
+// finalization and removes it afterwards. On deletion, it stops the runners
+// and finalizes the cloud test run first, retrying until cloudFinalizeTimeout.
+// It returns true if the reconcile ends here, i.e. the resource is deleted.
+func (r *K6Reconciler) reconcileCloudFinalizer(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6) (bool, ctrl.Result, error) {
+	hasFinalizer := controllerutil.ContainsFinalizer(k6, cloudFinalizer)
+
+	if k6.DeletionTimestamp.IsZero() {
+		switch needs := needsCloudFinalization(k6); {
+		case needs && !hasFinalizer:
+			return false, ctrl.Result{}, r.patchFinalizer(ctx, log, k6, controllerutil.AddFinalizer)
+		case !needs && hasFinalizer:
+			return false, ctrl.Result{}, r.patchFinalizer(ctx, log, k6, controllerutil.RemoveFinalizer)
+		}
+		return false, ctrl.Result{}, nil
+	}
+
+	// the resource is being deleted: there is nothing else to reconcile
+	if !hasFinalizer {
+		return true, ctrl.Result{}, nil
+	}
+
+	if needsCloudFinalization(k6) {
+		if stopping, err := stopRunners(ctx, log, k6, r); err != nil || stopping > 0 {
+			return true, ctrl.Result{RequeueAfter: stopping}, err
+		}
+
+		client, err := r.cloudClient(ctx, log, k6)
+		if err == nil {
+			err = finalizeCloudTestRunFn(client, k6.Status.TestRunID, cloudRunStatus(k6))
+			r.checkCloudAuth(client, err)
+		}
+		if err != nil {
+			if r.now().Sub(k6.DeletionTimestamp.Time) < cloudFinalizeTimeout {
+				log.Error(err, fmt.Sprintf("Failed to finalize cloud test run %s of deleted K6, retrying", k6.Status.TestRunID))
+				r.recordEvent(k6, corev1.EventTypeWarning, "CloudFinalizeFailed",
+					fmt.Sprintf("Failed to finalize cloud test run %s: %v", k6.Status.TestRunID, err))
+				return true, ctrl.Result{RequeueAfter: cloudFinalizeRetry}, nil
+			}
+
+			log.Error(err, fmt.Sprintf("Giving up on finalization of cloud test run %s after %s", k6.Status.TestRunID, cloudFinalizeTimeout))
+			r.recordEvent(k6, corev1.EventTypeWarning, "CloudFinalizeFailed",
+				fmt.Sprintf("Gave up on finalization of cloud test run %s after %s: %v", k6.Status.TestRunID, cloudFinalizeTimeout, err))
+		} else {
+			log.Info(fmt.Sprintf("Cloud test run %s of deleted K6 was finalized", k6.Status.TestRunID))
+		}
+	}
+
+	return true, ctrl.Result{}, r.patchFinalizer(ctx, log, k6, controllerutil.RemoveFinalizer)
+}
+
+// patchFinalizer adds or removes the cloud finalizer. The resource is
+// patched rather than updated so that defaults applied to the spec by the
+// operator are not persisted.
+func (r *K6Reconciler) patchFinalizer(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6,
+	change func(client.Object, string) bool) error {
+	patch := client.MergeFrom(k6.DeepCopy())
+	change(k6, cloudFinalizer)
+	if err := r.Patch(ctx, k6, patch); err != nil && !k8sErrors.IsNotFound(err) {
+		log.Error(err, "Could not update finalizers of the K6 resource")
+		return err
+	}
+	return nil
+}
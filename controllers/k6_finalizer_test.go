@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func newCloudK6() *v1alpha1.K6 {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 1,
+			Runner:      v1alpha1.Pod{Env: []corev1.EnvVar{{Name: "K6_CLOUD_TOKEN", Value: "token"}}},
+		},
+		Status: v1alpha1.K6Status{Stage: "started", TestRunID: "123"},
+	}
+	k6.InitializeConditions()
+	k6.UpdateCondition(v1alpha1.CloudTestRun, metav1.ConditionTrue)
+	k6.UpdateCondition(v1alpha1.CloudTestRunCreated, metav1.ConditionTrue)
+	k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionFalse)
+	return k6
+}
+
+func Test_CloudFinalizerOnDeletion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, timeout, stopTimeout time.Duration) {
+		finalizeCloudTestRunFn, cloudFinalizeTimeout, gracefulStopTimeout = finalize, timeout, stopTimeout
+	}(finalizeCloudTestRunFn, cloudFinalizeTimeout, gracefulStopTimeout)
+	// runners of cloud test runs are stopped gracefully, but this one never ends
+	gracefulStopTimeout = 0
+
+	runner := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-1", Namespace: "test", Labels: map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"},
+	}}
+	k6 := newCloudK6()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), runner).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+	get := func() error {
+		return c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6)
+	}
+
+	// the finalizer is added once the cloud test run is created
+	done, _, err := r.reconcileCloudFinalizer(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.NoError(t, get())
+	assert.True(t, controllerutil.ContainsFinalizer(k6, cloudFinalizer))
+
+	assert.NoError(t, c.Delete(context.Background(), k6))
+	assert.NoError(t, get())
+	assert.False(t, k6.DeletionTimestamp.IsZero())
+
+	// failed finalization is retried
+	var finalized []string
+	finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, _ cloudapi.RunStatus) error {
+		finalized = append(finalized, refID)
+		return errors.New("unavailable")
+	}
+	done, res, err := r.reconcileCloudFinalizer(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, cloudFinalizeRetry, res.RequeueAfter)
+	assert.NoError(t, get())
+
+	// runners are stopped before finalization
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(runner), &batchv1.Job{})
+	assert.True(t, k8sErrors.IsNotFound(err), "expected runner to be deleted, got: %v", err)
+
+	finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, _ cloudapi.RunStatus) error {
+		finalized = append(finalized, refID)
+		return nil
+	}
+	done, res, err = r.reconcileCloudFinalizer(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Zero(t, res.RequeueAfter)
+	assert.Equal(t, []string{"123", "123"}, finalized)
+
+	err = get()
+	assert.True(t, k8sErrors.IsNotFound(err), "expected K6 to be deleted, got: %v", err)
+}
+
+func Test_CloudFinalizerTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, timeout time.Duration) {
+		finalizeCloudTestRunFn, cloudFinalizeTimeout = finalize, timeout
+	}(finalizeCloudTestRunFn, cloudFinalizeTimeout)
+	finalizeCloudTestRunFn = func(*cloudapi.Client, string, cloudapi.RunStatus) error { return errors.New("unavailable") }
+	cloudFinalizeTimeout = 0
+
+	k6 := newCloudK6()
+	k6.Finalizers = []string{cloudFinalizer}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	assert.NoError(t, c.Delete(context.Background(), k6))
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+
+	// deletion is not blocked forever
+	done, res, err := r.reconcileCloudFinalizer(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	assert.True(t, done)
+	assert.Zero(t, res.RequeueAfter)
+
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6)
+	assert.True(t, k8sErrors.IsNotFound(err), "expected K6 to be deleted, got: %v", err)
+}
+
+func Test_CloudFinalizerRemovedOnceFinalized(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := newCloudK6()
+	k6.Finalizers = []string{cloudFinalizer}
+	k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	done, _, err := r.reconcileCloudFinalizer(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+	assert.Empty(t, k6.Finalizers)
+}
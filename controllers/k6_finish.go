@@ -3,47 +3,49 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/cloud"
+	"github.com/grafana/k6-operator/pkg/results"
 	batchv1 "k8s.io/api/batch/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // FinishJobs checks if the runners pods have finished execution.
 func FinishJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (allFinished bool) {
-	if len(k6.Status.TestRunID) > 0 {
-		log = log.WithValues("testRunId", k6.Status.TestRunID)
-	}
-
 	log.Info("Checking if all runner pods are finished")
 
-	selector := labels.SelectorFromSet(map[string]string{
-		"app":    "k6",
-		"k6_cr":  k6.Name,
-		"runner": "true",
-	})
-
-	opts := &client.ListOptions{LabelSelector: selector, Namespace: k6.Namespace}
+	opts := runnerListOptions(k6)
 	jl := &batchv1.JobList{}
 	var err error
 
-	if err = r.List(ctx, jl, opts); err != nil {
+	if err = listSelected(ctx, r, jl, opts); err != nil {
 		log.Error(err, "Could not list jobs")
 		return
 	}
 
-	// TODO: We should distinguish between Suceeded/Failed/Unknown
-	var finished int32
+	// Job status is authoritative here: there is no need to reach runners over HTTP.
+	var succeeded, failed, active int32
 	for _, job := range jl.Items {
-		if job.Status.Active != 0 {
-			continue
+		active += job.Status.Active
+		switch {
+		case isJobConditionTrue(&job, batchv1.JobComplete) || job.Status.Succeeded > 0:
+			succeeded++
+		case isJobConditionTrue(&job, batchv1.JobFailed) || (job.Status.Active == 0 && job.Status.Failed > 0):
+			failed++
 		}
-		finished++
 	}
+	finished := succeeded + failed
+	activeRunnerPods.WithLabelValues(k6.Namespace, k6.Name).Set(float64(active))
 
-	log.Info(fmt.Sprintf("%d/%d jobs complete", finished, k6.Spec.Parallelism))
+	log.Info(fmt.Sprintf("%d/%d jobs complete, %d succeeded, %d failed", finished, k6.Spec.Parallelism, succeeded, failed))
 
 	if finished < k6.Spec.Parallelism {
 		return
@@ -52,3 +54,219 @@ func FinishJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reco
 	allFinished = true
 	return
 }
+
+// isJobConditionTrue returns true if the job has the condition of given type set to True.
+func isJobConditionTrue(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// CollectRunnerResults inspects terminated runner pods and records the outcome
+// of each runner in status, together with the exit code of the test run and
+// PartialFailure condition.
+func CollectRunnerResults(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	opts := runnerListOptions(k6)
+	pl := &corev1.PodList{}
+	if err := listSelected(ctx, r, pl, opts); err != nil {
+		log.Error(err, "Could not list pods")
+		return err
+	}
+
+	var (
+		runnerResults []v1alpha1.RunnerResult
+		finished      []results.RunnerResult
+		failed        int32
+	)
+	for _, pod := range pl.Items {
+		result := results.FromPod(&pod)
+		if !result.Finished {
+			continue
+		}
+		finished = append(finished, result)
+
+		// job-name is of form <k6 name>-<index>
+		index, err := strconv.Atoi(strings.TrimPrefix(pod.Labels["job-name"], k6.Name+"-"))
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Could not determine the index of runner pod %s", pod.Name))
+			continue
+		}
+
+		runnerResult := v1alpha1.RunnerResult{
+			Index:    int32(index),
+			Result:   "Completed",
+			ExitCode: result.ExitCode,
+		}
+		if result.Class != results.NoFailure {
+			runnerResult.Result = "Failed"
+			runnerResult.Reason = result.Reason
+			failed++
+		}
+		runnerResults = append(runnerResults, runnerResult)
+	}
+
+	sort.Slice(runnerResults, func(i, j int) bool {
+		return runnerResults[i].Index < runnerResults[j].Index
+	})
+	k6.Status.RunnerResults = runnerResults
+
+	if code, reason, ok := results.ExitCode(finished); ok {
+		k6.Status.ExitCode = &code
+		k6.Status.ExitReason = reason
+	}
+
+	if failed > 0 && failed < k6.Spec.Parallelism {
+		log.Info(fmt.Sprintf("%d/%d runners have failed", failed, k6.Spec.Parallelism))
+		k6.UpdateCondition(v1alpha1.PartialFailure, metav1.ConditionTrue)
+	} else {
+		k6.UpdateCondition(v1alpha1.PartialFailure, metav1.ConditionFalse)
+	}
+
+	return nil
+}
+
+// recordSummary emits an event with a short verdict of the finished test run
+// and returns its message. It must be called before TestRunRunning is set to
+// False as the duration is counted from the time that condition was set to True.
+func (r *K6Reconciler) recordSummary(k6 *v1alpha1.K6) string {
+	eventType, reason, message := summary(k6, r.now())
+	r.recordEvent(k6, eventType, reason, message)
+	return message
+}
+
+// summary composes the event describing the result of the test run. It has
+// passed if no more runners have failed than spec.maxFailedRunners.
+func summary(k6 *v1alpha1.K6, now time.Time) (eventType, reason, message string) {
+	var (
+		failed   []string
+		duration string
+	)
+	for _, result := range k6.Status.RunnerResults {
+		if result.Result == "Failed" {
+			failed = append(failed, fmt.Sprintf("runner %d: %s, exit code %d", result.Index, result.Reason, result.ExitCode))
+		}
+	}
+
+	if condition := meta.FindStatusCondition(k6.Status.Conditions, v1alpha1.TestRunRunning); condition != nil &&
+		condition.Status == metav1.ConditionTrue {
+		duration = fmt.Sprintf(" in %s", now.Sub(condition.LastTransitionTime.Time).Round(time.Second))
+	}
+
+	requests := requestsSummary(k6.Status.Summary)
+
+	if len(failed) == 0 {
+		return corev1.EventTypeNormal, "TestPassed",
+			fmt.Sprintf("Test passed: %s%d/%d runners completed%s", requests, k6.Spec.Parallelism, k6.Spec.Parallelism, duration)
+	}
+	if tolerated := k6.Spec.ToleratedFailedRunners(); len(failed) <= tolerated {
+		return corev1.EventTypeNormal, "TestPassed",
+			fmt.Sprintf("Test passed: %s%d/%d runners completed%s, %d failed within tolerance of %d (%s)",
+				requests, int(k6.Spec.Parallelism)-len(failed), k6.Spec.Parallelism, duration, len(failed), tolerated, strings.Join(failed, "; "))
+	}
+
+	return corev1.EventTypeWarning, "TestFailed",
+		fmt.Sprintf("Test failed: %s%d/%d runners failed%s (%s)", requests, len(failed), k6.Spec.Parallelism, duration, strings.Join(failed, "; "))
+}
+
+// requestsSummary describes HTTP requests of the test run for the summary
+// event, e.g. "1.2M reqs, 0.3% errors, p95 210ms, ". It's empty if no HTTP
+// requests are known, e.g. when runners have exited before they could be
+// reached and there were no snapshots.
+func requestsSummary(summary *v1alpha1.TestRunSummary) string {
+	if summary == nil || summary.HTTPReqs == 0 {
+		return ""
+	}
+
+	parts := []string{formatCount(summary.HTTPReqs) + " reqs"}
+	if rate, err := strconv.ParseFloat(summary.ErrorRate, 64); err == nil {
+		parts = append(parts, fmt.Sprintf("%.1f%% errors", rate*100))
+	}
+	if p95, err := strconv.ParseFloat(summary.HTTPReqDurationP95, 64); err == nil {
+		parts = append(parts, fmt.Sprintf("p95 %s", time.Duration(p95*float64(time.Millisecond)).Round(time.Millisecond)))
+	}
+	return strings.Join(parts, ", ") + ", "
+}
+
+// formatCount shortens large counts, e.g. 1234567 to "1.2M".
+func formatCount(count int64) string {
+	switch {
+	case count >= 1e9:
+		return fmt.Sprintf("%.1fG", float64(count)/1e9)
+	case count >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(count)/1e6)
+	case count >= 1e3:
+		return fmt.Sprintf("%.1fk", float64(count)/1e3)
+	}
+	return strconv.FormatInt(count, 10)
+}
+
+// FinalizeCloudTestRun finalizes the cloud test run of the finished test, if
+// there is one. When finalization fails, it's retried with exponential
+// backoff: the returned duration is the time until the next attempt. Once all
+// attempts have failed, CloudFinalizeFailed is set and the operator moves on.
+func FinalizeCloudTestRun(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	if !k6.IsTrue(v1alpha1.CloudTestRun) || !k6.IsFalse(v1alpha1.CloudTestRunFinalized) || k6.IsTrue(v1alpha1.CloudFinalizeFailed) {
+		return 0, nil
+	}
+
+	if k6.Spec.Cloud != nil && k6.Spec.Cloud.SkipFinalize {
+		log.Info(fmt.Sprintf("Skipping finalization of cloud test run %s", k6.Status.TestRunID))
+
+		k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+		return 0, nil
+	}
+
+	client, err := r.cloudClient(ctx, log, k6)
+	if err == nil {
+		err = finalizeCloudTestRunFn(client, k6.Status.TestRunID, cloudRunStatus(k6))
+		r.checkCloudAuth(client, err)
+	}
+	switch {
+	case err == nil:
+		log.Info(fmt.Sprintf("Cloud test run %s was finalized succesfully", k6.Status.TestRunID))
+
+		k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+		return 0, nil
+
+	case cloud.IsForbidden(err):
+		// Limited tokens, like read-only ones, can't finalize test
+		// runs: retrying would never succeed.
+		msg := fmt.Sprintf("Token is not permitted to finalize cloud test run %s, skipping finalization", k6.Status.TestRunID)
+		log.Info(msg)
+		r.recordEvent(k6, corev1.EventTypeWarning, "CloudFinalizeForbidden", msg)
+
+		k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+		return 0, nil
+	}
+
+	// The test run may have been finalized or aborted in k6 Cloud
+	// directly: there is nothing left to do then.
+	if client != nil {
+		if finished, runStatus, checkErr := checkCloudTestRun(client, k6.Status.TestRunID); checkErr == nil && finished {
+			log.Info(fmt.Sprintf("Cloud test run %s was already finalized with run status %d", k6.Status.TestRunID, runStatus))
+
+			k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+			return 0, nil
+		}
+	}
+
+	k6.Status.CloudFinalizeAttempts++
+	attempts := k6.Status.CloudFinalizeAttempts
+	log.Error(err, fmt.Sprintf("Failed to finalize the test run with cloud output (%d/%d)", attempts, cloudFinalizeAttempts))
+	r.recordEvent(k6, corev1.EventTypeWarning, "CloudFinalizeFailed",
+		fmt.Sprintf("Failed to finalize cloud test run %s (%d/%d): %v", k6.Status.TestRunID, attempts, cloudFinalizeAttempts, err))
+
+	if attempts >= cloudFinalizeAttempts {
+		log.Info(fmt.Sprintf("Giving up on finalization of cloud test run %s", k6.Status.TestRunID))
+		k6.UpdateCondition(v1alpha1.CloudFinalizeFailed, metav1.ConditionTrue)
+		return 0, nil
+	}
+
+	if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		return 0, err
+	}
+	return cloudFinalizeBackoff << (attempts - 1), nil
+}
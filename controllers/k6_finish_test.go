@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	"github.com/stretchr/testify/assert"
+	cloudapi "go.k6.io/k6/cloudapi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_FinalizeCloudTestRunRetries(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, check func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error), attempts int32) {
+		finalizeCloudTestRunFn, checkCloudTestRun, cloudFinalizeAttempts = finalize, check, attempts
+	}(finalizeCloudTestRunFn, checkCloudTestRun, cloudFinalizeAttempts)
+	checkCloudTestRun = func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error) {
+		return false, cloudapi.RunStatusRunning, nil
+	}
+	cloudFinalizeAttempts = 3
+
+	tests := []struct {
+		name     string
+		failures int
+		backoff  []time.Duration
+		// finalized or given up on
+		finalized, failed bool
+		attempts          int32
+		// the last attempt is stored by the caller, with the stage
+		stored int32
+	}{
+		{"Succeeds", 0, []time.Duration{0}, true, false, 0, 0},
+		{"SucceedsAfterFailures", 2, []time.Duration{5 * time.Second, 10 * time.Second, 0}, true, false, 2, 2},
+		{"GivesUp", 5, []time.Duration{5 * time.Second, 10 * time.Second, 0}, false, true, 3, 2},
+	}
+
+	for _, test := range tests {
+		failures := test.failures
+		finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, _ cloudapi.RunStatus) error {
+			assert.Equal(t, "123", refID)
+			if failures > 0 {
+				failures--
+				return errors.New("unavailable")
+			}
+			return nil
+		}
+
+		k6 := newCloudK6()
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+		r := &K6Reconciler{Client: c, Scheme: scheme}
+
+		var backoff []time.Duration
+		for i := 0; i < len(test.backoff); i++ {
+			retryAfter, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), k6, r)
+			assert.NoError(t, err, test.name)
+			backoff = append(backoff, retryAfter)
+		}
+		assert.Equal(t, test.backoff, backoff, test.name)
+		assert.Equal(t, test.finalized, k6.IsTrue(v1alpha1.CloudTestRunFinalized), test.name)
+		assert.Equal(t, test.failed, k6.IsTrue(v1alpha1.CloudFinalizeFailed), test.name)
+		assert.Equal(t, test.attempts, k6.Status.CloudFinalizeAttempts, test.name)
+
+		// retries are counted in the resource
+		stored := &v1alpha1.K6{}
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), stored))
+		assert.Equal(t, test.stored, stored.Status.CloudFinalizeAttempts, test.name)
+
+		// once done, there is nothing more to do
+		retryAfter, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err, test.name)
+		assert.Zero(t, retryAfter, test.name)
+		trackedStages.forget(client.ObjectKeyFromObject(k6))
+	}
+}
+
+func Test_FinalizeCloudTestRunFinishedInCloud(t *testing.T) {
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, check func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error)) {
+		finalizeCloudTestRunFn, checkCloudTestRun = finalize, check
+	}(finalizeCloudTestRunFn, checkCloudTestRun)
+	finalizeCloudTestRunFn = func(*cloudapi.Client, string, cloudapi.RunStatus) error { return errors.New("already finished") }
+	checkCloudTestRun = func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error) {
+		return true, cloudapi.RunStatusAbortedUser, nil
+	}
+
+	k6 := newCloudK6()
+	retryAfter, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), k6, &K6Reconciler{})
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	assert.True(t, k6.IsTrue(v1alpha1.CloudTestRunFinalized))
+	assert.Zero(t, k6.Status.CloudFinalizeAttempts)
+}
+
+func Test_summaryMaxFailedRunners(t *testing.T) {
+	tests := []struct {
+		name      string
+		max       *intstr.IntOrString
+		failed    int
+		eventType string
+	}{
+		{"NoneTolerated", nil, 1, corev1.EventTypeWarning},
+		{"UnderThreshold", &intstr.IntOrString{Type: intstr.Int, IntVal: 2}, 1, corev1.EventTypeNormal},
+		{"AtThreshold", &intstr.IntOrString{Type: intstr.Int, IntVal: 2}, 2, corev1.EventTypeNormal},
+		{"OverThreshold", &intstr.IntOrString{Type: intstr.Int, IntVal: 2}, 3, corev1.EventTypeWarning},
+		// 25% of 10 runners is rounded down to 2
+		{"PercentageAtThreshold", &intstr.IntOrString{Type: intstr.String, StrVal: "25%"}, 2, corev1.EventTypeNormal},
+		{"PercentageOverThreshold", &intstr.IntOrString{Type: intstr.String, StrVal: "25%"}, 3, corev1.EventTypeWarning},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{Spec: v1alpha1.K6Spec{Parallelism: 10, MaxFailedRunners: test.max}}
+		for i := 1; i <= 10; i++ {
+			result := v1alpha1.RunnerResult{Index: int32(i), Result: "Completed"}
+			if i <= test.failed {
+				result = v1alpha1.RunnerResult{Index: int32(i), Result: "Failed", Reason: "OOMKilled", ExitCode: 137}
+			}
+			k6.Status.RunnerResults = append(k6.Status.RunnerResults, result)
+		}
+
+		eventType, _, message := summary(k6, time.Now())
+		assert.Equal(t, test.eventType, eventType, test.name)
+		assert.Contains(t, message, "runner 1: OOMKilled, exit code 137", test.name)
+	}
+
+	k6 := &v1alpha1.K6{Spec: v1alpha1.K6Spec{Parallelism: 3, MaxFailedRunners: &intstr.IntOrString{Type: intstr.Int, IntVal: 1}}}
+	k6.Status.RunnerResults = []v1alpha1.RunnerResult{
+		{Index: 1, Result: "Completed"},
+		{Index: 2, Result: "Failed", Reason: "Evicted", ExitCode: 1},
+		{Index: 3, Result: "Completed"},
+	}
+	_, _, message := summary(k6, time.Now())
+	assert.Equal(t, "Test passed: 2/3 runners completed, 1 failed within tolerance of 1 (runner 2: Evicted, exit code 1)", message)
+}
+
+func Test_summaryRequests(t *testing.T) {
+	k6 := &v1alpha1.K6{Spec: v1alpha1.K6Spec{Parallelism: 2}}
+	k6.Status.RunnerResults = []v1alpha1.RunnerResult{{Index: 1, Result: "Completed"}, {Index: 2, Result: "Completed"}}
+	k6.Status.Summary = &v1alpha1.TestRunSummary{Runners: 2, HTTPReqs: 1234567, ErrorRate: "0.0030", HTTPReqDurationP95: "210.4"}
+
+	_, _, message := summary(k6, time.Now())
+	assert.Equal(t, "Test passed: 1.2M reqs, 0.3% errors, p95 210ms, 2/2 runners completed", message)
+
+	// p95 is not known from snapshots
+	k6.Status.Summary = &v1alpha1.TestRunSummary{Runners: 2, HTTPReqs: 300, ErrorRate: "0.0200"}
+	_, _, message = summary(k6, time.Now())
+	assert.Equal(t, "Test passed: 300 reqs, 2.0% errors, 2/2 runners completed", message)
+}
+
+func Test_CollectRunnerResultsExitCode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+
+	tests := []struct {
+		name  string
+		codes []int32
+		// -1 if there is no exit code
+		code   int32
+		reason string
+	}{
+		{"Passed", []int32{0, 0}, 0, "test has passed"},
+		{"Thresholds", []int32{0, 99}, 99, "thresholds have failed"},
+		{"ScriptError", []int32{99, 104, 0}, 104, "invalid configuration"},
+		{"OOMKilled", []int32{107, 137}, 137, "k6 terminated with exit code 137"},
+		{"NotFinished", nil, -1, ""},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{Parallelism: int32(len(test.codes))},
+		}
+		k6.InitializeConditions()
+
+		var objects []client.Object
+		for i, code := range test.codes {
+			podLabels := jobs.NewRunnerLabels(k6)
+			podLabels["job-name"] = fmt.Sprintf("test-%d", i+1)
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("test-%d-abcde", i+1), Namespace: "test", Labels: podLabels},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "k6",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: code}},
+				}}},
+			})
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+
+		assert.NoError(t, CollectRunnerResults(context.Background(), logr.Discard(), k6, &K6Reconciler{Client: c}), test.name)
+		if test.code < 0 {
+			assert.Nil(t, k6.Status.ExitCode, test.name)
+		} else if assert.NotNil(t, k6.Status.ExitCode, test.name) {
+			assert.Equal(t, test.code, *k6.Status.ExitCode, test.name)
+		}
+		assert.Equal(t, test.reason, k6.Status.ExitReason, test.name)
+	}
+}
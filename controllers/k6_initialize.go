@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/k6-operator/pkg/types"
@@ -16,14 +19,30 @@ import (
 	"github.com/grafana/k6-operator/pkg/resources/jobs"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// inspectInitializer reads the result of the initializer; it's replaced in tests.
+var inspectInitializer = inspectTestRun
+
+// defaultCloudTokenMaxWait is how long a test run waits for k6 Cloud token
+// unless K6Reconciler.CloudTokenMaxWait is set.
+const defaultCloudTokenMaxWait = 10 * time.Minute
+
+var (
+	// cloudTokenBackoff is the delay before looking for k6 Cloud token again,
+	// doubled on each attempt up to cloudTokenMaxBackoff.
+	cloudTokenBackoff    = time.Second
+	cloudTokenMaxBackoff = time.Minute
+)
+
 // InitializeJobs creates jobs that will run initial checks for distributed test if any are necessary
 func InitializeJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (res ctrl.Result, err error) {
 	// initializer is a quick job so check in frequently
@@ -46,6 +65,7 @@ func InitializeJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 
 	if err = r.Create(ctx, initializer); err != nil {
 		log.Error(err, "Failed to launch k6 test initializer")
+		r.recordEvent(k6, corev1.EventTypeWarning, "JobCreationFailed", fmt.Sprintf("Failed to create initializer job: %v", err))
 		return res, err
 	}
 
@@ -58,9 +78,16 @@ func RunValidations(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 
 	cli := types.ParseCLI(&k6.Spec)
 
-	inspectOutput, inspectReady, err := inspectTestRun(ctx, log, *k6, r)
+	inspectOutput, inspectReady, err := inspectInitializer(ctx, log, *k6, r)
 	if err != nil {
-		// inspectTestRun made a log message already so just return without requeue
+		// inspectTestRun made a log message already so just update the stage
+		// and return without requeue
+		k6.Status.Stage = "error"
+		recordValidation(k6, nil, err)
+
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
 		return ctrl.Result{}, nil
 	}
 	if !inspectReady {
@@ -79,6 +106,7 @@ func RunValidations(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 			"parallelism", k6.Spec.Parallelism)
 
 		k6.Status.Stage = "error"
+		recordValidation(k6, &inspectOutput, err)
 
 		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
 			return ctrl.Result{}, err
@@ -88,6 +116,45 @@ func RunValidations(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 		return ctrl.Result{}, nil
 	}
 
+	versions, err := archiveVersions(ctx, log, k6, r)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if versions != nil {
+		if types.IsNewerVersion(versions.Archive, versions.K6) {
+			err = fmt.Errorf("archive was created with k6 %s but the image has k6 %s", versions.Archive, versions.K6)
+			k6.UpdateCondition(v1alpha1.K6VersionMismatch, metav1.ConditionTrue)
+
+			if k6.Spec.VersionCheck == "Fail" {
+				log.Error(err, "Version of k6 in the image is too old for the archive")
+
+				k6.Status.Stage = "error"
+				recordValidation(k6, &inspectOutput, err)
+
+				if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, nil
+			}
+
+			log.Info(fmt.Sprintf("Warning: %v", err))
+			r.recordEvent(k6, corev1.EventTypeWarning, "K6VersionMismatch", err.Error())
+		} else {
+			k6.UpdateCondition(v1alpha1.K6VersionMismatch, metav1.ConditionFalse)
+		}
+	}
+
+	if k6.Spec.ValidateOnly {
+		recordValidation(k6, &inspectOutput, nil)
+
+		log.Info("Script is valid, changing stage of K6 status to finished without starting runners")
+		k6.Status.Stage = "finished"
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	if cli.HasCloudOut {
 		k6.UpdateCondition(v1alpha1.CloudTestRun, metav1.ConditionTrue)
 		k6.UpdateCondition(v1alpha1.CloudTestRunCreated, metav1.ConditionFalse)
@@ -103,10 +170,39 @@ func RunValidations(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 	return res, nil
 }
 
+// recordValidation records the result of the initializer in status of
+// validate-only test runs, together with their final phase.
+func recordValidation(k6 *v1alpha1.K6, inspectOutput *cloud.InspectOutput, err error) {
+	if !k6.Spec.ValidateOnly {
+		return
+	}
+
+	validation := &v1alpha1.ValidationResult{Valid: err == nil}
+	if inspectOutput != nil {
+		validation.MaxVUs = int64(inspectOutput.MaxVUs)
+		if inspectOutput.TotalDuration.Valid {
+			validation.Duration = time.Duration(inspectOutput.TotalDuration.Duration).String()
+		}
+	}
+	k6.Status.Validation = validation
+
+	if err != nil {
+		validation.Reason = err.Error()
+		k6.Status.Phase = "Failed"
+		k6.Status.PhaseReason = fmt.Sprintf("script is not valid: %v", err)
+		return
+	}
+	k6.Status.Phase = "Succeeded"
+	k6.Status.PhaseReason = fmt.Sprintf("script is valid: up to %d VUs", validation.MaxVUs)
+	if len(validation.Duration) > 0 {
+		k6.Status.PhaseReason += " for " + validation.Duration
+	}
+}
+
 func SetupCloudTest(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (res ctrl.Result, err error) {
 	res = ctrl.Result{RequeueAfter: time.Second * 5}
 
-	inspectOutput, inspectReady, err := inspectTestRun(ctx, log, *k6, r)
+	inspectOutput, inspectReady, err := inspectInitializer(ctx, log, *k6, r)
 	if err != nil {
 		// This *shouldn't* fail since it was already done once. Don't requeue.
 		// Alternatively: store inspect options in K6 Status? Get rid off reading logs?
@@ -116,40 +212,73 @@ func SetupCloudTest(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 		return res, nil
 	}
 
-	token, tokenReady, err := loadToken(ctx, log, r)
+	token, tokenReady, err := cloudToken(ctx, log, k6, r)
 	if err != nil {
 		// An error here means a very likely mis-configuration of the token.
 		// Consider updating status to error to let a user know quicker?
 		log.Error(err, "A problem while getting token.")
+		r.recordEvent(k6, corev1.EventTypeWarning, "TokenNotFound", err.Error())
 		return ctrl.Result{}, nil
 	}
 	if !tokenReady {
-		return res, nil
+		return waitForToken(ctx, log, k6, r)
 	}
 
-	host := getEnvVar(k6.Spec.Runner.Env, "K6_CLOUD_HOST")
+	// an empty host is the default of k6 Cloud
+	host, err := getEnvVar(ctx, r, k6.Namespace, &k6.Spec.Runner, "K6_CLOUD_HOST")
+	if err != nil {
+		log.Error(err, "Failed to get K6_CLOUD_HOST of runners")
+		return res, nil
+	}
+	webAppURL, err := getEnvVar(ctx, r, k6.Namespace, &k6.Spec.Runner, "K6_CLOUD_WEB_APP_URL")
+	if err != nil {
+		log.Error(err, "Failed to get K6_CLOUD_WEB_APP_URL of runners")
+		return res, nil
+	}
 
 	if k6.IsFalse(v1alpha1.CloudTestRunCreated) {
 
 		// If CloudTestRunCreated has just been updated, wait for a bit before
 		// acting, to avoid race condition between different reconcile loops.
 		t, _ := k6.LastUpdate(v1alpha1.CloudTestRunCreated)
-		if time.Now().Sub(t) < 5*time.Second {
+		if r.now().Sub(t) < 5*time.Second {
 			return ctrl.Result{RequeueAfter: time.Second * 2}, nil
 		}
 
-		if testRunData, err := cloud.CreateTestRun(inspectOutput, k6.Spec.Parallelism, host, token, log); err != nil {
+		var note string
+		if k6.Spec.Cloud != nil {
+			if len(k6.Spec.Cloud.Name) > 0 {
+				inspectOutput.External.Loadimpact.Name = k6.Spec.Cloud.Name
+			}
+			note = k6.Spec.Cloud.Note
+		}
+
+		client := r.cloudClients.Get(token, host)
+		if testRunData, err := createCloudTestRun(client, inspectOutput, k6.Spec.Parallelism, host, note, log); err != nil {
 			log.Error(err, "Failed to create a new cloud test run.")
+			r.checkCloudAuth(client, err)
 			return res, nil
 		} else {
 			log = log.WithValues("testRunId", testRunData.ReferenceID)
 			log.Info(fmt.Sprintf("Created cloud test run: %s", testRunData.ReferenceID))
 
 			k6.Status.TestRunID = testRunData.ReferenceID
+			k6.Status.TestRunURL = cloud.TestRunURL(testRunData.ReferenceID, host, webAppURL, testRunData.ConfigOverride)
 			k6.UpdateCondition(v1alpha1.CloudTestRunCreated, metav1.ConditionTrue)
 
 			k6.Status.AggregationVars = cloud.EncodeAggregationConfig(testRunData)
 
+			// This is for information only so the test run goes on regardless.
+			if project, err := getCloudProject(client, testRunData.ReferenceID, host, inspectOutput.External.Loadimpact.ProjectID); err != nil {
+				log.Error(err, "Failed to get the project of the cloud test run")
+			} else {
+				log.Info(fmt.Sprintf("Cloud test run belongs to project %d (%s) of organization %d",
+					project.ID, project.Name, project.OrganizationID))
+
+				k6.Status.CloudProject = strconv.FormatInt(project.ID, 10)
+				k6.Status.CloudOrg = strconv.FormatInt(project.OrganizationID, 10)
+			}
+
 			_, err := r.UpdateStatus(ctx, k6, log)
 			// log.Info(fmt.Sprintf("Debug updating status after create %v", updateHappened))
 			if err != nil {
@@ -167,18 +296,43 @@ func SetupCloudTest(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6
 func inspectTestRun(ctx context.Context, log logr.Logger, k6 v1alpha1.K6, r *K6Reconciler) (
 	inspectOutput cloud.InspectOutput, ready bool, returnErr error) {
 	var (
-		listOpts = &client.ListOptions{
-			Namespace: k6.Namespace,
-			LabelSelector: labels.SelectorFromSet(map[string]string{
-				"app":      "k6",
-				"k6_cr":    k6.Name,
-				"job-name": fmt.Sprintf("%s-initializer", k6.Name),
-			}),
-		}
-		podList = &corev1.PodList{}
-		err     error
+		listOpts = initializerListOptions(&k6)
+		podList  = &corev1.PodList{}
+		err      error
 	)
-	if err = r.List(ctx, podList, listOpts); err != nil {
+	// The initializer Job fails on its own in case of errors or when its
+	// deadline is exceeded: no point in waiting for it after that.
+	initializer := &batchv1.Job{}
+	key := k8stypes.NamespacedName{Name: fmt.Sprintf("%s-initializer", k6.Name), Namespace: k6.Namespace}
+	err = r.Get(ctx, key, initializer)
+	if k8sErrors.IsNotFound(err) {
+		// The cache may not have caught up with the creation of the job yet.
+		if err = r.apiReader().Get(ctx, key, initializer); k8sErrors.IsNotFound(err) {
+			// Nothing would create the job again, nor is its result
+			// available anymore if it has finished.
+			returnErr = errors.New("initializer job is missing: it may have been removed before its result was read, e.g. by ttlSecondsAfterFinished")
+			log.Error(returnErr, "Initializer job was not found")
+			return
+		}
+	}
+	if err == nil {
+		for _, condition := range initializer.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				returnErr = fmt.Errorf("initializer job has failed: %s %s", condition.Reason, condition.Message)
+				// a missing script is reported by the initializer itself
+				if msg, err := initializerMessage(ctx, &k6, r); err != nil {
+					log.Error(err, "Could not list pods")
+				} else if strings.HasPrefix(msg, types.VerificationFailed) {
+					returnErr = errors.New(msg)
+					r.recordEvent(&k6, corev1.EventTypeWarning, "ScriptVerificationFailed", msg)
+				}
+				log.Error(returnErr, "Initializer job has failed")
+				return
+			}
+		}
+	}
+
+	if err = listSelected(ctx, r, podList, listOpts); err != nil {
 		log.Error(err, "Could not list pods")
 		return
 	}
@@ -187,8 +341,16 @@ func inspectTestRun(ctx context.Context, log logr.Logger, k6 v1alpha1.K6, r *K6R
 		return
 	}
 
-	// there should be only 1 initializer pod
-	if podList.Items[0].Status.Phase != "Succeeded" {
+	// there is more than 1 initializer pod only if it was retried, per
+	// spec.initializer.backoffLimit, and only 1 of them may succeed
+	var pod *corev1.Pod
+	for i := range podList.Items {
+		if podList.Items[i].Status.Phase == corev1.PodSucceeded {
+			pod = &podList.Items[i]
+			break
+		}
+	}
+	if pod == nil {
 		log.Info("Waiting for initializing pod to finish")
 		return
 	}
@@ -211,7 +373,7 @@ func inspectTestRun(ctx context.Context, log logr.Logger, k6 v1alpha1.K6, r *K6R
 		log.Error(err, "unable to get access to clientset")
 		return
 	}
-	req := clientset.CoreV1().Pods(k6.Namespace).GetLogs(podList.Items[0].Name, &corev1.PodLogOptions{
+	req := clientset.CoreV1().Pods(k6.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
 		Container: "k6",
 	})
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
@@ -240,17 +402,161 @@ func inspectTestRun(ctx context.Context, log logr.Logger, k6 v1alpha1.K6, r *K6R
 	return
 }
 
+// archiveVersions reads the versions of k6 reported by the initializer in case
+// the script is an archive. Otherwise, it returns nil. A message that can't be
+// read skips the check, while failures to list the pods are returned for the
+// check to be retried.
+func archiveVersions(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (*types.Versions, error) {
+	script, err := types.ParseScript(&k6.Spec)
+	if err != nil || !script.IsArchive() {
+		return nil, err
+	}
+
+	msg, err := initializerMessage(ctx, k6, r)
+	if err != nil {
+		log.Error(err, "Could not list pods")
+		return nil, err
+	}
+	if len(msg) == 0 {
+		return nil, nil
+	}
+
+	// the check is skipped if the versions can't be read
+	var versions types.Versions
+	if err = json.Unmarshal([]byte(msg), &versions); err != nil {
+		log.Error(err, fmt.Sprintf("unable to unmarshal versions: `%s`", msg))
+		return nil, nil
+	}
+	if len(versions.Archive) == 0 || len(versions.K6) == 0 {
+		log.Info(fmt.Sprintf("Versions of k6 are not known: `%s`", msg))
+		return nil, nil
+	}
+	return &versions, nil
+}
+
+// initializerMessage returns the termination message of k6 container in the
+// initializer pod, if there is any. If the initializer was retried, the
+// message of the succeeded pod is preferred.
+func initializerMessage(ctx context.Context, k6 *v1alpha1.K6, r *K6Reconciler) (string, error) {
+	podList := &corev1.PodList{}
+	if err := listSelected(ctx, r, podList, initializerListOptions(k6)); err != nil {
+		return "", err
+	}
+
+	var msg string
+	for _, pod := range podList.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == "k6" && status.State.Terminated != nil && len(status.State.Terminated.Message) > 0 {
+				if pod.Status.Phase == corev1.PodSucceeded {
+					return strings.TrimSpace(status.State.Terminated.Message), nil
+				}
+				if len(msg) == 0 {
+					msg = strings.TrimSpace(status.State.Terminated.Message)
+				}
+			}
+		}
+	}
+	return msg, nil
+}
+
+// cloudToken returns K6_CLOUD_TOKEN of runners if it is set, e.g. from a
+// Secret with envFrom, so that the test run is created with the token the
+// runners use. Otherwise, the token of the operator is loaded.
+func cloudToken(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (token string, ready bool, returnErr error) {
+	token, err := getEnvVar(ctx, r, k6.Namespace, &k6.Spec.Runner, "K6_CLOUD_TOKEN")
+	if err != nil {
+		log.Error(err, "Failed to get K6_CLOUD_TOKEN of runners")
+		// This may be a networking issue, etc. so just retry.
+		return
+	}
+	if len(token) > 0 {
+		return token, true, nil
+	}
+	return loadToken(ctx, log, r)
+}
+
+// defaultCloudTokenNamespace is where the secret with k6 Cloud token is
+// looked for by default.
+const defaultCloudTokenNamespace = "k6-operator-system"
+
+func (r *K6Reconciler) cloudTokenNamespace() string {
+	if len(r.CloudTokenNamespace) == 0 {
+		return defaultCloudTokenNamespace
+	}
+	return r.CloudTokenNamespace
+}
+
+// waitForToken requeues the test run with exponential backoff while k6 Cloud
+// token can't be loaded. Once the test run has waited for longer than
+// r.CloudTokenMaxWait, it is set to error with CloudTokenMissing condition.
+func waitForToken(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	maxWait := r.CloudTokenMaxWait
+	if maxWait <= 0 {
+		maxWait = defaultCloudTokenMaxWait
+	}
+
+	var waited time.Duration
+	delay := cloudTokenBackoff
+	for i := int32(0); i < k6.Status.CloudTokenAttempts; i++ {
+		waited += delay
+		if delay *= 2; delay > cloudTokenMaxBackoff {
+			delay = cloudTokenMaxBackoff
+		}
+	}
+
+	if waited >= maxWait {
+		reason := fmt.Sprintf("k6 Cloud token was not found within %s", maxWait)
+		log.Error(errors.New(reason), "Aborting the test run")
+		r.recordEvent(k6, corev1.EventTypeWarning, "TokenNotFound", reason)
+
+		k6.UpdateCondition(v1alpha1.CloudTokenMissing, metav1.ConditionTrue)
+		k6.Status.Stage = "error"
+		k6.Status.Phase = "Failed"
+		k6.Status.PhaseReason = reason
+		_, err := r.UpdateStatus(ctx, k6, log)
+		return ctrl.Result{}, err
+	}
+
+	// The first wait is logged as usual and the following ones only with
+	// higher verbosity, not to flood the logs.
+	k6.Status.CloudTokenAttempts++
+	msg := fmt.Sprintf("Waiting %s for k6 Cloud token", delay)
+	if k6.Status.CloudTokenAttempts == 1 {
+		log.Info(msg)
+	} else {
+		log.V(1).Info(msg)
+	}
+
+	k6.Status.Phase = "Pending"
+	k6.Status.PhaseReason = "waiting for k6 Cloud token"
+	if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
 // Similarly to inspectTestRun, there may be some errors during load of token
 // that should be just waited out. But other errors should result in change of
 // behaviour in the caller.
 // ready shows whether token was loaded yet, while returnErr indicates an error
 // that should be acted on.
+// The token is read from r.CloudTokenFile if it's set, otherwise from the
+// labelled secret.
 func loadToken(ctx context.Context, log logr.Logger, r *K6Reconciler) (token string, ready bool, returnErr error) {
+	if r.CloudTokenFile != nil {
+		t, err := r.CloudTokenFile.Token()
+		if err != nil {
+			log.Error(err, "Failed to load k6 Cloud token")
+			// The file may be in the middle of rotation so just retry.
+			return
+		}
+		return t, len(t) > 0, nil
+	}
+
 	var (
 		secrets    corev1.SecretList
 		secretOpts = &client.ListOptions{
-			// TODO: find out a better way to get namespace here
-			Namespace: "k6-operator-system",
+			Namespace: r.cloudTokenNamespace(),
 			LabelSelector: labels.SelectorFromSet(map[string]string{
 				"k6cloud": "token",
 			}),
@@ -258,23 +564,22 @@ func loadToken(ctx context.Context, log logr.Logger, r *K6Reconciler) (token str
 		err error
 	)
 
-	if err = r.List(ctx, &secrets, secretOpts); err != nil {
+	if err = r.apiReader().List(ctx, &secrets, secretOpts); err != nil {
 		log.Error(err, "Failed to load k6 Cloud token")
 		// This may be a networking issue, etc. so just retry.
 		return
 	}
 
 	if len(secrets.Items) < 1 {
-		// we should stop execution in case of this error
-		returnErr = fmt.Errorf("There are no secrets to hold k6 Cloud token")
-		log.Error(returnErr, err.Error())
+		// The secret may not be created yet, e.g. during bootstrap of the
+		// cluster, so this is waited out by the caller.
 		return
 	}
 
 	if t, ok := secrets.Items[0].Data["token"]; !ok {
 		// we should stop execution in case of this error
 		returnErr = fmt.Errorf("The secret doesn't have a field token for k6 Cloud")
-		log.Error(err, err.Error())
+		log.Error(returnErr, "Failed to load k6 Cloud token")
 		return
 	} else {
 		token = string(t)
@@ -285,11 +590,73 @@ func loadToken(ctx context.Context, log logr.Logger, r *K6Reconciler) (token str
 	return
 }
 
-func getEnvVar(vars []corev1.EnvVar, name string) string {
-	for _, v := range vars {
-		if v.Name == name {
-			return v.Value
+// getEnvVar returns the value the env var has in the pod. Explicitly listed
+// vars take precedence over envFrom sources and, as in the pod, the last
+// definition wins. Values from Secrets and ConfigMaps are fetched from the
+// API server; an empty value is returned if the var is not defined.
+func getEnvVar(ctx context.Context, r *K6Reconciler, namespace string, pod *v1alpha1.Pod, name string) (string, error) {
+	for i := len(pod.Env) - 1; i >= 0; i-- {
+		v := pod.Env[i]
+		if v.Name != name {
+			continue
 		}
+		if v.ValueFrom == nil {
+			return v.Value, nil
+		}
+		if ref := v.ValueFrom.SecretKeyRef; ref != nil {
+			secret := &corev1.Secret{}
+			if err := getOptional(ctx, r, namespace, ref.Name, ref.Optional, secret); err != nil {
+				return "", err
+			}
+			return string(secret.Data[ref.Key]), nil
+		}
+		if ref := v.ValueFrom.ConfigMapKeyRef; ref != nil {
+			configMap := &corev1.ConfigMap{}
+			if err := getOptional(ctx, r, namespace, ref.Name, ref.Optional, configMap); err != nil {
+				return "", err
+			}
+			return configMap.Data[ref.Key], nil
+		}
+		// field and resource refs are only known in the pod
+		return "", nil
+	}
+
+	for i := len(pod.EnvFrom) - 1; i >= 0; i-- {
+		source := pod.EnvFrom[i]
+		if !strings.HasPrefix(name, source.Prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(name, source.Prefix)
+
+		if ref := source.SecretRef; ref != nil {
+			secret := &corev1.Secret{}
+			if err := getOptional(ctx, r, namespace, ref.Name, ref.Optional, secret); err != nil {
+				return "", err
+			}
+			if value, ok := secret.Data[key]; ok {
+				return string(value), nil
+			}
+		}
+		if ref := source.ConfigMapRef; ref != nil {
+			configMap := &corev1.ConfigMap{}
+			if err := getOptional(ctx, r, namespace, ref.Name, ref.Optional, configMap); err != nil {
+				return "", err
+			}
+			if value, ok := configMap.Data[key]; ok {
+				return value, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// getOptional gets the object, which is left empty if it's optional and
+// doesn't exist. Secrets and ConfigMaps are read from the API server so that
+// the operator doesn't cache all of them in the cluster.
+func getOptional(ctx context.Context, r *K6Reconciler, namespace, name string, optional *bool, obj client.Object) error {
+	err := r.apiReader().Get(ctx, k8stypes.NamespacedName{Namespace: namespace, Name: name}, obj)
+	if k8sErrors.IsNotFound(err) && optional != nil && *optional {
+		return nil
 	}
-	return ""
+	return err
 }
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	k6types "go.k6.io/k6/lib/types"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_getEnvVar(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	optional := true
+	secretRef := func(name, prefix string) corev1.EnvFromSource {
+		return corev1.EnvFromSource{Prefix: prefix, SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name}, Optional: &optional,
+		}}
+	}
+	configMapRef := func(name string) corev1.EnvFromSource {
+		return corev1.EnvFromSource{ConfigMapRef: &corev1.ConfigMapEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		}}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cloud", Namespace: "test"},
+			Data:       map[string][]byte{"K6_CLOUD_HOST": []byte("https://ingest.example.com"), "HOST": []byte("https://prefixed.example.com")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "tuning", Namespace: "test"},
+			Data:       map[string]string{"K6_CLOUD_HOST": "https://ingest.example.org"},
+		},
+	).Build()
+	// Secrets and ConfigMaps are not read from the cache
+	r := &K6Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), APIReader: c}
+
+	tests := []struct {
+		name  string
+		pod   v1alpha1.Pod
+		value string
+		err   bool
+	}{
+		{"NotSet", v1alpha1.Pod{}, "", false},
+		{"Explicit", v1alpha1.Pod{Env: []corev1.EnvVar{
+			{Name: "K6_CLOUD_HOST", Value: "https://first.example.com"},
+			{Name: "K6_NO_USAGE_REPORT", Value: "true"},
+			{Name: "K6_CLOUD_HOST", Value: "https://last.example.com"},
+		}}, "https://last.example.com", false},
+		{"SecretKeyRef", v1alpha1.Pod{Env: []corev1.EnvVar{{Name: "K6_CLOUD_HOST", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "cloud"}, Key: "HOST"},
+		}}}}, "https://prefixed.example.com", false},
+		{"EnvFromSecret", v1alpha1.Pod{EnvFrom: []corev1.EnvFromSource{secretRef("cloud", "")}}, "https://ingest.example.com", false},
+		{"EnvFromPrefix", v1alpha1.Pod{EnvFrom: []corev1.EnvFromSource{secretRef("cloud", "K6_CLOUD_")}}, "https://prefixed.example.com", false},
+		{"LastEnvFrom", v1alpha1.Pod{EnvFrom: []corev1.EnvFromSource{secretRef("cloud", ""), configMapRef("tuning")}},
+			"https://ingest.example.org", false},
+		{"ExplicitOverEnvFrom", v1alpha1.Pod{
+			Env:     []corev1.EnvVar{{Name: "K6_CLOUD_HOST", Value: "https://explicit.example.com"}},
+			EnvFrom: []corev1.EnvFromSource{secretRef("cloud", "")},
+		}, "https://explicit.example.com", false},
+		{"OptionalMissing", v1alpha1.Pod{EnvFrom: []corev1.EnvFromSource{configMapRef("tuning"), secretRef("missing", "")}},
+			"https://ingest.example.org", false},
+		{"Missing", v1alpha1.Pod{EnvFrom: []corev1.EnvFromSource{configMapRef("missing")}}, "", true},
+	}
+
+	for _, test := range tests {
+		value, err := getEnvVar(context.Background(), r, "test", &test.pod, "K6_CLOUD_HOST")
+		assert.Equal(t, test.err, err != nil, test.name)
+		assert.Equal(t, test.value, value, test.name)
+	}
+}
+
+func Test_cloudToken(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "k6-operator-system", Labels: map[string]string{"k6cloud": "token"}},
+			Data:       map[string][]byte{"token": []byte("operator")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cloud", Namespace: "test"},
+			Data:       map[string][]byte{"K6_CLOUD_TOKEN": []byte("runners")},
+		},
+	).Build()
+	r := &K6Reconciler{Client: c}
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+
+	token, ready, err := cloudToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, ready)
+	assert.Equal(t, "operator", token)
+
+	k6.Spec.Runner.EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "cloud"},
+	}}}
+	token, ready, err = cloudToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, ready)
+	assert.Equal(t, "runners", token)
+}
+
+func Test_loadTokenNoSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	r := &K6Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+
+	_, ready, err := loadToken(context.Background(), logr.Discard(), r)
+	assert.NoError(t, err)
+	assert.False(t, ready)
+}
+
+func Test_waitForToken(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Status:     v1alpha1.K6Status{Stage: "initialization"},
+	}
+	k6.InitializeConditions()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, CloudTokenMaxWait: 10 * time.Second}
+
+	// 1s, 2s and 4s make 7s, then the wait is capped at 5s
+	defer func(max time.Duration) { cloudTokenMaxBackoff = max }(cloudTokenMaxBackoff)
+	cloudTokenMaxBackoff = 5 * time.Second
+
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		res, err := waitForToken(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res.RequeueAfter)
+
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+		assert.Equal(t, v1alpha1.Phase("Pending"), k6.Status.Phase)
+		assert.False(t, k6.IsTrue(v1alpha1.CloudTokenMissing))
+	}
+
+	res, err := waitForToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Zero(t, res.RequeueAfter)
+
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+	assert.True(t, k6.IsTrue(v1alpha1.CloudTokenMissing))
+	assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage)
+	assert.Equal(t, v1alpha1.Phase("Failed"), k6.Status.Phase)
+	assert.Equal(t, "k6 Cloud token was not found within 10s", k6.Status.PhaseReason)
+}
+
+func Test_RunValidationsValidateOnly(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(inspect func(context.Context, logr.Logger, v1alpha1.K6, *K6Reconciler) (cloud.InspectOutput, bool, error)) {
+		inspectInitializer = inspect
+	}(inspectInitializer)
+
+	inspected := cloud.InspectOutput{MaxVUs: 10, TotalDuration: k6types.NullDurationFrom(90 * time.Second)}
+
+	tests := []struct {
+		name        string
+		parallelism int32
+		inspectErr  error
+		stage       v1alpha1.Stage
+		phase       v1alpha1.Phase
+		validation  v1alpha1.ValidationResult
+	}{
+		{
+			name:        "Valid",
+			parallelism: 2,
+			stage:       "finished",
+			phase:       "Succeeded",
+			validation:  v1alpha1.ValidationResult{Valid: true, Duration: "1m30s", MaxVUs: 10},
+		},
+		{
+			name:        "TooManyRunners",
+			parallelism: 20,
+			stage:       "error",
+			phase:       "Failed",
+			validation: v1alpha1.ValidationResult{
+				Reason: "number of instances > number of VUs", Duration: "1m30s", MaxVUs: 10,
+			},
+		},
+		{
+			name:        "InitializerFailed",
+			parallelism: 2,
+			inspectErr:  errors.New("initializer job has failed: BackoffLimitExceeded"),
+			stage:       "error",
+			phase:       "Failed",
+			validation:  v1alpha1.ValidationResult{Reason: "initializer job has failed: BackoffLimitExceeded"},
+		},
+	}
+
+	for _, test := range tests {
+		inspectInitializer = func(context.Context, logr.Logger, v1alpha1.K6, *K6Reconciler) (cloud.InspectOutput, bool, error) {
+			if test.inspectErr != nil {
+				return cloud.InspectOutput{}, false, test.inspectErr
+			}
+			return inspected, true, nil
+		}
+
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec: v1alpha1.K6Spec{
+				Parallelism:  test.parallelism,
+				ValidateOnly: true,
+				Script:       v1alpha1.K6Script{ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"}},
+			},
+			Status: v1alpha1.K6Status{Stage: "initialization"},
+		}
+		k6.InitializeConditions()
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+		r := &K6Reconciler{Client: c, Scheme: scheme}
+
+		// the test run ends right after the initializer and stays there
+		for i := 0; i < 3; i++ {
+			_, err := r.reconcileStage(context.Background(), logr.Discard(), k6)
+			assert.NoError(t, err, test.name)
+		}
+
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+		assert.Equal(t, test.stage, k6.Status.Stage, test.name)
+		assert.Equal(t, test.phase, k6.Status.Phase, test.name)
+		assert.Equal(t, &test.validation, k6.Status.Validation, test.name)
+
+		jobs := &batchv1.JobList{}
+		assert.NoError(t, c.List(context.Background(), jobs, client.InNamespace("test")))
+		assert.Empty(t, jobs.Items, test.name)
+		trackedStages.forget(client.ObjectKeyFromObject(k6))
+	}
+}
+
+func Test_initializerMessageRetried(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+	pod := func(name string, phase corev1.PodPhase, msg string) *corev1.Pod {
+		podLabels := map[string]string{"app": "k6", "k6_cr": "test", "job-name": "test-initializer"}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: podLabels},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "k6",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: msg}},
+				}},
+			},
+		}
+	}
+
+	// the failed attempt is listed first
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("test-initializer-aaaaa", corev1.PodFailed, "failed attempt"),
+		pod("test-initializer-bbbbb", corev1.PodSucceeded, `{"archive":"0.45.0","k6":"0.46.0"}`),
+	).Build()
+	msg, err := initializerMessage(context.Background(), k6, &K6Reconciler{Client: c})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"archive":"0.45.0","k6":"0.46.0"}`, msg)
+
+	// without a succeeded pod, the message of the failed one is still reported
+	c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("test-initializer-aaaaa", corev1.PodFailed, "failed attempt"),
+	).Build()
+	msg, err = initializerMessage(context.Background(), k6, &K6Reconciler{Client: c})
+	assert.NoError(t, err)
+	assert.Equal(t, "failed attempt", msg)
+
+	// failures to list the pods are returned
+	c = fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+	_, err = initializerMessage(context.Background(), k6, &K6Reconciler{Client: c})
+	assert.Error(t, err)
+}
+
+func Test_inspectTestRunMissingInitializer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	k6 := v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+
+	// the initializer was removed, e.g. on expiry of its TTL, before its result was read
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	_, ready, err := inspectTestRun(context.Background(), logr.Discard(), k6, &K6Reconciler{Client: c})
+	assert.Error(t, err)
+	assert.False(t, ready)
+
+	// the initializer is still running
+	initializer := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-initializer", Namespace: "test"}}
+	c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(initializer).Build()
+	_, ready, err = inspectTestRun(context.Background(), logr.Discard(), k6, &K6Reconciler{Client: c})
+	assert.NoError(t, err)
+	assert.False(t, ready)
+}
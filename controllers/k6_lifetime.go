@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// ExpireTestRun stops the test run that has exceeded its max lifetime,
+// whatever the stage it is in, and sets it to error. The cloud test run, if
+// any, is finalized first, like on abort.
+func ExpireTestRun(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	err := fmt.Errorf("test run has exceeded its max lifetime of %s in stage %q", k6.Spec.MaxLifetime.Duration, k6.Status.Stage)
+	log.Error(err, "Stopping the test run")
+	r.recordEvent(k6, corev1.EventTypeWarning, "MaxLifetimeExceeded", err.Error())
+
+	if requeueAfter, err := tearDown(ctx, log, k6, r); err != nil || requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
+	}
+
+	k6.Status.Stage = "error"
+	k6.Status.Phase = "Failed"
+	k6.Status.PhaseReason = fmt.Sprintf("exceeded max lifetime of %s", k6.Spec.MaxLifetime.Duration)
+	if k6.IsTrue(v1alpha1.TestRunRunning) {
+		k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionFalse)
+	}
+
+	if _, err = r.UpdateStatus(ctx, k6, log); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// tearDown deletes the initializer, stops the runners and finalizes the cloud
+// test run, if any, of the test run that is about to be set to error or
+// restarted. It returns the time until it should be called again, if it's not
+// done yet.
+func tearDown(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	if err := deleteInitializer(ctx, log, k6, r); err != nil {
+		return 0, err
+	}
+
+	if stopping, err := stopRunners(ctx, log, k6, r); err != nil || stopping > 0 {
+		return stopping, err
+	}
+
+	return FinalizeCloudTestRun(ctx, log, k6, r)
+}
+
+// deleteInitializer removes the initializer job, if it exists.
+func deleteInitializer(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	initializer := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-initializer", k6.Name), Namespace: k6.Namespace}, initializer)
+	if err == nil {
+		err = r.Delete(ctx, initializer, propagationPolicy(k6))
+	}
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		log.Error(err, "Could not delete initializer job")
+		return err
+	}
+	return nil
+}
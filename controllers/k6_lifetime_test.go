@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_ExpireTestRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error) {
+		finalizeCloudTestRunFn = finalize
+	}(finalizeCloudTestRunFn)
+
+	k6 := newCloudK6()
+	k6.Spec.MaxLifetime = &metav1.Duration{Duration: time.Hour}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, Log: logr.Discard()}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	var finalized []string
+	finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, runStatus cloudapi.RunStatus) error {
+		assert.Equal(t, cloudapi.RunStatusFinished, runStatus)
+		finalized = append(finalized, refID)
+		return nil
+	}
+
+	_, err := ExpireTestRun(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"123"}, finalized)
+	assert.True(t, k6.IsTrue(v1alpha1.CloudTestRunFinalized))
+	assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage)
+	assert.Equal(t, "exceeded max lifetime of 1h0m0s", k6.Status.PhaseReason)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/cloud"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.k6.io/k6/cloudapi"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	testRunsByStage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k6_operator_test_runs",
+		Help: "Number of K6 resources by the stage of the test run.",
+	}, []string{"stage"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k6_operator_reconcile_errors_total",
+		Help: "Number of reconciliations of K6 resources that returned an error, by stage.",
+	}, []string{"stage"})
+
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k6_operator_stage_duration_seconds",
+		Help:    "Time test runs have spent in a stage, measured when they leave it.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600, 7200},
+	}, []string{"stage"})
+
+	activeRunnerPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k6_operator_active_runner_pods",
+		Help: "Number of active runner pods of the K6 resource.",
+	}, []string{"namespace", "name"})
+
+	cloudAPIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k6_operator_cloud_api_requests_total",
+		Help: "Number of requests made to k6 Cloud API, by operation and result.",
+	}, []string{"operation", "result"})
+)
+
+func init() {
+	// controller-runtime serves its registry on the metrics endpoint of the manager
+	metrics.Registry.MustRegister(testRunsByStage, reconcileErrors, stageDuration, activeRunnerPods, cloudAPIRequests)
+}
+
+// stageTracker remembers the last known stage of each K6 resource so that
+// the gauge of test runs by stage can be kept up to date.
+type stageTracker struct {
+	sync.Mutex
+	stages map[types.NamespacedName]v1alpha1.Stage
+}
+
+var trackedStages = &stageTracker{stages: map[types.NamespacedName]v1alpha1.Stage{}}
+
+// set records the current stage of the K6 resource.
+func (t *stageTracker) set(k6 *v1alpha1.K6) {
+	key := types.NamespacedName{Namespace: k6.Namespace, Name: k6.Name}
+
+	t.Lock()
+	defer t.Unlock()
+
+	if previous, ok := t.stages[key]; ok {
+		if previous == k6.Status.Stage {
+			return
+		}
+		testRunsByStage.WithLabelValues(stageLabel(previous)).Dec()
+	}
+	t.stages[key] = k6.Status.Stage
+	testRunsByStage.WithLabelValues(stageLabel(k6.Status.Stage)).Inc()
+}
+
+// forget removes the deleted K6 resource from the metrics.
+func (t *stageTracker) forget(key types.NamespacedName) {
+	t.Lock()
+	defer t.Unlock()
+
+	if previous, ok := t.stages[key]; ok {
+		testRunsByStage.WithLabelValues(stageLabel(previous)).Dec()
+		delete(t.stages, key)
+	}
+	activeRunnerPods.DeleteLabelValues(key.Namespace, key.Name)
+}
+
+// stageLabel names the stage of brand new resources which is empty in status.
+func stageLabel(stage v1alpha1.Stage) string {
+	if len(stage) == 0 {
+		return "new"
+	}
+	return string(stage)
+}
+
+// observeStageChange records the time the test run spent in its previous
+// stage: since it was entered or, for the first stage, since creation of the
+// resource.
+func observeStageChange(previous *v1alpha1.K6, now time.Time) {
+	since := previous.CreationTimestamp.Time
+	if previous.Status.StageTime != nil {
+		since = previous.Status.StageTime.Time
+	}
+	if !since.IsZero() && now.After(since) {
+		stageDuration.WithLabelValues(stageLabel(previous.Status.Stage)).Observe(now.Sub(since).Seconds())
+	}
+}
+
+// observeCloudRequest counts a request to k6 Cloud API.
+func observeCloudRequest(operation string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	cloudAPIRequests.WithLabelValues(operation, result).Inc()
+}
+
+// The wrappers of k6 Cloud API below count the requests made by the operator.
+
+func createCloudTestRun(client *cloudapi.Client, opts cloud.InspectOutput, instances int32, host, note string, log logr.Logger) (*cloudapi.CreateTestRunResponse, error) {
+	testRun, err := cloud.CreateTestRun(client, opts, instances, host, note, log)
+	observeCloudRequest("create_test_run", err)
+	return testRun, err
+}
+
+func getCloudProject(client *cloudapi.Client, refID, host string, projectID int64) (*cloud.Project, error) {
+	project, err := cloud.GetProject(client, refID, host, projectID)
+	observeCloudRequest("get_project", err)
+	return project, err
+}
+
+func finishCloudTestRun(client *cloudapi.Client, refID string, runStatus cloudapi.RunStatus) error {
+	err := cloud.FinishTestRun(client, refID, runStatus)
+	observeCloudRequest("finish_test_run", err)
+	return err
+}
+
+func isCloudTestRunFinished(client *cloudapi.Client, refID string) (bool, cloudapi.RunStatus, error) {
+	finished, runStatus, err := cloud.IsTestRunFinished(client, refID)
+	observeCloudRequest("get_test_progress", err)
+	return finished, runStatus, err
+}
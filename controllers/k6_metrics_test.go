@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// gatherMetric scrapes the registry of the metrics endpoint and returns the
+// series of the metric family with all given label values.
+func gatherMetric(t *testing.T, name string, labels map[string]string) *dto.Metric {
+	families, err := metrics.Registry.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, metric := range family.GetMetric() {
+			values := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				values[label.GetName()] = label.GetValue()
+			}
+			for name, value := range labels {
+				if values[name] != value {
+					continue series
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+func Test_MetricsStages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, batchv1.AddToScheme(scheme))
+
+	stageTime := metav1.NewTime(time.Now().Add(-time.Minute))
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2},
+		Status:     v1alpha1.K6Status{Stage: "initialized", StageTime: &stageTime},
+	}
+	runner := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-1", Namespace: "test", Labels: map[string]string{"app": "k6", "k6_cr": "metrics", "runner": "true"}},
+		Status:     batchv1.JobStatus{Active: 1},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), runner).Build()
+	r := &K6Reconciler{Client: c}
+
+	initialized := testutil.ToFloat64(testRunsByStage.WithLabelValues("initialized"))
+	created := testutil.ToFloat64(testRunsByStage.WithLabelValues("created"))
+	durations := uint64(0)
+	if metric := gatherMetric(t, "k6_operator_stage_duration_seconds", map[string]string{"stage": "initialized"}); metric != nil {
+		durations = metric.GetHistogram().GetSampleCount()
+	}
+
+	trackedStages.set(k6)
+	assert.Equal(t, initialized+1, testutil.ToFloat64(testRunsByStage.WithLabelValues("initialized")))
+
+	k6.Status.Stage = "created"
+	updated, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	assert.Equal(t, initialized, testutil.ToFloat64(testRunsByStage.WithLabelValues("initialized")))
+	assert.Equal(t, created+1, testutil.ToFloat64(testRunsByStage.WithLabelValues("created")))
+
+	// the time spent in the stage comes from status
+	assert.NotNil(t, k6.Status.StageTime)
+	assert.True(t, k6.Status.StageTime.After(stageTime.Time))
+	metric := gatherMetric(t, "k6_operator_stage_duration_seconds", map[string]string{"stage": "initialized"})
+	if assert.NotNil(t, metric) {
+		assert.Equal(t, durations+1, metric.GetHistogram().GetSampleCount())
+		assert.GreaterOrEqual(t, metric.GetHistogram().GetSampleSum(), 60.0)
+	}
+
+	assert.False(t, FinishJobs(context.Background(), logr.Discard(), k6, r))
+	assert.Equal(t, 1.0, testutil.ToFloat64(activeRunnerPods.WithLabelValues("test", "metrics")))
+
+	// deleted resources are not counted anymore
+	trackedStages.forget(types.NamespacedName{Name: "metrics", Namespace: "test"})
+	assert.Equal(t, created, testutil.ToFloat64(testRunsByStage.WithLabelValues("created")))
+	assert.Nil(t, gatherMetric(t, "k6_operator_active_runner_pods", map[string]string{"namespace": "test", "name": "metrics"}))
+}
+
+func Test_MetricsReconcileErrors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "test"},
+		Status:     v1alpha1.K6Status{Stage: "invalid"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6).Build()
+	r := &K6Reconciler{Client: c, Log: logr.Discard()}
+
+	errorsBefore := testutil.ToFloat64(reconcileErrors.WithLabelValues("invalid"))
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "invalid", Namespace: "test"}})
+	assert.Error(t, err)
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(reconcileErrors.WithLabelValues("invalid")))
+
+	trackedStages.forget(types.NamespacedName{Name: "invalid", Namespace: "test"})
+}
+
+func Test_MetricsCloudRequests(t *testing.T) {
+	success := testutil.ToFloat64(cloudAPIRequests.WithLabelValues("finish_test_run", "success"))
+	failure := testutil.ToFloat64(cloudAPIRequests.WithLabelValues("finish_test_run", "error"))
+
+	observeCloudRequest("finish_test_run", nil)
+	observeCloudRequest("finish_test_run", errors.New("unavailable"))
+	observeCloudRequest("finish_test_run", errors.New("unavailable"))
+
+	assert.Equal(t, success+1, testutil.ToFloat64(cloudAPIRequests.WithLabelValues("finish_test_run", "success")))
+	assert.Equal(t, failure+2, testutil.ToFloat64(cloudAPIRequests.WithLabelValues("finish_test_run", "error")))
+}
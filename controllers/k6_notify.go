@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/notify"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// secretValue reads the value of the key from the Secret in the namespace of the K6 resource.
+func secretValue(ctx context.Context, k6 *v1alpha1.K6, r *K6Reconciler, selector corev1.SecretKeySelector) (string, error) {
+	secret := &corev1.Secret{}
+	if err := r.apiReader().Get(ctx, types.NamespacedName{Name: selector.Name, Namespace: k6.Namespace}, secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s doesn't have a key %s", selector.Name, selector.Key)
+	}
+	return string(value), nil
+}
+
+// annotate creates Grafana annotation with the text, if it's configured. It
+// only warns on failures as the test run must go on regardless.
+func annotate(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, text string) {
+	if k6.Spec.Notify == nil || k6.Spec.Notify.GrafanaAnnotation == nil {
+		return
+	}
+	config := k6.Spec.Notify.GrafanaAnnotation
+
+	err := func() error {
+		apiKey, err := secretValue(ctx, k6, r, config.APIKey)
+		if err != nil {
+			return err
+		}
+
+		return notify.CreateAnnotation(ctx, config.URL, apiKey, notify.Annotation{
+			DashboardUID: config.DashboardUID,
+			PanelID:      config.PanelID,
+			Tags:         append([]string{"k6", k6.Name}, config.Tags...),
+			Text:         text,
+		})
+	}()
+
+	if err != nil {
+		log.Error(err, "Failed to create Grafana annotation")
+		r.recordEvent(k6, corev1.EventTypeWarning, "NotifyFailed", fmt.Sprintf("Failed to create Grafana annotation: %v", err))
+	}
+}
+
+var (
+	// defaultWebhookAttempts is used for webhooks without maxAttempts.
+	defaultWebhookAttempts int32 = 3
+	// webhookBackoff is the delay before the first retry of a webhook,
+	// doubled with every next one.
+	webhookBackoff = time.Second
+)
+
+// testRunResult composes the payload of the webhook from status of the test run.
+func testRunResult(k6 *v1alpha1.K6, now time.Time) notify.TestRunResult {
+	result := notify.TestRunResult{
+		Name:      k6.Name,
+		Namespace: k6.Namespace,
+		Stage:     string(k6.Status.Stage),
+		Phase:     string(k6.Status.Phase),
+		Reason:    k6.Status.PhaseReason,
+		Passed:    k6.Status.Phase == "Succeeded",
+		RunID:     k6.Status.RunID,
+		TestRunID: k6.Status.TestRunID,
+		EndTime:   now,
+	}
+	if k6.Status.StartTime != nil {
+		start := k6.Status.StartTime.Time
+		result.StartTime = &start
+	}
+	// runners are done by the time TestRunRunning becomes False
+	if condition := meta.FindStatusCondition(k6.Status.Conditions, v1alpha1.TestRunRunning); condition != nil &&
+		condition.Status == metav1.ConditionFalse && k6.Status.StartTime != nil {
+		result.EndTime = condition.LastTransitionTime.Time
+	}
+	return result
+}
+
+// notifyWebhook posts the result of the test run to the webhook, if it's
+// configured, and records the outcome in WebhookDelivered condition so that
+// the result is posted only once. Failed attempts are counted in status and
+// retried with backoff by requeueing: it returns the time of the next attempt.
+// Failures to deliver never fail the test run.
+func notifyWebhook(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	if k6.Spec.Notify == nil || k6.Spec.Notify.Webhook == nil || !k6.IsUnknown(v1alpha1.WebhookDelivered) {
+		return 0, nil
+	}
+	config := k6.Spec.Notify.Webhook
+
+	attempts := defaultWebhookAttempts
+	if config.MaxAttempts > 0 {
+		attempts = config.MaxAttempts
+	}
+
+	retry, err := func() (bool, error) {
+		var authorization string
+		if config.Authorization != nil {
+			var err error
+			if authorization, err = secretValue(ctx, k6, r, *config.Authorization); err != nil {
+				return false, err
+			}
+		}
+		return notify.PostWebhook(ctx, config.URL, authorization, testRunResult(k6, r.now()))
+	}()
+
+	if err != nil {
+		k6.Status.WebhookAttempts++
+		attempt := k6.Status.WebhookAttempts
+		if retry && attempt < attempts {
+			log.Error(err, fmt.Sprintf("Failed to deliver the result to the webhook (%d/%d), retrying", attempt, attempts))
+			if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+				return 0, err
+			}
+			return webhookBackoff << (attempt - 1), nil
+		}
+
+		log.Error(err, fmt.Sprintf("Failed to deliver the result to the webhook after %d attempts", attempt))
+		r.recordEvent(k6, corev1.EventTypeWarning, "NotifyFailed", fmt.Sprintf("Failed to deliver the result to the webhook: %v", err))
+		k6.UpdateCondition(v1alpha1.WebhookDelivered, metav1.ConditionFalse)
+	} else {
+		k6.UpdateCondition(v1alpha1.WebhookDelivered, metav1.ConditionTrue)
+	}
+
+	_, err = r.UpdateStatus(ctx, k6, log)
+	return 0, err
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_NotifyWebhook(t *testing.T) {
+	var (
+		received []notify.TestRunResult
+		auth     string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result notify.TestRunResult
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&result))
+		received = append(received, result)
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	start := metav1.Now()
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 1,
+			Notify: &v1alpha1.Notify{Webhook: &v1alpha1.Webhook{
+				URL: server.URL,
+				Authorization: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "webhook"},
+					Key:                  "auth",
+				},
+			}},
+		},
+		Status: v1alpha1.K6Status{Stage: "finished", Phase: "Succeeded", TestRunID: "123", StartTime: &start},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "test"},
+		Data:       map[string][]byte{"auth": []byte("Bearer secret")},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), secret).Build()
+	r := &K6Reconciler{Client: c}
+
+	_, err := r.reconcileStage(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+	// the result is posted only once
+	_, err = r.reconcileStage(context.Background(), logr.Discard(), k6)
+	assert.NoError(t, err)
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, "test", received[0].Name)
+		assert.Equal(t, "finished", received[0].Stage)
+		assert.Equal(t, "123", received[0].TestRunID)
+		assert.True(t, received[0].Passed)
+		assert.Equal(t, start.Unix(), received[0].StartTime.Unix())
+	}
+	assert.Equal(t, "Bearer secret", auth)
+
+	current := &v1alpha1.K6{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "test", Namespace: "test"}, current))
+	assert.True(t, current.IsTrue(v1alpha1.WebhookDelivered))
+}
+
+func Test_NotifyWebhookRetries(t *testing.T) {
+	codes := []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(codes[hits])
+		hits++
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	newK6 := func(maxAttempts int32) *v1alpha1.K6 {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec: v1alpha1.K6Spec{
+				Notify: &v1alpha1.Notify{Webhook: &v1alpha1.Webhook{URL: server.URL, MaxAttempts: maxAttempts}},
+			},
+			Status: v1alpha1.K6Status{Stage: "finished", Phase: "Succeeded"},
+		}
+		k6.InitializeConditions()
+		return k6
+	}
+
+	// failed attempts requeue with backoff instead of waiting in the reconcile
+	k6 := newK6(0)
+	r := &K6Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()}
+	for attempt, backoff := range []time.Duration{webhookBackoff, 2 * webhookBackoff, 0} {
+		retryAfter, err := notifyWebhook(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err)
+		assert.Equal(t, backoff, retryAfter, "attempt %d", attempt+1)
+		assert.Equal(t, attempt+1, hits)
+	}
+	assert.True(t, k6.IsTrue(v1alpha1.WebhookDelivered))
+	assert.Equal(t, int32(2), k6.Status.WebhookAttempts)
+
+	// attempts are given up on after maxAttempts
+	hits = 0
+	k6 = newK6(2)
+	r = &K6Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()}
+	retryAfter, err := notifyWebhook(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, webhookBackoff, retryAfter)
+	retryAfter, err = notifyWebhook(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	assert.Equal(t, 2, hits)
+	assert.True(t, k6.IsFalse(v1alpha1.WebhookDelivered))
+}
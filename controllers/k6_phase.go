@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+)
+
+// setPhase records the phase of the test run with its reason, if they've
+// changed. Failure to do so is only logged as phase is for users only.
+func setPhase(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, phase v1alpha1.Phase, reason string) {
+	if k6.Status.Phase == phase && k6.Status.PhaseReason == reason {
+		return
+	}
+
+	k6.Status.Phase = phase
+	k6.Status.PhaseReason = reason
+	if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		log.Error(err, "Could not update phase of the test run")
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	k6v1alpha1 "github.com/grafana/k6-operator/api/v1alpha1"
+)
+
+// getTable gets the resource at the path as rendered by kubectl get.
+func getTable(path string) *metav1.Table {
+	httpClient, err := rest.HTTPClientFor(cfg)
+	Expect(err).ToNot(HaveOccurred())
+
+	req, err := http.NewRequest(http.MethodGet, cfg.Host+path, nil)
+	Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Accept", "application/json;as=Table;v=v1;g=meta.k8s.io")
+
+	resp, err := httpClient.Do(req)
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	table := &metav1.Table{}
+	Expect(json.NewDecoder(resp.Body).Decode(table)).To(Succeed())
+	return table
+}
+
+var _ = Describe("K6 printer columns", func() {
+	It("shows stage, parallelism, test run ID and age", func() {
+		ctx := context.Background()
+
+		k6 := &k6v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "printcolumns", Namespace: "default"},
+			Spec: k6v1alpha1.K6Spec{
+				Parallelism: 4,
+				Script:      k6v1alpha1.K6Script{ConfigMap: k6v1alpha1.K6Configmap{Name: "test", File: "test.js"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, k6)).To(Succeed())
+		defer func() {
+			Expect(k8sClient.Delete(ctx, k6)).To(Succeed())
+		}()
+
+		k6.Status.Stage = "started"
+		k6.Status.TestRunID = "123"
+		Expect(k8sClient.Status().Update(ctx, k6)).To(Succeed())
+
+		table := getTable("/apis/k6.io/v1alpha1/namespaces/default/k6s/printcolumns")
+		Expect(table.Rows).To(HaveLen(1))
+
+		cells := map[string]string{}
+		for i, column := range table.ColumnDefinitions {
+			cells[column.Name] = fmt.Sprint(table.Rows[0].Cells[i])
+		}
+		Expect(cells).To(HaveKeyWithValue("Name", "printcolumns"))
+		Expect(cells).To(HaveKeyWithValue("Stage", "started"))
+		Expect(cells).To(HaveKeyWithValue("Parallelism", "4"))
+		Expect(cells).To(HaveKeyWithValue("TestRunID", "123"))
+		Expect(cells).To(HaveKey("Age"))
+		Expect(cells["Age"]).ToNot(BeEmpty())
+	})
+})
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReadinessPath is the prefix of the readiness endpoint: the full path is
+// ReadinessPath + "<namespace>/<name>" of the K6 resource.
+const ReadinessPath = "/k6/readiness/"
+
+// Readiness is an aggregate of readiness of all runners of a test run.
+type Readiness struct {
+	Stage       v1alpha1.Stage `json:"stage"`
+	Parallelism int32          `json:"parallelism"`
+	Scheduled   int32          `json:"scheduled"`
+	Running     int32          `json:"running"`
+	Ready       int32          `json:"ready"`
+	AllReady    bool           `json:"allReady"`
+}
+
+// isPodReady returns true if the pod passes its readiness probe.
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// ReadinessHandler serves readiness of runners per K6 resource, with
+// the 200 status code only when all of them are ready. External systems
+// can poll it instead of enumerating runner services.
+func ReadinessHandler(c client.Reader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(req.URL.Path, ReadinessPath), "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			http.Error(w, "expected path "+ReadinessPath+"<namespace>/<name>", http.StatusBadRequest)
+			return
+		}
+
+		k6 := &v1alpha1.K6{}
+		if err := c.Get(req.Context(), types.NamespacedName{Namespace: parts[0], Name: parts[1]}, k6); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		pl := &v1.PodList{}
+		if err := listSelected(req.Context(), c, pl, runnerListOptions(k6)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		readiness := Readiness{
+			Stage:       k6.Status.Stage,
+			Parallelism: k6.Spec.Parallelism,
+		}
+		for i := range pl.Items {
+			pod := &pl.Items[i]
+			if len(pod.Spec.NodeName) > 0 {
+				readiness.Scheduled++
+			}
+			if pod.Status.Phase == v1.PodRunning {
+				readiness.Running++
+			}
+			if isPodReady(pod) {
+				readiness.Ready++
+			}
+		}
+		readiness.AllReady = readiness.Parallelism > 0 && readiness.Ready == readiness.Parallelism
+
+		w.Header().Set("Content-Type", "application/json")
+		if !readiness.AllReady {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(readiness)
+	})
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func runnerPod(k6 *v1alpha1.K6, name string, ready bool) *v1.Pod {
+	status := v1.ConditionFalse
+	if ready {
+		status = v1.ConditionTrue
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: k6.Namespace,
+			Labels:    jobs.NewRunnerLabels(k6),
+		},
+		Spec: v1.PodSpec{NodeName: "node"},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: status}},
+		},
+	}
+}
+
+func Test_ReadinessHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2},
+		Status:     v1alpha1.K6Status{Stage: "created"},
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		pods      []*v1.Pod
+		code      int
+		readiness *Readiness
+	}{
+		{
+			"NotReady",
+			ReadinessPath + "test/test",
+			[]*v1.Pod{runnerPod(k6, "test-1", true), runnerPod(k6, "test-2", false)},
+			http.StatusServiceUnavailable,
+			&Readiness{Stage: "created", Parallelism: 2, Scheduled: 2, Running: 2, Ready: 1},
+		},
+		{
+			"AllReady",
+			ReadinessPath + "test/test",
+			[]*v1.Pod{runnerPod(k6, "test-1", true), runnerPod(k6, "test-2", true)},
+			http.StatusOK,
+			&Readiness{Stage: "created", Parallelism: 2, Scheduled: 2, Running: 2, Ready: 2, AllReady: true},
+		},
+		{"NotFound", ReadinessPath + "test/other", nil, http.StatusNotFound, nil},
+		{"InvalidPath", ReadinessPath + "test", nil, http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy())
+			for _, pod := range test.pods {
+				builder = builder.WithObjects(pod)
+			}
+
+			rec := httptest.NewRecorder()
+			ReadinessHandler(builder.Build()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.code, rec.Code)
+			if test.readiness != nil {
+				readiness := &Readiness{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(readiness))
+				assert.Equal(t, test.readiness, readiness)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RestartTestRun stops the active test run after a change of its spec and
+// resets the status so that the test run is started anew with the new spec.
+// The cloud test run, if any, is finalized as aborted by the user first.
+func RestartTestRun(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (ctrl.Result, error) {
+	msg := fmt.Sprintf("Spec has changed in stage %q, restarting the test run", k6.Status.Stage)
+	log.Info(msg)
+	r.recordEvent(k6, corev1.EventTypeNormal, "Restarting", msg)
+
+	// The status is reset below, so the condition only tells the cloud how
+	// the test run has ended.
+	k6.UpdateCondition(v1alpha1.TestRunAborted, metav1.ConditionTrue)
+	if requeueAfter, err := tearDown(ctx, log, k6, r); err != nil || requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
+	}
+
+	// Going back to the first stage is not a progression of the test run,
+	// so the status is replaced as a whole instead of UpdateStatus.
+	cleanObj := k6.DeepCopyObject().(client.Object)
+	k6.Status = v1alpha1.K6Status{}
+	if err := r.Client.Status().Patch(ctx, k6, client.MergeFrom(cleanObj)); err != nil {
+		log.Error(err, "Could not reset status of the test run")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{Requeue: true}, nil
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RestartTestRun(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error) {
+		finalizeCloudTestRunFn = finalize
+	}(finalizeCloudTestRunFn)
+
+	k6 := newCloudK6()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, Log: logr.Discard()}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	var finalized []string
+	finalizeCloudTestRunFn = func(_ *cloudapi.Client, refID string, runStatus cloudapi.RunStatus) error {
+		assert.Equal(t, cloudapi.RunStatusAbortedUser, runStatus)
+		finalized = append(finalized, refID)
+		return nil
+	}
+
+	res, err := RestartTestRun(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, res.Requeue)
+	assert.Equal(t, []string{"123"}, finalized)
+	// the test run starts anew, with a new cloud test run
+	assert.Empty(t, k6.Status.Stage)
+	assert.Empty(t, k6.Status.TestRunID)
+}
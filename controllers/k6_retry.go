@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/results"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RetryJobs checks whether finished test run failed because of infrastructure
+// and if the retry policy allows it, removes the runners so that the test run
+// can be started anew. It returns true if the retry was started.
+func RetryJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (retried bool, err error) {
+	policy := k6.Spec.RetryPolicy
+	if policy == nil || policy.RetryOn != "InfraFailure" || k6.Status.Retries >= policy.MaxRetries {
+		return false, nil
+	}
+
+	if k6.IsTrue(v1alpha1.CloudTestRun) {
+		// runners can't push metrics to the same cloud test run twice
+		log.Info("Retries are not supported for test runs with cloud output")
+		return false, nil
+	}
+
+	opts := runnerListOptions(k6)
+
+	pl := &corev1.PodList{}
+	if err = listSelected(ctx, r, pl, opts); err != nil {
+		log.Error(err, "Could not list pods")
+		return false, err
+	}
+
+	var infraFailure string
+	for _, pod := range pl.Items {
+		result := results.FromPod(&pod)
+		switch result.Class {
+		case results.TestFailure:
+			// at least one runner reported a real test result
+			log.Info(fmt.Sprintf("Runner %s failed with exit code %d, not retrying", pod.Name, result.ExitCode))
+			return false, nil
+		case results.InfraFailure:
+			infraFailure = fmt.Sprintf("%s: %s", pod.Name, result.Reason)
+		}
+	}
+
+	if len(infraFailure) == 0 {
+		return false, nil
+	}
+
+	log.Info(fmt.Sprintf("Infrastructure failure detected (%s), retrying test run %d/%d",
+		infraFailure, k6.Status.Retries+1, policy.MaxRetries))
+
+	if err = deleteRunners(ctx, log, k6, r, opts); err != nil {
+		return false, err
+	}
+
+	k6.Status.Retries++
+	k6.Status.Stage = "initialized"
+	k6.Status.RunnerResults = nil
+	k6.Status.ExitCode = nil
+	k6.Status.ExitReason = ""
+	k6.Status.StartedRunners = 0
+	// the retry is timed on its own once its runners are started
+	k6.Status.StartTime = nil
+	k6.Status.Phase = "Pending"
+	k6.Status.PhaseReason = fmt.Sprintf("retrying after infrastructure failure (%d/%d)", k6.Status.Retries, policy.MaxRetries)
+	k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionFalse)
+	// results of the failed attempt don't apply to the retry
+	if k6.IsTrue(v1alpha1.PartialFailure) {
+		k6.UpdateCondition(v1alpha1.PartialFailure, metav1.ConditionFalse)
+	}
+
+	if _, err = r.UpdateStatus(ctx, k6, log); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// deleteRunners removes runner jobs, their services and the starter job.
+func deleteRunners(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, opts *client.ListOptions) error {
+	// With Foreground propagation, runner jobs stay until their pods are
+	// gone and creation of new runners waits for that.
+	propagation := propagationPolicy(k6)
+
+	jl := &batchv1.JobList{}
+	if err := listSelected(ctx, r, jl, opts); err != nil {
+		log.Error(err, "Could not list jobs")
+		return err
+	}
+	for i := range jl.Items {
+		if err := r.Delete(ctx, &jl.Items[i], propagation); err != nil && !k8sErrors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("Could not delete job %s", jl.Items[i].Name))
+			return err
+		}
+	}
+
+	sl := &corev1.ServiceList{}
+	if err := listSelected(ctx, r, sl, opts); err != nil {
+		log.Error(err, "Could not list services")
+		return err
+	}
+	for i := range sl.Items {
+		if err := r.Delete(ctx, &sl.Items[i]); err != nil && !k8sErrors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("Could not delete service %s", sl.Items[i].Name))
+			return err
+		}
+	}
+
+	starter := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-starter", k6.Name), Namespace: k6.Namespace}, starter)
+	if err == nil {
+		err = r.Delete(ctx, starter, propagation)
+	}
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		log.Error(err, "Could not delete starter job")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_RetryJobsClearsResults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test", UID: "test-uid"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 2,
+			RetryPolicy: &v1alpha1.RetryPolicy{RetryOn: "InfraFailure", MaxRetries: 1},
+		},
+		Status: v1alpha1.K6Status{Stage: "started", RunID: "run-1"},
+	}
+	k6.InitializeConditions()
+
+	runner := func(index string, terminated v1.ContainerStateTerminated) *v1.Pod {
+		labels := jobs.NewRunnerLabels(k6)
+		labels["job-name"] = "test-" + index
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-" + index + "-abcde", Namespace: "test", Labels: labels},
+			Status: v1.PodStatus{
+				Phase:             v1.PodFailed,
+				ContainerStatuses: []v1.ContainerStatus{{Name: "k6", State: v1.ContainerState{Terminated: &terminated}}},
+			},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		k6.DeepCopy(),
+		runner("1", v1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}),
+		runner("2", v1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}),
+	).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	assert.NoError(t, CollectRunnerResults(context.Background(), logr.Discard(), k6, r))
+	assert.True(t, k6.IsTrue(v1alpha1.PartialFailure))
+	_, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+	assert.NoError(t, err)
+
+	retried, err := RetryJobs(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, retried)
+
+	// the retry starts without the results of the failed attempt
+	stored := &v1alpha1.K6{}
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), stored))
+	assert.Equal(t, int32(1), stored.Status.Retries)
+	assert.Empty(t, stored.Status.RunnerResults)
+	assert.False(t, stored.IsTrue(v1alpha1.PartialFailure))
+}
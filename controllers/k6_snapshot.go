@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// maxSnapshots caps the history of snapshots in status: the oldest
+// snapshots are dropped first.
+const maxSnapshots = 60
+
+// metricsAPIResponse is a subset of k6 REST API response for /v1/metrics.
+type metricsAPIResponse struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Sample map[string]float64 `json:"sample"`
+			// Tainted is null for metrics without thresholds.
+			Tainted *bool `json:"tainted"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// getRunnerMetrics fetches the current values of metrics from the runner behind the service.
+func getRunnerMetrics(k6 *v1alpha1.K6, service *v1.Service) (*metricsAPIResponse, error) {
+	resp, err := runnerAPIClient(k6).Get(runnerServiceURL(k6, service) + "/v1/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to get metrics from %v: status code %d", service.Name, resp.StatusCode)
+	}
+
+	metrics := &metricsAPIResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// aggregateMetrics sums up metrics of all runners into a single snapshot.
+// The error rate is weighted by the number of requests of each runner.
+func aggregateMetrics(runners []*metricsAPIResponse, now time.Time) v1alpha1.MetricSnapshot {
+	snapshot := v1alpha1.MetricSnapshot{Time: metav1.NewTime(now)}
+
+	var failures float64
+	for _, metrics := range runners {
+		var reqs, failedRate float64
+		for _, metric := range metrics.Data {
+			sample := metric.Attributes.Sample
+			switch metric.ID {
+			case "vus":
+				snapshot.VUs += int64(sample["value"])
+			case "iterations":
+				snapshot.Iterations += int64(sample["count"])
+			case "http_reqs":
+				reqs = sample["count"]
+			case "http_req_failed":
+				failedRate = sample["rate"]
+			}
+		}
+		snapshot.HTTPReqs += int64(reqs)
+		failures += reqs * failedRate
+	}
+
+	if snapshot.HTTPReqs > 0 {
+		snapshot.ErrorRate = fmt.Sprintf("%.4f", failures/float64(snapshot.HTTPReqs))
+	}
+	return snapshot
+}
+
+// appendSnapshot adds the snapshot to the history, keeping at most maxSnapshots.
+func appendSnapshot(snapshots []v1alpha1.MetricSnapshot, snapshot v1alpha1.MetricSnapshot) []v1alpha1.MetricSnapshot {
+	snapshots = append(snapshots, snapshot)
+	if len(snapshots) > maxSnapshots {
+		snapshots = snapshots[len(snapshots)-maxSnapshots:]
+	}
+	return snapshots
+}
+
+// SnapshotMetrics records aggregated metrics of the started runners in
+// status, once per snapshot interval. It returns the time left until the next
+// snapshot is due.
+func SnapshotMetrics(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	interval := k6.Spec.Runner.SnapshotInterval.Duration
+
+	if n := len(k6.Status.Snapshots); n > 0 {
+		if left := interval - r.now().Sub(k6.Status.Snapshots[n-1].Time.Time); left > 0 {
+			return left, nil
+		}
+	}
+
+	sl := &v1.ServiceList{}
+	if err := listSelected(ctx, r, sl, runnerListOptions(k6)); err != nil {
+		log.Error(err, "Could not list services")
+		return 0, err
+	}
+
+	var runners []*metricsAPIResponse
+	for i := range sl.Items {
+		// runners that weren't started yet have nothing to report
+		if index, err := runnerIndex(k6, sl.Items[i].Name); err != nil || index > k6.Status.StartedRunners {
+			continue
+		}
+
+		metrics, err := getRunnerMetrics(k6, &sl.Items[i])
+		if err != nil {
+			// the runner may have finished already
+			log.Info(fmt.Sprintf("Skipping runner behind %v in the snapshot: %v", sl.Items[i].Name, err))
+			continue
+		}
+		runners = append(runners, metrics)
+	}
+
+	if len(runners) == 0 {
+		return interval, nil
+	}
+
+	k6.Status.Snapshots = appendSnapshot(k6.Status.Snapshots, aggregateMetrics(runners, r.now()))
+	if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+		return 0, err
+	}
+
+	return interval, nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func runnerMetrics(t *testing.T, data string) *metricsAPIResponse {
+	metrics := &metricsAPIResponse{}
+	assert.NoError(t, json.Unmarshal([]byte(data), metrics))
+	return metrics
+}
+
+func Test_aggregateMetrics(t *testing.T) {
+	now := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	runners := []*metricsAPIResponse{
+		runnerMetrics(t, `{"data":[
+			{"id":"vus","attributes":{"sample":{"value":10}}},
+			{"id":"iterations","attributes":{"sample":{"count":100,"rate":1.5}}},
+			{"id":"http_reqs","attributes":{"sample":{"count":300,"rate":5}}},
+			{"id":"http_req_failed","attributes":{"sample":{"rate":0.1}}}
+		]}`),
+		runnerMetrics(t, `{"data":[
+			{"id":"vus","attributes":{"sample":{"value":5}}},
+			{"id":"iterations","attributes":{"sample":{"count":50,"rate":1}}},
+			{"id":"http_reqs","attributes":{"sample":{"count":100,"rate":2}}},
+			{"id":"http_req_failed","attributes":{"sample":{"rate":0.2}}}
+		]}`),
+	}
+
+	assert.Equal(t, v1alpha1.MetricSnapshot{
+		Time:       metav1.NewTime(now),
+		VUs:        15,
+		Iterations: 150,
+		HTTPReqs:   400,
+		ErrorRate:  "0.1250",
+	}, aggregateMetrics(runners, now))
+
+	// without HTTP requests, there is no error rate
+	assert.Equal(t, v1alpha1.MetricSnapshot{Time: metav1.NewTime(now)}, aggregateMetrics(nil, now))
+}
+
+func Test_appendSnapshot(t *testing.T) {
+	var snapshots []v1alpha1.MetricSnapshot
+	for i := 0; i < maxSnapshots+5; i++ {
+		snapshots = appendSnapshot(snapshots, v1alpha1.MetricSnapshot{VUs: int64(i)})
+	}
+
+	assert.Len(t, snapshots, maxSnapshots)
+	assert.Equal(t, int64(5), snapshots[0].VUs)
+	assert.Equal(t, int64(maxSnapshots+4), snapshots[maxSnapshots-1].VUs)
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// resumeRequest is the body of PATCH /v1/status that starts a paused runner.
+const resumeRequest = `{"data":{"attributes":{"paused":false},"id":"default","type":"status"}}`
+
+// stagedRunners returns how many runners should be started once elapsed time
+// has passed since the start of the test run, together with the time left
+// until the next stage. Without stages, all runners are started right away.
+func stagedRunners(stages []v1alpha1.RunnerStage, parallelism int32, elapsed time.Duration) (int32, time.Duration) {
+	var (
+		count int32
+		end   time.Duration
+	)
+	for _, stage := range stages {
+		if stage.RunnerCount > count {
+			count = stage.RunnerCount
+		}
+		end += stage.Duration.Duration
+		if elapsed < end {
+			if count > parallelism {
+				count = parallelism
+			}
+			return count, end - elapsed
+		}
+	}
+	return parallelism, 0
+}
+
+// runnerIndex returns the index of the runner from the name of its service.
+func runnerIndex(k6 *v1alpha1.K6, serviceName string) (int32, error) {
+	index, err := strconv.Atoi(strings.TrimPrefix(serviceName, k6.Name+"-service-"))
+	return int32(index), err
+}
+
+// StartStagedRunners starts the runners of the current stage that were not
+// started yet. It returns the time left until the next stage.
+func StartStagedRunners(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	condition := meta.FindStatusCondition(k6.Status.Conditions, v1alpha1.TestRunRunning)
+	if condition == nil {
+		return 0, nil
+	}
+
+	count, nextStage := stagedRunners(k6.Spec.RunnerStages(), k6.Spec.Parallelism, r.now().Sub(condition.LastTransitionTime.Time))
+	if count <= k6.Status.StartedRunners {
+		return nextStage, nil
+	}
+
+	log.Info(fmt.Sprintf("Starting runners %d to %d", k6.Status.StartedRunners+1, count))
+
+	opts := runnerListOptions(k6)
+
+	sl := &v1.ServiceList{}
+	if err := listSelected(ctx, r, sl, opts); err != nil {
+		log.Error(err, "Could not list services")
+		return 0, err
+	}
+
+	services := map[int32]*v1.Service{}
+	for i := range sl.Items {
+		if index, err := runnerIndex(k6, sl.Items[i].Name); err == nil {
+			services[index] = &sl.Items[i]
+		}
+	}
+
+	// runners are started in order of their index so that the count in
+	// status is enough to know which of them are running
+	started := k6.Status.StartedRunners
+	for index := started + 1; index <= count; index++ {
+		service, ok := services[index]
+		if !ok {
+			log.Info(fmt.Sprintf("Service of runner %d not found", index))
+			break
+		}
+		if err := resumeRunner(k6, service); err != nil {
+			log.Error(err, fmt.Sprintf("Failed to start runner behind %v", service.Name))
+			break
+		}
+		log.Info(fmt.Sprintf("Started runner behind %v", service.Name))
+		started = index
+	}
+
+	if started > k6.Status.StartedRunners {
+		k6.Status.StartedRunners = started
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return 0, err
+		}
+	}
+
+	return nextStage, nil
+}
+
+// resumeRunner starts the paused runner behind the service.
+func resumeRunner(k6 *v1alpha1.K6, service *v1.Service) error {
+	req, err := http.NewRequest(http.MethodPatch, serviceURL(k6, service)+"/v1/status", bytes.NewBufferString(resumeRequest))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := runnerAPIClient(k6).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_stagedRunners(t *testing.T) {
+	stages := []v1alpha1.RunnerStage{
+		{RunnerCount: 1, Duration: metav1.Duration{Duration: 5 * time.Minute}},
+		{RunnerCount: 3, Duration: metav1.Duration{Duration: 5 * time.Minute}},
+		{RunnerCount: 2, Duration: metav1.Duration{Duration: 5 * time.Minute}},
+	}
+
+	tests := []struct {
+		name      string
+		stages    []v1alpha1.RunnerStage
+		elapsed   time.Duration
+		count     int32
+		nextStage time.Duration
+	}{
+		{"NoStages", nil, 0, 4, 0},
+		{"FirstStage", stages, 0, 1, 5 * time.Minute},
+		{"SecondStage", stages, 7 * time.Minute, 3, 3 * time.Minute},
+		{"LowerCountIgnored", stages, 10 * time.Minute, 3, 5 * time.Minute},
+		{"AfterStages", stages, 15 * time.Minute, 4, 0},
+		{"CappedByParallelism", []v1alpha1.RunnerStage{
+			{RunnerCount: 10, Duration: metav1.Duration{Duration: time.Minute}},
+		}, 0, 4, time.Minute},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			count, nextStage := stagedRunners(test.stages, 4, test.elapsed)
+			assert.Equal(t, test.count, count)
+			assert.Equal(t, test.nextStage, nextStage)
+		})
+	}
+}
+
+func Test_stagedRunnersStartupBatches(t *testing.T) {
+	spec := v1alpha1.K6Spec{
+		Parallelism:      5,
+		StartupBatchSize: 2,
+		StartupDelay:     &metav1.Duration{Duration: 30 * time.Second},
+	}
+
+	// runners are released in batches of 2, every 30s
+	for _, cadence := range []struct {
+		elapsed   time.Duration
+		count     int32
+		nextBatch time.Duration
+	}{
+		{0, 2, 30 * time.Second},
+		{29 * time.Second, 2, time.Second},
+		{30 * time.Second, 4, 30 * time.Second},
+		{time.Minute, 5, 0},
+	} {
+		count, nextBatch := stagedRunners(spec.RunnerStages(), spec.Parallelism, cadence.elapsed)
+		assert.Equal(t, cadence.count, count, cadence.elapsed)
+		assert.Equal(t, cadence.nextBatch, nextBatch, cadence.elapsed)
+	}
+}
@@ -2,29 +2,251 @@ package controllers
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/grafana/k6-operator/api/v1alpha1"
 	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func isServiceReady(log logr.Logger, service *v1.Service) bool {
-	resp, err := http.Get(fmt.Sprintf("http://%v.%v.svc.cluster.local:6565/v1/status", service.ObjectMeta.Name, service.ObjectMeta.Namespace))
+// k6 execution status as reported by the REST API: see lib.ExecutionStatus in k6.
+// Only the value of "paused before run" is of interest for the operator.
+const k6ExecutionStatusPausedBeforeRun = 4
 
+type statusAPIResponse struct {
+	Data struct {
+		Attributes struct {
+			Status int `json:"status"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// runnerAPITimeout limits requests to k6 REST API of runners, so that a
+// wedged runner doesn't block the reconcile loop.
+var runnerAPITimeout = 5 * time.Second
+
+// runnerAPIClientKey tells apart clients for k6 REST API of runners.
+type runnerAPIClientKey struct {
+	insecureSkipVerify bool
+	timeout            time.Duration
+}
+
+var (
+	runnerAPIClientsMu sync.Mutex
+	// runnerAPIClients are built once and reused, so that connections to the
+	// runners are kept alive between requests.
+	runnerAPIClients = map[runnerAPIClientKey]*http.Client{}
+)
+
+// runnerAPIClient returns a client for k6 REST API of runners. Runners with
+// self-signed certificates get a client of their own.
+func runnerAPIClient(k6 *v1alpha1.K6) *http.Client {
+	key := runnerAPIClientKey{insecureSkipVerify: k6.Spec.RestAPIInsecureSkipVerify(), timeout: runnerAPITimeout}
+
+	runnerAPIClientsMu.Lock()
+	defer runnerAPIClientsMu.Unlock()
+
+	if client, ok := runnerAPIClients[key]; ok {
+		return client
+	}
+	client := &http.Client{Timeout: key.timeout}
+	if key.insecureSkipVerify {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		client.Transport = transport
+	}
+	runnerAPIClients[key] = client
+	return client
+}
+
+// isTimeout returns true if the request failed because of a timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// serviceURL returns the address of k6 REST API of the runner behind the service.
+func serviceURL(k6 *v1alpha1.K6, service *v1.Service) string {
+	return k6.Spec.RestAPIURL(fmt.Sprintf("%v.%v.svc.cluster.local", service.ObjectMeta.Name, service.ObjectMeta.Namespace))
+}
+
+// runnerAddress returns the IP of the runner behind the service: ClusterIP
+// of the service or, for headless services, IP of the runner pod.
+func runnerAddress(service *v1.Service, pods []v1.Pod) string {
+	if ip := service.Spec.ClusterIP; len(ip) > 0 && ip != v1.ClusterIPNone {
+		return ip
+	}
+	if len(service.Spec.Selector) == 0 {
+		return ""
+	}
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	for _, pod := range pods {
+		if selector.Matches(labels.Set(pod.Labels)) && len(pod.Status.PodIP) > 0 {
+			return pod.Status.PodIP
+		}
+	}
+	return ""
+}
+
+// runnerEndpoints returns addresses of k6 REST API of the runners, in order
+// of their index.
+func runnerEndpoints(k6 *v1alpha1.K6, services []v1.Service, pods []v1.Pod) []v1alpha1.RunnerEndpoint {
+	var endpoints []v1alpha1.RunnerEndpoint
+	for i := range services {
+		index, err := runnerIndex(k6, services[i].Name)
+		if err != nil {
+			continue
+		}
+		address := runnerAddress(&services[i], pods)
+		if len(address) == 0 {
+			continue
+		}
+		endpoints = append(endpoints, v1alpha1.RunnerEndpoint{
+			Index:    index,
+			Endpoint: k6.Spec.RestAPIURL(address),
+		})
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].Index < endpoints[j].Index })
+	return endpoints
+}
+
+// isServiceReady checks whether the runner behind the service can be started.
+// It also returns the skew between clocks of the runner and the operator,
+// as estimated from the response of the runner.
+func isServiceReady(log logr.Logger, k6 *v1alpha1.K6, service *v1.Service) (ready bool, skew time.Duration) {
+	return isRunnerReady(log, runnerAPIClient(k6), serviceURL(k6, service), service.ObjectMeta.Name, k6.Spec.CheckSetup)
+}
+
+// isRunnerReady checks k6 REST API at the url. A runner that doesn't respond
+// in time is considered not ready yet.
+func isRunnerReady(log logr.Logger, client *http.Client, url, name string, checkSetup bool) (ready bool, skew time.Duration) {
+	sent := time.Now()
+	resp, err := client.Get(url + "/v1/status")
+	if isTimeout(err) {
+		log.V(1).Info(fmt.Sprintf("timed out getting status from %v", name))
+		return false, 0
+	}
+	if err != nil {
+		log.Error(err, fmt.Sprintf("failed to get status from %v", name))
+		return false, 0
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return false, 0
+	}
+
+	skew, _ = clockSkew(resp, sent, time.Now())
+
+	if !checkSetup {
+		return true, skew
+	}
+
+	// The runner is ready to be started only once its init stage is fully
+	// done and it is waiting for the resume from the starter.
+	var status statusAPIResponse
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		log.Error(err, fmt.Sprintf("failed to decode status from %v", name))
+		return false, skew
+	}
+	if status.Data.Attributes.Status < k6ExecutionStatusPausedBeforeRun {
+		log.Info(fmt.Sprintf("%v is still initializing, execution status is %d", name, status.Data.Attributes.Status))
+		return false, skew
+	}
+
+	// Scripts without setup() still get a successful response here, with
+	// empty data, so they are not blocked by this check.
+	setupResp, err := client.Get(url + "/v1/setup")
+	if isTimeout(err) {
+		log.V(1).Info(fmt.Sprintf("timed out getting setup data from %v", name))
+		return false, skew
+	}
 	if err != nil {
-		log.Error(err, fmt.Sprintf("failed to get status from %v", service.ObjectMeta.Name))
-		return false
+		log.Error(err, fmt.Sprintf("failed to get setup data from %v", name))
+		return false, skew
 	}
+	defer setupResp.Body.Close()
 
-	return resp.StatusCode < 400
+	return setupResp.StatusCode < 400, skew
+}
+
+// maxConcurrentRunnerChecks bounds the number of runners checked at once.
+const maxConcurrentRunnerChecks = 16
+
+// runnerCheck is the outcome of isServiceReady for a single runner.
+type runnerCheck struct {
+	ready bool
+	skew  time.Duration
+}
+
+// checkRunners runs the check for all services concurrently, so that a slow
+// runner doesn't delay the rest. Results are in order of the services.
+func checkRunners(services []v1.Service, check func(*v1.Service) (bool, time.Duration)) []runnerCheck {
+	results := make([]runnerCheck, len(services))
+	sem := make(chan struct{}, maxConcurrentRunnerChecks)
+
+	var wg sync.WaitGroup
+	for i := range services {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ready, skew := check(&services[i])
+			results[i] = runnerCheck{ready: ready, skew: skew}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runnerCountMismatchGracePeriod is for the cache to catch up with runners
+// that were just created, before the mismatch is acted on.
+const runnerCountMismatchGracePeriod = 30 * time.Second
+
+// runnerCountMismatch flags that the number of runners differs from parallelism
+// and either waits for it to be resolved or aborts the test run, depending on
+// the policy.
+func runnerCountMismatch(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, found string) (ctrl.Result, error) {
+	reason := fmt.Sprintf("runner count mismatch: %s, expected %d", found, k6.Spec.Parallelism)
+	log.Info(reason)
+
+	if !k6.IsTrue(v1alpha1.RunnerCountMismatch) {
+		k6.UpdateCondition(v1alpha1.RunnerCountMismatch, metav1.ConditionTrue)
+		r.recordEvent(k6, v1.EventTypeWarning, "RunnerCountMismatch", reason)
+	}
+
+	since, _ := k6.LastUpdate(v1alpha1.RunnerCountMismatch)
+	if k6.Spec.RunnerCountPolicy == "Abort" && r.now().Sub(since) >= runnerCountMismatchGracePeriod {
+		log.Error(errors.New(reason), "Aborting the test run")
+
+		k6.Status.Stage = "error"
+		k6.Status.Phase = "Failed"
+		k6.Status.PhaseReason = reason
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	setPhase(ctx, log, k6, r, "Pending", reason)
+	return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 }
 
 // StartJobs in the Ready phase using a curl container
@@ -32,27 +254,34 @@ func StartJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Recon
 	// It may take some time to get Services up, so check in frequently
 	res = ctrl.Result{RequeueAfter: time.Second}
 
-	if len(k6.Status.TestRunID) > 0 {
-		log = log.WithValues("testRunId", k6.Status.TestRunID)
-	}
-
 	log.Info("Waiting for pods to get ready")
 
-	selector := labels.SelectorFromSet(map[string]string{
-		"app":    "k6",
-		"k6_cr":  k6.Name,
-		"runner": "true",
-	})
+	opts := runnerListOptions(k6)
+
+	jl := &batchv1.JobList{}
+	if err = listSelected(ctx, r, jl, opts); err != nil {
+		log.Error(err, "Could not list jobs")
+		return res, nil
+	}
+	if len(jl.Items) != int(k6.Spec.Parallelism) {
+		return runnerCountMismatch(ctx, log, k6, r, fmt.Sprintf("%d runner jobs exist", len(jl.Items)))
+	}
 
-	opts := &client.ListOptions{LabelSelector: selector, Namespace: k6.Namespace}
 	pl := &v1.PodList{}
-	if err = r.List(ctx, pl, opts); err != nil {
+	if err = listSelected(ctx, r, pl, opts); err != nil {
 		log.Error(err, "Could not list pods")
 		return res, nil
 	}
 
-	var count int
+	if res, failed, err := checkRunnerBackoff(ctx, log, k6, r, pl.Items); err != nil || failed {
+		return res, err
+	}
+
+	var count, scheduled int
 	for _, pod := range pl.Items {
+		if len(pod.Spec.NodeName) > 0 {
+			scheduled++
+		}
 		if pod.Status.Phase != "Running" {
 			continue
 		}
@@ -61,29 +290,63 @@ func StartJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Recon
 
 	log.Info(fmt.Sprintf("%d/%d runner pods ready", count, k6.Spec.Parallelism))
 
+	if count > int(k6.Spec.Parallelism) {
+		return runnerCountMismatch(ctx, log, k6, r, fmt.Sprintf("%d runner pods are running", count))
+	}
+
 	if count != int(k6.Spec.Parallelism) {
-		return res, nil
+		if scheduled < int(k6.Spec.Parallelism) {
+			return waitForRunners(ctx, log, k6, r, res, fmt.Sprintf("waiting for cluster capacity (%d/%d runners scheduled)", scheduled, k6.Spec.Parallelism))
+		}
+		return waitForRunners(ctx, log, k6, r, res, fmt.Sprintf("waiting for runners to start (%d/%d running)", count, k6.Spec.Parallelism))
 	}
 
 	var hostnames []string
 	sl := &v1.ServiceList{}
 
-	if err = r.List(ctx, sl, opts); err != nil {
+	if err = listSelected(ctx, r, sl, opts); err != nil {
 		log.Error(err, "Could not list services")
 		return res, nil
 	}
+	if len(sl.Items) != int(k6.Spec.Parallelism) {
+		return runnerCountMismatch(ctx, log, k6, r, fmt.Sprintf("%d runner services exist", len(sl.Items)))
+	}
 
-	for _, service := range sl.Items {
-		hostnames = append(hostnames, service.Spec.ClusterIP)
+	// With stages, only the first wave of runners is started by the starter.
+	startedRunners, _ := stagedRunners(k6.Spec.RunnerStages(), k6.Spec.Parallelism, 0)
 
-		if !isServiceReady(log, &service) {
-			log.Info(fmt.Sprintf("%v service is not ready, aborting", service.ObjectMeta.Name))
-			return res, nil
-		} else {
+	checks := checkRunners(sl.Items, func(service *v1.Service) (bool, time.Duration) {
+		return isServiceReady(log, k6, service)
+	})
+
+	var (
+		maxSkew time.Duration
+		ready   int
+	)
+	for i, service := range sl.Items {
+		if index, err := runnerIndex(k6, service.Name); err == nil && index <= startedRunners {
+			hostnames = append(hostnames, runnerAddress(&service, pl.Items))
+		}
+
+		if absDuration(checks[i].skew) > absDuration(maxSkew) {
+			maxSkew = checks[i].skew
+		}
+		if checks[i].ready {
+			ready++
 			log.Info(fmt.Sprintf("%v service is ready", service.ObjectMeta.Name))
+		} else {
+			log.Info(fmt.Sprintf("%v service is not ready", service.ObjectMeta.Name))
 		}
 	}
 
+	log.Info(fmt.Sprintf("%d/%d runner services ready", ready, len(sl.Items)))
+
+	if ready != len(sl.Items) {
+		return waitForRunners(ctx, log, k6, r, res, fmt.Sprintf("waiting for runners to be ready (%d/%d ready)", ready, len(sl.Items)))
+	}
+
+	reportClockSkew(log, k6, r, maxSkew)
+
 	starter := jobs.NewStarterJob(k6, hostnames)
 
 	if err = ctrl.SetControllerReference(k6, starter, r.Scheme); err != nil {
@@ -94,6 +357,7 @@ func StartJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Recon
 
 	if err = r.Create(ctx, starter); err != nil {
 		log.Error(err, "Failed to launch k6 test starter")
+		r.recordEvent(k6, v1.EventTypeWarning, "JobCreationFailed", fmt.Sprintf("Failed to create starter job: %v", err))
 		return res, nil
 	}
 
@@ -101,11 +365,17 @@ func StartJobs(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Recon
 
 	log.Info("Changing stage of K6 status to started")
 	k6.Status.Stage = "started"
+	k6.Status.StartedRunners = startedRunners
+	k6.Status.RunnerEndpoints = runnerEndpoints(k6, sl.Items, pl.Items)
+	k6.Status.Phase = "Running"
+	k6.Status.PhaseReason = ""
+	k6.UpdateCondition(v1alpha1.RunnerCountMismatch, metav1.ConditionFalse)
 	k6.UpdateCondition(v1alpha1.TestRunRunning, metav1.ConditionTrue)
 
 	if updateHappened, err := r.UpdateStatus(ctx, k6, log); err != nil {
 		return ctrl.Result{}, err
 	} else if updateHappened {
+		annotate(ctx, log, k6, r, fmt.Sprintf("%s/%s: Test started with %d runners", k6.Namespace, k6.Name, k6.Spec.Parallelism))
 		return ctrl.Result{Requeue: true}, nil
 	}
 	return ctrl.Result{}, nil
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-test/deep"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunnerEndpoints(t *testing.T) {
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+
+	const parallelism = 4
+	var (
+		services []v1.Service
+		pods     []v1.Pod
+	)
+	// listed in reverse order, the last one is headless
+	for i := parallelism; i >= 1; i-- {
+		clusterIP := fmt.Sprintf("10.96.0.%d", i)
+		if i == parallelism {
+			clusterIP = v1.ClusterIPNone
+		}
+		services = append(services, v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("test-service-%d", i), Namespace: "test"},
+			Spec: v1.ServiceSpec{
+				ClusterIP: clusterIP,
+				Selector:  map[string]string{"job-name": fmt.Sprintf("test-%d", i)},
+			},
+		})
+		pods = append(pods, v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("test-%d-abcde", i),
+				Namespace: "test",
+				Labels:    map[string]string{"job-name": fmt.Sprintf("test-%d", i)},
+			},
+			Status: v1.PodStatus{PodIP: fmt.Sprintf("10.244.0.%d", i)},
+		})
+	}
+
+	expected := []v1alpha1.RunnerEndpoint{
+		{Index: 1, Endpoint: "http://10.96.0.1:6565"},
+		{Index: 2, Endpoint: "http://10.96.0.2:6565"},
+		{Index: 3, Endpoint: "http://10.96.0.3:6565"},
+		{Index: 4, Endpoint: "http://10.244.0.4:6565"},
+	}
+	if diff := deep.Equal(expected, runnerEndpoints(k6, services, pods)); diff != nil {
+		t.Errorf("runnerEndpoints returned unexpected endpoints, diff: %s", diff)
+	}
+
+	// the pod of the headless service has no IP yet
+	pods[0].Status.PodIP = ""
+	if endpoints := runnerEndpoints(k6, services, pods); len(endpoints) != parallelism-1 {
+		t.Errorf("runnerEndpoints should skip runners without address, got: %v", endpoints)
+	}
+}
+
+func TestIsRunnerReadyTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	defer func(timeout time.Duration) { runnerAPITimeout = timeout }(runnerAPITimeout)
+	runnerAPITimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	ready, _ := isRunnerReady(logr.Discard(), runnerAPIClient(&v1alpha1.K6{}), server.URL, "test-service-1", false)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("isRunnerReady should return within the timeout, took %v", elapsed)
+	}
+	if ready {
+		t.Error("isRunnerReady should consider a runner not responding in time as not ready")
+	}
+}
+
+// serialCheckRunners is how the runners used to be checked, one by one.
+func serialCheckRunners(services []v1.Service, check func(*v1.Service) (bool, time.Duration)) []runnerCheck {
+	results := make([]runnerCheck, len(services))
+	for i := range services {
+		ready, skew := check(&services[i])
+		results[i] = runnerCheck{ready: ready, skew: skew}
+	}
+	return results
+}
+
+func testServices(n int) []v1.Service {
+	services := make([]v1.Service, n)
+	for i := range services {
+		services[i].Name = fmt.Sprintf("test-service-%d", i+1)
+	}
+	return services
+}
+
+func TestCheckRunners(t *testing.T) {
+	services := testServices(50)
+
+	// every third runner is still running its init stage
+	check := func(service *v1.Service) (bool, time.Duration) {
+		var index int
+		fmt.Sscanf(service.Name, "test-service-%d", &index)
+		time.Sleep(time.Duration(index%5) * time.Millisecond)
+		return index%3 != 0, time.Duration(index) * time.Second
+	}
+
+	if diff := deep.Equal(serialCheckRunners(services, check), checkRunners(services, check)); diff != nil {
+		t.Errorf("checkRunners returned results different from serial checks, diff: %s", diff)
+	}
+}
+
+func BenchmarkCheckRunners(b *testing.B) {
+	services := testServices(200)
+	check := func(service *v1.Service) (bool, time.Duration) {
+		time.Sleep(time.Millisecond)
+		return true, 0
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			serialCheckRunners(services, check)
+		}
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			checkRunners(services, check)
+		}
+	})
+}
+
+func TestIsRunnerReadyTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	k6 := &v1alpha1.K6{Spec: v1alpha1.K6Spec{RestAPI: &v1alpha1.RestAPI{Scheme: "https"}}}
+	if ready, _ := isRunnerReady(logr.Discard(), runnerAPIClient(k6), server.URL, "test-service-1", false); ready {
+		t.Error("isRunnerReady should fail to verify a self-signed certificate")
+	}
+
+	k6.Spec.RestAPI.InsecureSkipVerify = true
+	if ready, _ := isRunnerReady(logr.Discard(), runnerAPIClient(k6), server.URL, "test-service-1", false); !ready {
+		t.Error("isRunnerReady should skip verification of the certificate")
+	}
+
+	// clients are built once for each kind
+	if runnerAPIClient(k6) != runnerAPIClient(k6) {
+		t.Error("runnerAPIClient should reuse the client")
+	}
+	if runnerAPIClient(k6) == runnerAPIClient(&v1alpha1.K6{}) {
+		t.Error("runnerAPIClient should not skip verification of the certificate with the same client")
+	}
+}
+
+func TestServiceURL(t *testing.T) {
+	service := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-service-1", Namespace: "test"}}
+
+	k6 := &v1alpha1.K6{}
+	if url := serviceURL(k6, service); url != "http://test-service-1.test.svc.cluster.local:6565" {
+		t.Errorf("serviceURL returned unexpected URL by default: %s", url)
+	}
+
+	k6.Spec.RestAPI = &v1alpha1.RestAPI{Scheme: "https", Port: 8443}
+	if url := serviceURL(k6, service); url != "https://test-service-1.test.svc.cluster.local:8443" {
+		t.Errorf("serviceURL returned unexpected URL with custom REST API: %s", url)
+	}
+}
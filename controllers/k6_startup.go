@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// runnerBackoffGracePeriod is how long a runner container may be in backoff,
+// e.g. while the image is pulled from a flaky registry, before the test run
+// is set to error.
+const runnerBackoffGracePeriod = 2 * time.Minute
+
+// backoffReasons are the waiting reasons of containers that won't start
+// without an outside fix.
+var backoffReasons = map[string]bool{
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+// runnerBackoff returns the description of the first runner container, or
+// init container, that is in backoff, if any.
+func runnerBackoff(pods []v1.Pod) string {
+	sorted := make([]v1.Pod, len(pods))
+	copy(sorted, pods)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	for _, pod := range sorted {
+		var statuses []v1.ContainerStatus
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if waiting := status.State.Waiting; waiting != nil && backoffReasons[waiting.Reason] {
+				reason := fmt.Sprintf("container %s of pod %s is in %s", status.Name, pod.Name, waiting.Reason)
+				if len(waiting.Message) > 0 {
+					reason += ": " + waiting.Message
+				}
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// checkRunnerBackoff flags runner containers in backoff and sets the test run
+// to error once they have been in it for longer than the grace period.
+func checkRunnerBackoff(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, pods []v1.Pod) (res ctrl.Result, failed bool, err error) {
+	reason := runnerBackoff(pods)
+	if len(reason) == 0 {
+		if k6.IsTrue(v1alpha1.RunnerBackoff) {
+			k6.UpdateCondition(v1alpha1.RunnerBackoff, metav1.ConditionFalse)
+			_, err = r.UpdateStatus(ctx, k6, log)
+		}
+		return res, false, err
+	}
+
+	log.Info(reason)
+	if !k6.IsTrue(v1alpha1.RunnerBackoff) {
+		k6.UpdateCondition(v1alpha1.RunnerBackoff, metav1.ConditionTrue)
+		r.recordEvent(k6, v1.EventTypeWarning, "RunnerBackoff", reason)
+		_, err = r.UpdateStatus(ctx, k6, log)
+		return res, false, err
+	}
+
+	if since, _ := k6.LastUpdate(v1alpha1.RunnerBackoff); r.now().Sub(since) < runnerBackoffGracePeriod {
+		return res, false, nil
+	}
+	res, err = failStartup(ctx, log, k6, r, reason)
+	return res, true, err
+}
+
+// waitForRunners keeps the test run pending while the runners are not ready,
+// unless it has exceeded spec.startupTimeout in created stage.
+func waitForRunners(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, res ctrl.Result, reason string) (ctrl.Result, error) {
+	if timeout := k6.Spec.StartupTimeout; timeout != nil && k6.Status.StageTime != nil &&
+		r.now().Sub(k6.Status.StageTime.Time) >= timeout.Duration {
+		return failStartup(ctx, log, k6, r, fmt.Sprintf("runners are not ready within startup timeout of %s: %s", timeout.Duration, reason))
+	}
+
+	setPhase(ctx, log, k6, r, "Pending", reason)
+	return res, nil
+}
+
+// failStartup stops the test run that can't be started, like an expired one,
+// and sets it to error. StartupFailed is set first, so that the following
+// reconciles get back here until the runners have stopped.
+func failStartup(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler, reason string) (ctrl.Result, error) {
+	if !k6.IsTrue(v1alpha1.StartupFailed) {
+		log.Error(errors.New(reason), "Aborting the test run")
+		r.recordEvent(k6, v1.EventTypeWarning, "StartupFailed", reason)
+
+		k6.UpdateCondition(v1alpha1.StartupFailed, metav1.ConditionTrue)
+		k6.Status.Phase = "Failed"
+		k6.Status.PhaseReason = reason
+		if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if requeueAfter, err := tearDown(ctx, log, k6, r); err != nil || requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
+	}
+
+	k6.Status.Stage = "error"
+	_, err := r.UpdateStatus(ctx, k6, log)
+	return ctrl.Result{}, err
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// startingK6 returns a created test run with one runner job and its pod in
+// the given state.
+func startingK6(t *testing.T, k6 *v1alpha1.K6, state v1.ContainerState) (*K6Reconciler, client.Client) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	labels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "test-1", Namespace: "test", Labels: labels}}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1-abcde", Namespace: "test", Labels: labels},
+		Spec:       v1.PodSpec{NodeName: "node"},
+		Status: v1.PodStatus{
+			Phase:             v1.PodPending,
+			ContainerStatuses: []v1.ContainerStatus{{Name: "k6", State: state}},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy(), job, pod).Build()
+	return &K6Reconciler{Client: c, Scheme: scheme}, c
+}
+
+func newStartingK6() *v1alpha1.K6 {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 1},
+		Status:     v1alpha1.K6Status{Stage: "created"},
+	}
+	k6.InitializeConditions()
+	return k6
+}
+
+func Test_StartJobsRunnerBackoff(t *testing.T) {
+	backoff := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{
+		Reason:  "ImagePullBackOff",
+		Message: `Back-off pulling image "grafana/k6:typo"`,
+	}}
+
+	// backoff is flagged first
+	k6 := newStartingK6()
+	r, c := startingK6(t, k6, backoff)
+	_, err := StartJobs(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+	assert.True(t, k6.IsTrue(v1alpha1.RunnerBackoff))
+	assert.Equal(t, v1alpha1.Stage("created"), k6.Status.Stage)
+
+	// and fails the test run after the grace period
+	k6 = newStartingK6()
+	meta.SetStatusCondition(&k6.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.RunnerBackoff,
+		Status:             metav1.ConditionTrue,
+		Reason:             "RunnerBackoffTrue",
+		LastTransitionTime: metav1.NewTime(time.Now().Add(-runnerBackoffGracePeriod)),
+	})
+	r, c = startingK6(t, k6, backoff)
+	_, err = StartJobs(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+	assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage)
+	assert.Equal(t, v1alpha1.Phase("Failed"), k6.Status.Phase)
+	assert.Equal(t, `container k6 of pod test-1-abcde is in ImagePullBackOff: Back-off pulling image "grafana/k6:typo"`, k6.Status.PhaseReason)
+	assert.True(t, k6.IsTrue(v1alpha1.StartupFailed))
+	// the runners are stopped, like on expiry
+	err = c.Get(context.Background(), types.NamespacedName{Name: "test-1", Namespace: "test"}, &batchv1.Job{})
+	assert.True(t, k8sErrors.IsNotFound(err), "expected runner to be deleted, got: %v", err)
+}
+
+func Test_StartJobsStartupTimeout(t *testing.T) {
+	creating := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+
+	tests := []struct {
+		name    string
+		timeout *metav1.Duration
+		stage   v1alpha1.Stage
+	}{
+		{"NoTimeout", nil, "created"},
+		{"WithinTimeout", &metav1.Duration{Duration: time.Hour}, "created"},
+		{"Exceeded", &metav1.Duration{Duration: 5 * time.Minute}, "error"},
+	}
+
+	for _, test := range tests {
+		k6 := newStartingK6()
+		k6.Spec.StartupTimeout = test.timeout
+		k6.Status.StageTime = &metav1.Time{Time: time.Now().Add(-10 * time.Minute)}
+
+		r, c := startingK6(t, k6, creating)
+		_, err := StartJobs(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err, test.name)
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+		assert.Equal(t, test.stage, k6.Status.Stage, test.name)
+	}
+}
+
+func Test_StartJobsStartupTimeoutClock(t *testing.T) {
+	creating := v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+	created := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// the timeout is measured with the clock of the reconciler
+	for _, test := range []struct {
+		elapsed time.Duration
+		stage   v1alpha1.Stage
+	}{{time.Minute, "created"}, {10 * time.Minute, "error"}} {
+		k6 := newStartingK6()
+		k6.Spec.StartupTimeout = &metav1.Duration{Duration: 5 * time.Minute}
+		k6.Status.StageTime = &metav1.Time{Time: created}
+
+		r, c := startingK6(t, k6, creating)
+		r.Clock = clocktesting.NewFakePassiveClock(created.Add(test.elapsed))
+		_, err := StartJobs(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err)
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+		assert.Equal(t, test.stage, k6.Status.Stage, test.elapsed.String())
+	}
+}
+
+func Test_FailedStartupIsResumed(t *testing.T) {
+	// the runners were deleted on an earlier reconcile, before finalization
+	// of the cloud test run was retried
+	k6 := newCloudK6()
+	k6.Status.Stage = "created"
+	k6.Status.Phase = "Failed"
+	k6.Status.PhaseReason = "runners are not ready within startup timeout of 5m0s"
+	k6.UpdateCondition(v1alpha1.StartupFailed, metav1.ConditionTrue)
+	k6.UpdateCondition(v1alpha1.CloudTestRunFinalized, metav1.ConditionTrue)
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme, Log: logr.Discard()}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(k6)})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(k6), k6))
+	assert.Equal(t, v1alpha1.Stage("error"), k6.Status.Stage)
+	assert.Equal(t, "runners are not ready within startup timeout of 5m0s", k6.Status.PhaseReason)
+	assert.False(t, k6.IsTrue(v1alpha1.RunnerCountMismatch))
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// stopRequest is the body of PATCH /v1/status that stops the test on a runner.
+const stopRequest = `{"data":{"attributes":{"stopped":true},"id":"default","type":"status"}}`
+
+var (
+	// gracefulStopTimeout bounds the wait for runners to end the test, after
+	// which they are deleted anyway.
+	gracefulStopTimeout = 30 * time.Second
+
+	// runnerServiceURL is where the runner behind the service is reached.
+	runnerServiceURL = serviceURL
+)
+
+// gracefulStop returns true if the test is to be stopped on the runners
+// before they are deleted. Cloud test runs are stopped gracefully unless the
+// stop signal is kill, so that k6 Cloud receives their last metrics.
+func gracefulStop(k6 *v1alpha1.K6) bool {
+	switch k6.Spec.StopSignal {
+	case "graceful":
+		return true
+	case "kill":
+		return false
+	}
+	return k6.IsTrue(v1alpha1.CloudTestRun)
+}
+
+// stopRunners deletes the runners of the test run. With graceful stop, the
+// test is stopped on each runner over k6 REST API first, so that in-flight
+// iterations and outputs are finished, and runners are deleted once they are
+// done or when the deadline recorded in status has passed. Until then, it
+// returns the time to check again: jobs changing status requeue sooner.
+func stopRunners(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) (time.Duration, error) {
+	opts := runnerListOptions(k6)
+
+	if gracefulStop(k6) {
+		if k6.Status.StopDeadline == nil {
+			sl := &v1.ServiceList{}
+			if err := listSelected(ctx, r, sl, opts); err != nil {
+				log.Error(err, "Could not list services")
+				return 0, err
+			}
+
+			if len(sl.Items) > 0 {
+				// the runners may be gone already: they are deleted regardless
+				if err := stopTests(ctx, k6, sl.Items); err != nil {
+					log.Error(err, "Failed to stop some of the runners")
+				}
+
+				deadline := metav1.NewTime(r.now().Add(gracefulStopTimeout))
+				k6.Status.StopDeadline = &deadline
+				if _, err := r.UpdateStatus(ctx, k6, log); err != nil {
+					return 0, err
+				}
+			}
+		}
+
+		if k6.Status.StopDeadline != nil {
+			active, err := activeRunners(ctx, k6, r)
+			if err != nil {
+				log.Error(err, "Could not list jobs")
+				return 0, err
+			}
+			left := k6.Status.StopDeadline.Sub(r.now())
+			switch {
+			case active == 0:
+				log.Info("All runners have stopped")
+			case left > 0:
+				log.Info(fmt.Sprintf("Waiting up to %s for %d runners to stop", left.Round(time.Second), active))
+				return left, nil
+			default:
+				log.Info(fmt.Sprintf("%d runners have not stopped in %s, deleting them", active, gracefulStopTimeout))
+			}
+		}
+	}
+
+	return 0, deleteRunners(ctx, log, k6, r, opts)
+}
+
+// stopTests stops the test on the runner behind each of the services. They
+// are called concurrently, like in checkRunners, and all of them are called
+// even if some fail: the failures are returned together, in order of the
+// services.
+func stopTests(ctx context.Context, k6 *v1alpha1.K6, services []v1.Service) error {
+	client := runnerAPIClient(k6)
+	results := make([]error, len(services))
+	sem := make(chan struct{}, maxConcurrentRunnerChecks)
+
+	var wg sync.WaitGroup
+	for i := range services {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := stopRunner(ctx, client, runnerServiceURL(k6, &services[i])); err != nil {
+				results[i] = fmt.Errorf("runner behind %s: %w", services[i].Name, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// stopRunner stops the test on the runner with k6 REST API at the URL.
+func stopRunner(ctx context.Context, client *http.Client, url string) error {
+	// an unresponsive runner must not hold up stopping the others
+	ctx, cancel := context.WithTimeout(ctx, runnerAPITimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url+"/v1/status", bytes.NewBufferString(stopRequest))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// activeRunners returns the number of runner jobs that are not finished yet.
+func activeRunners(ctx context.Context, k6 *v1alpha1.K6, r *K6Reconciler) (int, error) {
+	jl := &batchv1.JobList{}
+	if err := listSelected(ctx, r, jl, runnerListOptions(k6)); err != nil {
+		return 0, err
+	}
+
+	var active int
+	for i := range jl.Items {
+		job := &jl.Items[i]
+		if !isJobConditionTrue(job, batchv1.JobComplete) && !isJobConditionTrue(job, batchv1.JobFailed) &&
+			job.Status.Succeeded == 0 && (job.Status.Active > 0 || job.Status.Failed == 0) {
+			active++
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// recordingClient records the order of stop requests and deletions.
+type recordingClient struct {
+	client.Client
+	sync.Mutex
+	calls []string
+}
+
+func (c *recordingClient) record(call string) {
+	c.Lock()
+	defer c.Unlock()
+	c.calls = append(c.calls, call)
+}
+
+// sortedCalls returns the calls with the stop requests, which are concurrent,
+// in order of the runners.
+func (c *recordingClient) sortedCalls() []string {
+	c.Lock()
+	defer c.Unlock()
+	calls := append([]string{}, c.calls...)
+	stops := 0
+	for stops < len(calls) && strings.HasPrefix(calls[stops], "stop ") {
+		stops++
+	}
+	sort.Strings(calls[:stops])
+	return calls
+}
+
+func (c *recordingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.record("delete " + obj.GetName())
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func Test_StopRunners(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(url func(*v1alpha1.K6, *v1.Service) string) { runnerServiceURL = url }(runnerServiceURL)
+
+	runnerLabels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+	newObjects := func(k6 *v1alpha1.K6) []client.Object {
+		objects := []client.Object{k6.DeepCopy()}
+		for _, index := range []string{"1", "2"} {
+			objects = append(objects,
+				&batchv1.Job{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-" + index, Namespace: "test", Labels: runnerLabels},
+					Status:     batchv1.JobStatus{Active: 1},
+				},
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-service-" + index, Namespace: "test", Labels: runnerLabels},
+				})
+		}
+		return objects
+	}
+	deletions := []string{"delete test-1", "delete test-2", "delete test-service-1", "delete test-service-2"}
+
+	tests := []struct {
+		name       string
+		stopSignal string
+		// stops is whether the runners end the test when asked to
+		stops bool
+		calls []string
+	}{
+		{"Graceful", "graceful", true, append([]string{"stop test-service-1", "stop test-service-2"}, deletions...)},
+		{"Kill", "", true, deletions},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{Parallelism: 2, StopSignal: test.stopSignal},
+		}
+		c := &recordingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(newObjects(k6)...).Build()}
+		r := &K6Reconciler{Client: c}
+
+		server := newStoppingServer(t, c, true)
+		runnerServiceURL = func(k6 *v1alpha1.K6, service *v1.Service) string {
+			return server.URL + "/" + service.Name
+		}
+
+		stopping, err := stopRunners(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err, test.name)
+		assert.Zero(t, stopping, test.name)
+		assert.Equal(t, test.calls, c.sortedCalls(), test.name)
+		server.Close()
+	}
+
+	// runners that don't stop are waited for without blocking, until the deadline
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2, StopSignal: "graceful"},
+	}
+	c := &recordingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(newObjects(k6)...).Build()}
+	// times are kept with a precision of a second in the resource
+	clock := clocktesting.NewFakePassiveClock(time.Now().Truncate(time.Second))
+	r := &K6Reconciler{Client: c, Clock: clock}
+	server := newStoppingServer(t, c, false)
+	defer server.Close()
+	runnerServiceURL = func(k6 *v1alpha1.K6, service *v1.Service) string {
+		return server.URL + "/" + service.Name
+	}
+
+	stopping, err := stopRunners(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, gracefulStopTimeout, stopping)
+	assert.Equal(t, []string{"stop test-service-1", "stop test-service-2"}, c.sortedCalls())
+	if assert.NotNil(t, k6.Status.StopDeadline) {
+		assert.Equal(t, clock.Now().Add(gracefulStopTimeout), k6.Status.StopDeadline.Time)
+	}
+
+	clock.SetTime(clock.Now().Add(gracefulStopTimeout / 2))
+	stopping, err = stopRunners(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, gracefulStopTimeout/2, stopping)
+	// the runners are asked to stop only once
+	assert.Len(t, c.calls, 2)
+
+	clock.SetTime(clock.Now().Add(gracefulStopTimeout))
+	stopping, err = stopRunners(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Zero(t, stopping)
+	assert.Equal(t, append([]string{"stop test-service-1", "stop test-service-2"}, deletions...), c.sortedCalls())
+}
+
+// newStoppingServer serves k6 REST API of runners test-service-<index>,
+// recording stop requests. With stops, the job of the runner completes.
+func newStoppingServer(t *testing.T, c *recordingClient, stops bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, http.MethodPatch, req.Method)
+		assert.JSONEq(t, stopRequest, string(body))
+
+		// the path is /<service>/v1/status
+		service := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/v1/status")
+		c.record("stop " + service)
+		if !stops {
+			return
+		}
+
+		job := &batchv1.Job{}
+		name := "test-" + strings.TrimPrefix(service, "test-service-")
+		assert.NoError(t, c.Get(context.Background(), client.ObjectKey{Name: name, Namespace: "test"}, job))
+		job.Status = batchv1.JobStatus{Succeeded: 1}
+		assert.NoError(t, c.Status().Update(context.Background(), job))
+	}))
+}
+
+func Test_gracefulStop(t *testing.T) {
+	tests := []struct {
+		name       string
+		stopSignal string
+		cloud      bool
+		graceful   bool
+	}{
+		{"Default", "", false, false},
+		{"DefaultCloud", "", true, true},
+		{"Graceful", "graceful", false, true},
+		{"KillCloud", "kill", true, false},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{Spec: v1alpha1.K6Spec{StopSignal: test.stopSignal}}
+		k6.InitializeConditions()
+		if test.cloud {
+			k6.UpdateCondition(v1alpha1.CloudTestRun, metav1.ConditionTrue)
+		}
+		assert.Equal(t, test.graceful, gracefulStop(k6), test.name)
+	}
+}
+
+func Test_stopTests(t *testing.T) {
+	defer func(timeout time.Duration, url func(*v1alpha1.K6, *v1.Service) string) {
+		runnerAPITimeout, runnerServiceURL = timeout, url
+	}(runnerAPITimeout, runnerServiceURL)
+	runnerAPITimeout = 100 * time.Millisecond
+
+	var (
+		mu      sync.Mutex
+		stopped []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, http.MethodPatch, req.Method)
+		assert.JSONEq(t, stopRequest, string(body))
+
+		service := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/v1/status")
+		mu.Lock()
+		stopped = append(stopped, service)
+		mu.Unlock()
+
+		switch service {
+		case "test-service-2":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "test-service-3", "test-service-4":
+			// hangs past the timeout
+			time.Sleep(300 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+	runnerServiceURL = func(k6 *v1alpha1.K6, service *v1.Service) string {
+		return server.URL + "/" + service.Name
+	}
+
+	var services []v1.Service
+	for _, name := range []string{"test-service-1", "test-service-2", "test-service-3", "test-service-4"} {
+		services = append(services, v1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+
+	// every runner is called, whatever the others respond, and a hanging
+	// one doesn't delay the rest
+	start := time.Now()
+	err := stopTests(context.Background(), &v1alpha1.K6{}, services)
+	assert.Less(t, time.Since(start), 2*runnerAPITimeout)
+	assert.ElementsMatch(t, []string{"test-service-1", "test-service-2", "test-service-3", "test-service-4"}, stopped)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "runner behind test-service-2: unexpected status code 500")
+		assert.Contains(t, err.Error(), "runner behind test-service-3:")
+		assert.Contains(t, err.Error(), "runner behind test-service-4:")
+		assert.NotContains(t, err.Error(), "test-service-1")
+	}
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"go.k6.io/k6/errext/exitcodes"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CollectThresholds records in status whether k6 thresholds have passed,
+// together with a summary of the test run. Thresholds are read from
+// /v1/metrics of the runners which are still reachable; for the rest, the
+// exit code of the runner tells if any of its thresholds has failed.
+func CollectThresholds(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6, r *K6Reconciler) error {
+	sl := &v1.ServiceList{}
+	if err := listSelected(ctx, r, sl, runnerListOptions(k6)); err != nil {
+		log.Error(err, "Could not list services")
+		return err
+	}
+
+	var runners []*metricsAPIResponse
+	for i := range sl.Items {
+		metrics, err := getRunnerMetrics(k6, &sl.Items[i])
+		if err != nil {
+			// most runners exit as soon as the test ends
+			log.Info(fmt.Sprintf("Skipping thresholds of runner behind %v: %v", sl.Items[i].Name, err))
+			continue
+		}
+		runners = append(runners, metrics)
+	}
+
+	summary := summarizeThresholds(runners, k6.Status.RunnerResults)
+	summary.Runners = k6.Spec.Parallelism
+	summarizeRequests(&summary, runners, k6.Status.Snapshots, r.now())
+
+	passed := summary.FailedThresholds == 0
+	for _, result := range k6.Status.RunnerResults {
+		if result.ExitCode == int32(exitcodes.ThresholdsHaveFailed) {
+			passed = false
+		}
+	}
+
+	k6.Status.ThresholdsPassed = &passed
+	k6.Status.Summary = &summary
+	k6.UpdateCondition(v1alpha1.ThresholdsCollected, metav1.ConditionTrue)
+
+	if !passed {
+		log.Info(fmt.Sprintf("Thresholds have failed: %v", summary.FailedMetrics))
+	}
+	return nil
+}
+
+// summarizeThresholds counts metrics with thresholds across the runners: a
+// metric has failed thresholds if they have failed on any of the runners.
+func summarizeThresholds(runners []*metricsAPIResponse, results []v1alpha1.RunnerResult) v1alpha1.TestRunSummary {
+	var summary v1alpha1.TestRunSummary
+	for _, result := range results {
+		if result.Result == "Failed" {
+			summary.FailedRunners++
+		}
+	}
+
+	tainted := map[string]bool{}
+	for _, metrics := range runners {
+		for _, metric := range metrics.Data {
+			if metric.Attributes.Tainted == nil {
+				continue
+			}
+			tainted[metric.ID] = tainted[metric.ID] || *metric.Attributes.Tainted
+		}
+	}
+
+	for id, failed := range tainted {
+		summary.Thresholds++
+		if failed {
+			summary.FailedThresholds++
+			summary.FailedMetrics = append(summary.FailedMetrics, id)
+		}
+	}
+	sort.Strings(summary.FailedMetrics)
+	return summary
+}
+
+// summarizeRequests adds the totals of HTTP requests of the runners to the
+// summary. If none of the runners could be reached, the last snapshot, if
+// any, is the closest to the totals there is; the percentiles are not known
+// then.
+func summarizeRequests(summary *v1alpha1.TestRunSummary, runners []*metricsAPIResponse, snapshots []v1alpha1.MetricSnapshot, now time.Time) {
+	if len(runners) == 0 {
+		if n := len(snapshots); n > 0 {
+			summary.HTTPReqs = snapshots[n-1].HTTPReqs
+			summary.ErrorRate = snapshots[n-1].ErrorRate
+		}
+		return
+	}
+
+	totals := aggregateMetrics(runners, now)
+	summary.HTTPReqs = totals.HTTPReqs
+	summary.ErrorRate = totals.ErrorRate
+
+	var p95 float64
+	found := false
+	for _, metrics := range runners {
+		for _, metric := range metrics.Data {
+			if value, ok := metric.Attributes.Sample["p(95)"]; ok && metric.ID == "http_req_duration" {
+				p95 = math.Max(p95, value)
+				found = true
+			}
+		}
+	}
+	if found {
+		summary.HTTPReqDurationP95 = fmt.Sprintf("%.1f", p95)
+	}
+}
+
+// thresholdsFailure explains which thresholds have failed, if it's known.
+func thresholdsFailure(summary *v1alpha1.TestRunSummary) string {
+	if summary == nil || len(summary.FailedMetrics) == 0 {
+		return "thresholds have failed on runners"
+	}
+	return fmt.Sprintf("thresholds have failed for %s", strings.Join(summary.FailedMetrics, ", "))
+}
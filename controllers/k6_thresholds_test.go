@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	passingThresholds = `{"data":[
+		{"id":"http_req_duration","attributes":{"sample":{"p(95)":120},"tainted":false}},
+		{"id":"http_reqs","attributes":{"sample":{"count":1000,"rate":10},"tainted":null}},
+		{"id":"http_req_failed","attributes":{"sample":{"rate":0.01},"tainted":null}},
+		{"id":"checks","attributes":{"sample":{"rate":1},"tainted":false}},
+		{"id":"vus","attributes":{"sample":{"value":10},"tainted":null}}
+	]}`
+	failingThresholds = `{"data":[
+		{"id":"http_req_duration","attributes":{"sample":{"p(95)":900},"tainted":true}},
+		{"id":"http_reqs","attributes":{"sample":{"count":1000,"rate":10},"tainted":null}},
+		{"id":"http_req_failed","attributes":{"sample":{"rate":0.03},"tainted":null}},
+		{"id":"checks","attributes":{"sample":{"rate":1},"tainted":false}},
+		{"id":"vus","attributes":{"sample":{"value":10},"tainted":null}}
+	]}`
+)
+
+func Test_CollectThresholds(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(url func(*v1alpha1.K6, *v1.Service) string) {
+		runnerServiceURL = url
+	}(runnerServiceURL)
+
+	runnerLabels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"}
+
+	tests := []struct {
+		name      string
+		runners   map[string]string
+		results   []v1alpha1.RunnerResult
+		snapshots []v1alpha1.MetricSnapshot
+		passed    bool
+		summary   v1alpha1.TestRunSummary
+	}{
+		{
+			name:    "Passing",
+			runners: map[string]string{"test-service-1": passingThresholds, "test-service-2": passingThresholds},
+			passed:  true,
+			summary: v1alpha1.TestRunSummary{Runners: 2, Thresholds: 2, HTTPReqs: 2000, ErrorRate: "0.0100", HTTPReqDurationP95: "120.0"},
+		},
+		{
+			name:    "FailingOnOneRunner",
+			runners: map[string]string{"test-service-1": passingThresholds, "test-service-2": failingThresholds},
+			passed:  false,
+			summary: v1alpha1.TestRunSummary{Runners: 2, Thresholds: 2, FailedThresholds: 1, FailedMetrics: []string{"http_req_duration"},
+				HTTPReqs: 2000, ErrorRate: "0.0200", HTTPReqDurationP95: "900.0"},
+		},
+		{
+			// the runners have exited already: only exit codes are known
+			name:    "ExitCode",
+			runners: map[string]string{},
+			results: []v1alpha1.RunnerResult{
+				{Index: 1, Result: "Completed"},
+				{Index: 2, Result: "Failed", ExitCode: 99, Reason: "Error"},
+			},
+			passed:  false,
+			summary: v1alpha1.TestRunSummary{Runners: 2, FailedRunners: 1},
+		},
+		{
+			// requests are known from the last snapshot only
+			name:    "Snapshot",
+			runners: map[string]string{},
+			snapshots: []v1alpha1.MetricSnapshot{
+				{HTTPReqs: 100, ErrorRate: "0.0500"},
+				{HTTPReqs: 300, ErrorRate: "0.0200"},
+			},
+			passed:  true,
+			summary: v1alpha1.TestRunSummary{Runners: 2, HTTPReqs: 300, ErrorRate: "0.0200"},
+		},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// the path is /<service>/v1/metrics
+			service := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/v1/metrics")
+			payload, ok := test.runners[service]
+			if !ok {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(payload))
+		}))
+		runnerServiceURL = func(k6 *v1alpha1.K6, service *v1.Service) string {
+			return server.URL + "/" + service.Name
+		}
+
+		var objects []client.Object
+		for _, name := range []string{"test-service-1", "test-service-2"} {
+			objects = append(objects, &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: runnerLabels},
+			})
+		}
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+			Spec:       v1alpha1.K6Spec{Parallelism: 2},
+			Status:     v1alpha1.K6Status{RunnerResults: test.results, Snapshots: test.snapshots},
+		}
+		r := &K6Reconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()}
+
+		assert.NoError(t, CollectThresholds(context.Background(), logr.Discard(), k6, r), test.name)
+		if assert.NotNil(t, k6.Status.ThresholdsPassed, test.name) {
+			assert.Equal(t, test.passed, *k6.Status.ThresholdsPassed, test.name)
+		}
+		assert.Equal(t, &test.summary, k6.Status.Summary, test.name)
+		assert.True(t, k6.IsTrue(v1alpha1.ThresholdsCollected), test.name)
+		server.Close()
+	}
+}
+
+func Test_thresholdsFailure(t *testing.T) {
+	assert.Equal(t, "thresholds have failed for checks, http_req_duration",
+		thresholdsFailure(&v1alpha1.TestRunSummary{FailedMetrics: []string{"checks", "http_req_duration"}}))
+	assert.Equal(t, "thresholds have failed on runners", thresholdsFailure(&v1alpha1.TestRunSummary{}))
+}
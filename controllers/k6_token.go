@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/cloud"
+	"go.k6.io/k6/cloudapi"
+)
+
+// TokenFile is k6 Cloud token read from a file, e.g. a projected volume
+// mounted in the operator pod. The file is read again once refresh has
+// passed, so that a rotated token is picked up without a restart.
+type TokenFile struct {
+	path    string
+	refresh time.Duration
+
+	// onRotate is called with the previous token once the file has a new one.
+	onRotate func(previous string)
+
+	mu     sync.Mutex
+	token  string
+	readAt time.Time
+}
+
+// NewTokenFile returns the token of the file at path; with zero refresh,
+// the file is read every time.
+func NewTokenFile(path string, refresh time.Duration) *TokenFile {
+	return &TokenFile{path: path, refresh: refresh}
+}
+
+// Token returns the token from the file, reading it again if it's older
+// than refresh. An empty token means the file is not there yet.
+func (f *TokenFile) Token() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.token) > 0 && time.Since(f.readAt) < f.refresh {
+		return f.token, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// the volume may not be projected yet
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read k6 Cloud token from %s: %w", f.path, err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if previous := f.token; len(previous) > 0 && token != previous && f.onRotate != nil {
+		f.onRotate(previous)
+	}
+	f.token = token
+	f.readAt = time.Now()
+	return f.token, nil
+}
+
+// cloudClient returns the k6 Cloud client of the test run, i.e. of the token
+// and host it was created with, so that test runs with tokens of different
+// teams don't share a client.
+func (r *K6Reconciler) cloudClient(ctx context.Context, log logr.Logger, k6 *v1alpha1.K6) (*cloudapi.Client, error) {
+	token, ready, err := cloudToken(ctx, log, k6, r)
+	if err != nil {
+		return nil, err
+	}
+	if !ready {
+		return nil, errors.New("k6 Cloud token is not available")
+	}
+
+	// an empty host is the default of k6 Cloud
+	host, err := getEnvVar(ctx, r, k6.Namespace, &k6.Spec.Runner, "K6_CLOUD_HOST")
+	if err != nil {
+		return nil, err
+	}
+	return r.cloudClients.Get(token, host), nil
+}
+
+// checkCloudAuth drops the client if k6 Cloud has rejected its token, so that
+// the next request is made with a client of the token loaded anew.
+func (r *K6Reconciler) checkCloudAuth(client *cloudapi.Client, err error) {
+	if cloud.IsUnauthenticated(err) {
+		r.cloudClients.Forget(client)
+	}
+}
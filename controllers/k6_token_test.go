@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_TokenFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	f := NewTokenFile(path, time.Hour)
+	var rotated []string
+	f.onRotate = func(previous string) { rotated = append(rotated, previous) }
+
+	// the volume is not projected yet
+	token, err := f.Token()
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+
+	assert.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+	token, err = f.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	// the rotated token is picked up only once refresh has passed
+	assert.NoError(t, os.WriteFile(path, []byte("second\n"), 0600))
+	token, err = f.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "first", token)
+
+	f.readAt = f.readAt.Add(-time.Hour)
+	token, err = f.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, "second", token)
+
+	// re-reading the same token is not a rotation
+	f.readAt = f.readAt.Add(-time.Hour)
+	_, err = f.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first"}, rotated)
+}
+
+func Test_cloudTokenFromFile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	path := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(path, []byte("first"), 0600))
+
+	// the secret is not used when the token is read from file
+	r := &K6Reconciler{
+		Client:         fake.NewClientBuilder().WithScheme(scheme).Build(),
+		CloudTokenFile: NewTokenFile(path, 0),
+	}
+	k6 := &v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"}}
+
+	token, ready, err := cloudToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, ready)
+	assert.Equal(t, "first", token)
+
+	// rotation between creation of the cloud test run and of the runners
+	assert.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	token, ready, err = cloudToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.True(t, ready)
+	assert.Equal(t, "second", token)
+
+	// an emptied file is waited out
+	assert.NoError(t, os.WriteFile(path, nil, 0600))
+	_, ready, err = cloudToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.False(t, ready)
+}
+
+func Test_CloudClientPerToken(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	rejected := map[string]bool{}
+	finalized := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rejected[req.Header.Get("Authorization")] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		finalized[req.URL.Path] = req.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	newK6 := func(name, testRunID, token string) *v1alpha1.K6 {
+		k6 := newCloudK6()
+		k6.Name = name
+		k6.Status.TestRunID = testRunID
+		k6.Spec.Runner.Env = []corev1.EnvVar{
+			{Name: "K6_CLOUD_TOKEN", Value: token},
+			{Name: "K6_CLOUD_HOST", Value: server.URL},
+		}
+		return k6
+	}
+	teamA, teamB := newK6("team-a", "1", "token-a"), newK6("team-b", "2", "token-b")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(teamA.DeepCopy(), teamB.DeepCopy()).Build()
+	r := &K6Reconciler{Client: c, Scheme: scheme}
+
+	// each test run has a client of its own token
+	clientA, err := r.cloudClient(context.Background(), logr.Discard(), teamA)
+	assert.NoError(t, err)
+	clientB, err := r.cloudClient(context.Background(), logr.Discard(), teamB)
+	assert.NoError(t, err)
+	assert.NotSame(t, clientA, clientB)
+
+	same, err := r.cloudClient(context.Background(), logr.Discard(), teamA)
+	assert.NoError(t, err)
+	assert.Same(t, clientA, same)
+
+	for _, k6 := range []*v1alpha1.K6{teamA, teamB} {
+		retryAfter, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), k6, r)
+		assert.NoError(t, err)
+		assert.Zero(t, retryAfter)
+	}
+	assert.Equal(t, map[string]string{"/v1/tests/1": "Token token-a", "/v1/tests/2": "Token token-b"}, finalized)
+
+	// the client of a rejected token is rebuilt
+	rejected["Token token-a"] = true
+	teamA = newK6("team-a", "1", "token-a")
+	retryAfter, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), teamA, r)
+	trackedStages.forget(client.ObjectKeyFromObject(teamA))
+	assert.NoError(t, err)
+	assert.Equal(t, cloudFinalizeBackoff, retryAfter)
+
+	rebuilt, err := r.cloudClient(context.Background(), logr.Discard(), teamA)
+	assert.NoError(t, err)
+	assert.NotSame(t, clientA, rebuilt)
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/cron"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// k6ScheduleLabelName is set on K6 resources created on schedule.
+	k6ScheduleLabelName = "k6_schedule"
+	// scheduledAtAnnotation is the time the K6 resource was scheduled at.
+	scheduledAtAnnotation = "k6.io/scheduled-at"
+
+	defaultSuccessfulRunsHistoryLimit = 3
+	defaultFailedRunsHistoryLimit     = 1
+)
+
+// K6ScheduleReconciler creates K6 resources on the schedule of a K6Schedule.
+type K6ScheduleReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// Clock is the real clock unless it's replaced in tests.
+	Clock clock.PassiveClock
+}
+
+// +kubebuilder:rbac:groups=k6.io,resources=k6schedules,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=k6.io,resources=k6schedules/status;k6schedules/finalizers,verbs=get;update;patch
+
+// Reconcile creates the K6 resource due on schedule, if any, and prunes the
+// history of finished ones. It requeues itself for the next scheduled time.
+func (r *K6ScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.Namespace, "name", req.Name)
+
+	k6Schedule := &v1alpha1.K6Schedule{}
+	if err := r.Get(ctx, req.NamespacedName, k6Schedule); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			// K6 resources are deleted with their owner
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Could not fetch request")
+		return ctrl.Result{}, err
+	}
+
+	schedule, err := cron.Parse(k6Schedule.Spec.Schedule)
+	if err != nil {
+		// nothing to retry until the spec is fixed
+		log.Error(err, "Invalid schedule")
+		r.event(k6Schedule, v1.EventTypeWarning, "InvalidSchedule", err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	k6List := &v1alpha1.K6List{}
+	if err := r.List(ctx, k6List, &client.ListOptions{
+		Namespace:     k6Schedule.Namespace,
+		LabelSelector: labels.SelectorFromSet(map[string]string{k6ScheduleLabelName: k6Schedule.Name}),
+	}); err != nil {
+		log.Error(err, "Could not list K6 resources")
+		return ctrl.Result{}, err
+	}
+
+	var active, succeeded, failed []*v1alpha1.K6
+	for i := range k6List.Items {
+		k6 := &k6List.Items[i]
+		if !metav1.IsControlledBy(k6, k6Schedule) {
+			continue
+		}
+		switch k6.Status.Stage {
+		case "finished":
+			succeeded = append(succeeded, k6)
+		case "error":
+			failed = append(failed, k6)
+		default:
+			active = append(active, k6)
+		}
+	}
+
+	r.pruneHistory(ctx, log, succeeded, k6Schedule.Spec.SuccessfulRunsHistoryLimit, defaultSuccessfulRunsHistoryLimit)
+	r.pruneHistory(ctx, log, failed, k6Schedule.Spec.FailedRunsHistoryLimit, defaultFailedRunsHistoryLimit)
+
+	now := r.now()
+	scheduledTime, next := dueTime(schedule, k6Schedule, now)
+	res := ctrl.Result{}
+	if !next.IsZero() {
+		res.RequeueAfter = next.Sub(now)
+	}
+
+	if k6Schedule.Spec.Suspend || scheduledTime.IsZero() {
+		return res, r.updateStatus(ctx, k6Schedule, active, k6Schedule.Status.LastScheduleTime)
+	}
+
+	if len(active) > 0 {
+		switch k6Schedule.Spec.ConcurrencyPolicy {
+		case v1alpha1.ForbidConcurrent:
+			log.Info(fmt.Sprintf("Skipping the test run scheduled at %s: %d test runs are still active", scheduledTime, len(active)))
+			r.event(k6Schedule, v1.EventTypeNormal, "Skipped", "the previous test run is still active")
+			// the run is skipped, not postponed
+			return res, r.updateStatus(ctx, k6Schedule, active, &metav1.Time{Time: scheduledTime})
+
+		case v1alpha1.ReplaceConcurrent:
+			for _, k6 := range active {
+				if err := r.Delete(ctx, k6, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sErrors.IsNotFound(err) {
+					log.Error(err, fmt.Sprintf("Could not delete K6 %s", k6.Name))
+					return ctrl.Result{}, err
+				}
+				r.event(k6Schedule, v1.EventTypeNormal, "Replaced", fmt.Sprintf("deleted active test run %s", k6.Name))
+			}
+			active = nil
+		}
+	}
+
+	k6, err := r.newK6(k6Schedule, scheduledTime)
+	if err != nil {
+		log.Error(err, "Could not build K6 resource")
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, k6); err != nil && !k8sErrors.IsAlreadyExists(err) {
+		log.Error(err, fmt.Sprintf("Could not create K6 %s", k6.Name))
+		r.event(k6Schedule, v1.EventTypeWarning, "CreationFailed", err.Error())
+		return ctrl.Result{}, err
+	}
+	log.Info(fmt.Sprintf("Created K6 %s scheduled at %s", k6.Name, scheduledTime))
+	r.event(k6Schedule, v1.EventTypeNormal, "Created", fmt.Sprintf("created test run %s", k6.Name))
+
+	return res, r.updateStatus(ctx, k6Schedule, append(active, k6), &metav1.Time{Time: scheduledTime})
+}
+
+// dueTime returns the latest scheduled time that has passed since the last
+// one, or the zero time if none has, together with the next scheduled time.
+// When several runs were missed, e.g. while the operator was down, only the
+// latest one is due.
+func dueTime(schedule *cron.Schedule, k6Schedule *v1alpha1.K6Schedule, now time.Time) (due, next time.Time) {
+	since := k6Schedule.CreationTimestamp.Time
+	if last := k6Schedule.Status.LastScheduleTime; last != nil {
+		since = last.Time
+	}
+	if since.IsZero() {
+		since = now
+	}
+
+	for t := schedule.Next(since.UTC()); !t.IsZero(); t = schedule.Next(t) {
+		if t.After(now) {
+			return due, t
+		}
+		due = t
+	}
+	return due, time.Time{}
+}
+
+// pruneHistory deletes the oldest of the finished K6 resources above the limit.
+func (r *K6ScheduleReconciler) pruneHistory(ctx context.Context, log logr.Logger, finished []*v1alpha1.K6, limit *int32, defaultLimit int) {
+	keep := defaultLimit
+	if limit != nil {
+		keep = int(*limit)
+	}
+	if len(finished) <= keep {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreationTimestamp.Before(&finished[j].CreationTimestamp)
+	})
+	for _, k6 := range finished[:len(finished)-keep] {
+		if err := r.Delete(ctx, k6, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8sErrors.IsNotFound(err) {
+			// it's retried on the next reconcile
+			log.Error(err, fmt.Sprintf("Could not delete K6 %s", k6.Name))
+			continue
+		}
+		log.Info(fmt.Sprintf("Deleted K6 %s from history", k6.Name))
+	}
+}
+
+// newK6 builds the K6 resource of the given scheduled time from the template.
+// Its name is derived from the time so that it's created only once.
+func (r *K6ScheduleReconciler) newK6(k6Schedule *v1alpha1.K6Schedule, scheduledTime time.Time) (*v1alpha1.K6, error) {
+	template := k6Schedule.Spec.Template.DeepCopy()
+
+	k6Labels := map[string]string{}
+	for k, v := range template.Metadata.Labels {
+		k6Labels[k] = v
+	}
+	k6Labels[k6ScheduleLabelName] = k6Schedule.Name
+
+	annotations := map[string]string{}
+	for k, v := range template.Metadata.Annotations {
+		annotations[k] = v
+	}
+	annotations[scheduledAtAnnotation] = scheduledTime.UTC().Format(time.RFC3339)
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%d", k6Schedule.Name, scheduledTime.Unix()/60),
+			Namespace:   k6Schedule.Namespace,
+			Labels:      k6Labels,
+			Annotations: annotations,
+		},
+		Spec: template.Spec,
+	}
+	if err := controllerutil.SetControllerReference(k6Schedule, k6, r.Scheme); err != nil {
+		return nil, err
+	}
+	return k6, nil
+}
+
+// updateStatus records the active K6 resources and the last schedule time
+// in status, if they have changed.
+func (r *K6ScheduleReconciler) updateStatus(ctx context.Context, k6Schedule *v1alpha1.K6Schedule, active []*v1alpha1.K6, lastScheduleTime *metav1.Time) error {
+	var names []string
+	for _, k6 := range active {
+		names = append(names, k6.Name)
+	}
+	sort.Strings(names)
+
+	status := v1alpha1.K6ScheduleStatus{LastScheduleTime: lastScheduleTime, Active: names}
+	if equality.Semantic.DeepEqual(k6Schedule.Status, status) {
+		return nil
+	}
+	k6Schedule.Status = status
+	return r.Status().Update(ctx, k6Schedule)
+}
+
+func (r *K6ScheduleReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+func (r *K6ScheduleReconciler) event(k6Schedule *v1alpha1.K6Schedule, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(k6Schedule, eventType, reason, message)
+	}
+}
+
+// SetupWithManager sets up a managed controller for the K6Schedule CRD
+// which is also reconciled when the K6 resources it created change.
+func (r *K6ScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.K6Schedule{}).
+		Owns(&v1alpha1.K6{}).
+		Complete(r)
+}
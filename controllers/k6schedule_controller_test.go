@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var scheduleCreated = time.Date(2023, time.March, 15, 1, 0, 0, 0, time.UTC)
+
+func newTestSchedule(policy v1alpha1.ConcurrencyPolicy) *v1alpha1.K6Schedule {
+	return &v1alpha1.K6Schedule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "nightly",
+			Namespace:         "test",
+			UID:               "nightly-uid",
+			CreationTimestamp: metav1.NewTime(scheduleCreated),
+		},
+		Spec: v1alpha1.K6ScheduleSpec{
+			Schedule:          "0 3 * * *",
+			ConcurrencyPolicy: policy,
+			Template: v1alpha1.K6Template{
+				Metadata: v1alpha1.PodMetadata{Labels: map[string]string{"team": "qa"}},
+				Spec: v1alpha1.K6Spec{
+					Parallelism: 2,
+					Script:      v1alpha1.K6Script{ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"}},
+				},
+			},
+		},
+	}
+}
+
+// scheduledK6 returns a K6 resource created by the schedule at the time.
+func scheduledK6(t *testing.T, scheme *runtime.Scheme, k6Schedule *v1alpha1.K6Schedule, created time.Time, stage v1alpha1.Stage) *v1alpha1.K6 {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              fmt.Sprintf("nightly-%d", created.Unix()/60),
+			Namespace:         "test",
+			Labels:            map[string]string{k6ScheduleLabelName: "nightly"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Status: v1alpha1.K6Status{Stage: stage},
+	}
+	assert.NoError(t, controllerutil.SetControllerReference(k6Schedule, k6, scheme))
+	return k6
+}
+
+func reconcileSchedule(t *testing.T, r *K6ScheduleReconciler) ctrl.Result {
+	res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "nightly", Namespace: "test"}})
+	assert.NoError(t, err)
+	return res
+}
+
+func listScheduled(t *testing.T, c client.Client) []string {
+	k6List := &v1alpha1.K6List{}
+	assert.NoError(t, c.List(context.Background(), k6List, client.InNamespace("test")))
+	var names []string
+	for _, k6 := range k6List.Items {
+		names = append(names, k6.Name)
+	}
+	return names
+}
+
+func Test_K6ScheduleCreatesOnSchedule(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6Schedule := newTestSchedule("")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6Schedule).Build()
+	clock := clocktesting.NewFakePassiveClock(time.Date(2023, time.March, 15, 2, 59, 0, 0, time.UTC))
+	r := &K6ScheduleReconciler{Client: c, Scheme: scheme, Log: logr.Discard(), Clock: clock}
+
+	res := reconcileSchedule(t, r)
+	assert.Equal(t, time.Minute, res.RequeueAfter)
+	assert.Empty(t, listScheduled(t, c))
+
+	due := time.Date(2023, time.March, 15, 3, 0, 0, 0, time.UTC)
+	clock.SetTime(due.Add(30 * time.Second))
+	res = reconcileSchedule(t, r)
+	assert.Equal(t, 24*time.Hour-30*time.Second, res.RequeueAfter)
+
+	name := fmt.Sprintf("nightly-%d", due.Unix()/60)
+	k6 := &v1alpha1.K6{}
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "test"}, k6))
+	assert.Equal(t, map[string]string{"team": "qa", k6ScheduleLabelName: "nightly"}, k6.Labels)
+	assert.Equal(t, "2023-03-15T03:00:00Z", k6.Annotations[scheduledAtAnnotation])
+	assert.True(t, metav1.IsControlledBy(k6, k6Schedule))
+	assert.Equal(t, int32(2), k6.Spec.Parallelism)
+
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "nightly", Namespace: "test"}, k6Schedule))
+	assert.True(t, k6Schedule.Status.LastScheduleTime.Time.Equal(due))
+	assert.Equal(t, []string{name}, k6Schedule.Status.Active)
+
+	// the same scheduled time is not run twice
+	reconcileSchedule(t, r)
+	assert.Equal(t, []string{name}, listScheduled(t, c))
+}
+
+func Test_K6ScheduleConcurrencyPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	yesterday := time.Date(2023, time.March, 15, 3, 0, 0, 0, time.UTC)
+	today := yesterday.Add(24 * time.Hour)
+	previous := fmt.Sprintf("nightly-%d", yesterday.Unix()/60)
+	next := fmt.Sprintf("nightly-%d", today.Unix()/60)
+
+	tests := []struct {
+		policy v1alpha1.ConcurrencyPolicy
+		k6s    []string
+	}{
+		{v1alpha1.AllowConcurrent, []string{previous, next}},
+		{v1alpha1.ForbidConcurrent, []string{previous}},
+		{v1alpha1.ReplaceConcurrent, []string{next}},
+	}
+
+	for _, test := range tests {
+		k6Schedule := newTestSchedule(test.policy)
+		k6Schedule.Status.LastScheduleTime = &metav1.Time{Time: yesterday}
+		active := scheduledK6(t, scheme, k6Schedule, yesterday, "started")
+
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6Schedule, active).Build()
+		r := &K6ScheduleReconciler{Client: c, Scheme: scheme, Log: logr.Discard(), Clock: clocktesting.NewFakePassiveClock(today)}
+
+		reconcileSchedule(t, r)
+		assert.ElementsMatch(t, test.k6s, listScheduled(t, c), test.policy)
+
+		assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "nightly", Namespace: "test"}, k6Schedule))
+		assert.True(t, k6Schedule.Status.LastScheduleTime.Time.Equal(today), test.policy)
+		assert.ElementsMatch(t, test.k6s, k6Schedule.Status.Active, test.policy)
+	}
+}
+
+func Test_K6SchedulePrunesHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6Schedule := newTestSchedule("")
+	now := time.Date(2023, time.March, 25, 2, 0, 0, 0, time.UTC)
+	k6Schedule.Status.LastScheduleTime = &metav1.Time{Time: now.Add(-23 * time.Hour)}
+
+	var (
+		objects []client.Object
+		kept    []string
+	)
+	for day := 0; day < 7; day++ {
+		created := time.Date(2023, time.March, 16+day, 3, 0, 0, 0, time.UTC)
+		stage := v1alpha1.Stage("finished")
+		// the last two are failed
+		if day >= 5 {
+			stage = "error"
+		}
+		k6 := scheduledK6(t, scheme, k6Schedule, created, stage)
+		objects = append(objects, k6)
+		// 3 finished and 1 failed are kept
+		if day >= 2 && day != 5 {
+			kept = append(kept, k6.Name)
+		}
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objects, k6Schedule)...).Build()
+	r := &K6ScheduleReconciler{Client: c, Scheme: scheme, Log: logr.Discard(), Clock: clocktesting.NewFakePassiveClock(now)}
+
+	reconcileSchedule(t, r)
+	assert.ElementsMatch(t, kept, listScheduled(t, c))
+
+	// with zero limit, the history is not kept at all
+	zero := int32(0)
+	assert.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "nightly", Namespace: "test"}, k6Schedule))
+	k6Schedule.Spec.SuccessfulRunsHistoryLimit = &zero
+	k6Schedule.Spec.FailedRunsHistoryLimit = &zero
+	assert.NoError(t, c.Update(context.Background(), k6Schedule))
+
+	reconcileSchedule(t, r)
+	assert.Empty(t, listScheduled(t, c))
+}
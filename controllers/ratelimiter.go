@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// transient errors are retried starting from this delay, doubled on each
+	// consecutive failure up to the max, unless set otherwise in the reconciler
+	defaultFailureBaseDelay = time.Second
+	defaultFailureMaxDelay  = 5 * time.Minute
+	// up to this fraction of the delay is added on top of it
+	failureJitter = 0.5
+)
+
+// jitteredRateLimiter adds jitter to the delays of the wrapped rate limiter, so
+// that reconciles failing at the same time, e.g. on API server hiccups, are not
+// retried all at once.
+type jitteredRateLimiter struct {
+	workqueue.RateLimiter
+	maxFactor float64
+}
+
+func (r *jitteredRateLimiter) When(item interface{}) time.Duration {
+	return wait.Jitter(r.RateLimiter.When(item), r.maxFactor)
+}
+
+// newRateLimiter returns a rate limiter for reconciles that either returned an
+// error or asked for requeue without a delay: a per-item exponential backoff
+// with jitter, together with the overall limit as in controller-runtime default.
+// Reconciles that asked for requeue after a delay are not rate limited.
+func newRateLimiter(baseDelay, maxDelay time.Duration) workqueue.RateLimiter {
+	if baseDelay <= 0 {
+		baseDelay = defaultFailureBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultFailureMaxDelay
+	}
+	if maxDelay < baseDelay {
+		maxDelay = baseDelay
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		&jitteredRateLimiter{
+			RateLimiter: workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+			maxFactor:   failureJitter,
+		},
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(0, 0)
+
+	expected := defaultFailureBaseDelay
+	for i := 0; i < 5; i++ {
+		delay := limiter.When("k6")
+		assert.GreaterOrEqual(t, delay, expected)
+		assert.LessOrEqual(t, delay, time.Duration(float64(expected)*(1+failureJitter)))
+		expected *= 2
+	}
+	assert.Equal(t, 5, limiter.NumRequeues("k6"))
+
+	// a successful reconcile resets the backoff
+	limiter.Forget("k6")
+	delay := limiter.When("k6")
+	assert.LessOrEqual(t, delay, time.Duration(float64(defaultFailureBaseDelay)*(1+failureJitter)))
+}
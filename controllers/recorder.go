@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// runIDAnnotation is set on events about K6 resources to correlate them
+// with the run of the test.
+const runIDAnnotation = "k6.io/run-id"
+
+// runIDRecorder annotates events about K6 resources with their run ID.
+type runIDRecorder struct {
+	record.EventRecorder
+}
+
+func runIDAnnotations(object runtime.Object) map[string]string {
+	if k6, ok := object.(*v1alpha1.K6); ok && len(k6.Status.RunID) > 0 {
+		return map[string]string{runIDAnnotation: k6.Status.RunID}
+	}
+	return nil
+}
+
+func (r runIDRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.EventRecorder.AnnotatedEventf(object, runIDAnnotations(object), eventtype, reason, "%s", message)
+}
+
+func (r runIDRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, runIDAnnotations(object), eventtype, reason, messageFmt, args...)
+}
+
+// recordEvent emits an event about the K6 resource if there is a recorder.
+func (r *K6Reconciler) recordEvent(k6 *v1alpha1.K6, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(k6, eventType, reason, message)
+	}
+}
+
+// recordStageChange emits an event about the stage the test run has just
+// entered, so that the timeline of the test run is visible in the events.
+func (r *K6Reconciler) recordStageChange(k6 *v1alpha1.K6) {
+	switch k6.Status.Stage {
+	case "initialization":
+		r.recordEvent(k6, corev1.EventTypeNormal, "Initializing", "Initializer is inspecting the script")
+	case "initialized":
+		r.recordEvent(k6, corev1.EventTypeNormal, "Initialized", "Test run is initialized")
+	case "created":
+		r.recordEvent(k6, corev1.EventTypeNormal, "Created", fmt.Sprintf("Created %d runners", k6.Spec.Parallelism))
+	case "started":
+		r.recordEvent(k6, corev1.EventTypeNormal, "Started", fmt.Sprintf("Started %d/%d runners", k6.Status.StartedRunners, k6.Spec.Parallelism))
+	case "finished":
+		r.recordEvent(k6, corev1.EventTypeNormal, "Finished", "Test run is finished")
+	case "error":
+		r.recordEvent(k6, corev1.EventTypeWarning, "Error", "Test run has failed")
+	}
+}
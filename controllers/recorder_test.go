@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// eventReasons drains the recorder and returns the type and reason of each event.
+func eventReasons(recorder *record.FakeRecorder) []string {
+	var reasons []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			fields := strings.Fields(event)
+			reasons = append(reasons, fields[0]+" "+fields[1])
+		default:
+			return reasons
+		}
+	}
+}
+
+func Test_StageChangeEvents(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "events", Namespace: "test"},
+		Spec:       v1alpha1.K6Spec{Parallelism: 2},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	recorder := record.NewFakeRecorder(16)
+	r := &K6Reconciler{Client: c, Recorder: recorder}
+
+	for _, stage := range []v1alpha1.Stage{"initialization", "initialized", "created", "started", "finished"} {
+		k6.Status.Stage = stage
+		if stage == "started" {
+			k6.Status.StartedRunners = 2
+		}
+		_, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+		assert.NoError(t, err)
+	}
+
+	// the same stage again is not a change
+	_, err := r.UpdateStatus(context.Background(), k6, logr.Discard())
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Normal Initializing",
+		"Normal Initialized",
+		"Normal Created",
+		"Normal Started",
+		"Normal Finished",
+	}, eventReasons(recorder))
+	trackedStages.forget(client.ObjectKeyFromObject(k6))
+}
+
+// failingClient fails creation of all objects.
+type failingClient struct {
+	client.Client
+}
+
+func (c *failingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return errors.New("quota exceeded")
+}
+
+func Test_JobCreationFailedEvent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, batchv1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "events", Namespace: "test"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 1,
+			Script:      v1alpha1.K6Script{ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"}},
+		},
+		Status: v1alpha1.K6Status{Stage: "initialized"},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	recorder := record.NewFakeRecorder(16)
+	r := &K6Reconciler{Client: &failingClient{c}, Scheme: scheme, Recorder: recorder}
+
+	_, err := CreateJobs(context.Background(), logr.Discard(), k6, r)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"Warning JobCreationFailed"}, eventReasons(recorder))
+}
+
+func Test_TokenNotFoundEvent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "events", Namespace: "test"},
+		Status:     v1alpha1.K6Status{Stage: "initialization", CloudTokenAttempts: 1},
+	}
+	k6.InitializeConditions()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	recorder := record.NewFakeRecorder(16)
+	r := &K6Reconciler{Client: c, Scheme: scheme, Recorder: recorder, CloudTokenMaxWait: time.Second}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	_, err := waitForToken(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Warning TokenNotFound", "Warning Error"}, eventReasons(recorder))
+}
+
+func Test_CloudFinalizeFailedEvent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	defer func(finalize func(*cloudapi.Client, string, cloudapi.RunStatus) error, check func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error)) {
+		finalizeCloudTestRunFn, checkCloudTestRun = finalize, check
+	}(finalizeCloudTestRunFn, checkCloudTestRun)
+	finalizeCloudTestRunFn = func(*cloudapi.Client, string, cloudapi.RunStatus) error {
+		return errors.New("unavailable")
+	}
+	checkCloudTestRun = func(*cloudapi.Client, string) (bool, cloudapi.RunStatus, error) {
+		return false, cloudapi.RunStatusRunning, nil
+	}
+
+	k6 := newCloudK6()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(k6.DeepCopy()).Build()
+	recorder := record.NewFakeRecorder(16)
+	r := &K6Reconciler{Client: c, Scheme: scheme, Recorder: recorder}
+	defer trackedStages.forget(client.ObjectKeyFromObject(k6))
+
+	_, err := FinalizeCloudTestRun(context.Background(), logr.Discard(), k6, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Warning CloudFinalizeFailed"}, eventReasons(recorder))
+}
@@ -18,16 +18,22 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/grafana/k6-operator/controllers"
+	"github.com/grafana/k6-operator/pkg/cloud"
+	"github.com/grafana/k6-operator/pkg/logging"
+	"github.com/grafana/k6-operator/pkg/resources/jobs"
+	"github.com/grafana/k6-operator/pkg/types"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	k6v1alpha1 "github.com/grafana/k6-operator/api/v1alpha1"
 	// +kubebuilder:scaffold:imports
@@ -52,9 +58,74 @@ func main() {
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	var noUsageReport bool
+	noUsageReportDefault, _ := strconv.ParseBool(os.Getenv("K6_NO_USAGE_REPORT"))
+	flag.BoolVar(&noUsageReport, "no-usage-report", noUsageReportDefault,
+		"Disable k6 usage report for all test runs, unless set otherwise in the spec. "+
+			"Defaults to the value of K6_NO_USAGE_REPORT env variable.")
+	var enableReadinessEndpoint bool
+	flag.BoolVar(&enableReadinessEndpoint, "enable-readiness-endpoint", false,
+		"Serve aggregate readiness of runners per K6 resource at "+controllers.ReadinessPath+"<namespace>/<name> "+
+			"on the metrics address.")
+	var restrictedSecurityContext bool
+	restrictedSecurityContextDefault, _ := strconv.ParseBool(os.Getenv("RESTRICTED_SECURITY_CONTEXT"))
+	flag.BoolVar(&restrictedSecurityContext, "restricted-security-context", restrictedSecurityContextDefault,
+		"Run pods of test runs compliant with the restricted Pod Security Standard, unless they have security contexts in the spec. "+
+			"Defaults to the value of RESTRICTED_SECURITY_CONTEXT env variable.")
+	var maxConcurrentReconciles int
+	maxConcurrentReconcilesDefault := 1
+	if n, err := strconv.Atoi(os.Getenv("MAX_CONCURRENT_RECONCILES")); err == nil {
+		maxConcurrentReconcilesDefault = n
+	}
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", maxConcurrentReconcilesDefault,
+		"Maximum number of K6 resources reconciled at the same time. "+
+			"Defaults to the value of MAX_CONCURRENT_RECONCILES env variable or 1.")
+	var failureBaseDelay, failureMaxDelay time.Duration
+	flag.DurationVar(&failureBaseDelay, "failure-base-delay", time.Second,
+		"Delay before retrying a failed reconcile of a K6 resource, doubled on each consecutive failure.")
+	flag.DurationVar(&failureMaxDelay, "failure-max-delay", 5*time.Minute,
+		"Maximum delay before retrying a failed reconcile of a K6 resource.")
+	var cloudTokenMaxWait time.Duration
+	flag.DurationVar(&cloudTokenMaxWait, "cloud-token-max-wait", 10*time.Minute,
+		"Maximum time a cloud test run waits for k6 Cloud token before it fails.")
+	var cloudTokenNamespace string
+	flag.StringVar(&cloudTokenNamespace, "cloud-token-namespace", "k6-operator-system",
+		"Namespace of the secret with k6 Cloud token, labelled k6cloud=token.")
+	var cloudTokenFile string
+	flag.StringVar(&cloudTokenFile, "cloud-token-file", "",
+		"Read k6 Cloud token from the file, e.g. a projected volume, instead of the secret with k6cloud=token label.")
+	var cloudTokenRefresh time.Duration
+	flag.DurationVar(&cloudTokenRefresh, "cloud-token-refresh", time.Minute,
+		"Interval of reading the file in --cloud-token-file again, to pick up a rotated token.")
+	var cloudCAFile string
+	flag.StringVar(&cloudCAFile, "cloud-ca-file", "",
+		"Trust the CAs in the PEM file, e.g. the private CA of self-hosted k6 Cloud, in requests to k6 Cloud.")
+	var logFormat string
+	logFormatDefault := os.Getenv("LOG_FORMAT")
+	if len(logFormatDefault) == 0 {
+		logFormatDefault = logging.ConsoleFormat
+	}
+	flag.StringVar(&logFormat, "log-format", logFormatDefault,
+		"Format of the logs, console or json. Defaults to the value of LOG_FORMAT env variable or console.")
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	logger, err := logging.New(logFormat, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ctrl.SetLogger(logger)
+
+	if len(cloudCAFile) > 0 {
+		bundle, err := os.ReadFile(cloudCAFile)
+		if err == nil {
+			err = cloud.TrustCABundle(bundle)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to load CA bundle for k6 Cloud", "file", cloudCAFile)
+			os.Exit(1)
+		}
+	}
 
 	watchNamespace := getWatchNamespace()
 
@@ -72,16 +143,61 @@ func main() {
 		os.Exit(1)
 	}
 
+	jobs.Defaults.NoUsageReport = noUsageReport
+	jobs.Defaults.RestrictedSecurityContext = restrictedSecurityContext
+
+	var tokenFile *controllers.TokenFile
+	if len(cloudTokenFile) > 0 {
+		tokenFile = controllers.NewTokenFile(cloudTokenFile, cloudTokenRefresh)
+	}
+
 	if err = (&controllers.K6Reconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("K6"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("K6"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("k6-operator"),
+
+		APIReader:               mgr.GetAPIReader(),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		FailureBaseDelay:        failureBaseDelay,
+		FailureMaxDelay:         failureMaxDelay,
+		CloudTokenMaxWait:       cloudTokenMaxWait,
+		CloudTokenNamespace:     cloudTokenNamespace,
+		CloudTokenFile:          tokenFile,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "K6")
 		os.Exit(1)
 	}
+	if err = (&controllers.K6ScheduleReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("K6Schedule"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("k6-operator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "K6Schedule")
+		os.Exit(1)
+	}
+	// Webhooks need serving certificates so they are enabled only on demand,
+	// see config/default/manager_webhook_patch.yaml.
+	if enableWebhooks, _ := strconv.ParseBool(os.Getenv("ENABLE_WEBHOOKS")); enableWebhooks {
+		if err = (&k6v1alpha1.K6{}).SetupWebhookWithManager(mgr, k6v1alpha1.WebhookOptions{
+			TokenNamespace: cloudTokenNamespace,
+			TokenFromFile:  len(cloudTokenFile) > 0,
+			HasCloudOutput: func(spec *k6v1alpha1.K6Spec) bool { return types.ParseCLI(spec).HasCloudOut },
+		}); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "K6")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if enableReadinessEndpoint {
+		if err = mgr.AddMetricsExtraHandler(controllers.ReadinessPath, controllers.ReadinessHandler(mgr.GetClient())); err != nil {
+			setupLog.Error(err, "unable to set up readiness endpoint")
+			os.Exit(1)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
@@ -0,0 +1,35 @@
+package cloud
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+)
+
+// TrustCABundle makes k6 Cloud clients trust the certificates in the PEM
+// bundle, e.g. of the private CA of self-hosted k6 Cloud, in addition to the
+// system ones. cloudapi.Client doesn't take a transport of its own and uses
+// the default one, so the bundle is trusted by the default transport of the
+// whole process.
+func TrustCABundle(bundle []byte) error {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(bundle) {
+		return errors.New("no certificates found in CA bundle")
+	}
+
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return errors.New("default transport is not an *http.Transport")
+	}
+	transport := defaultTransport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.RootCAs = pool
+	http.DefaultTransport = transport
+	return nil
+}
@@ -0,0 +1,46 @@
+package cloud
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+)
+
+func Test_TrustCABundle(t *testing.T) {
+	defer func(transport http.RoundTripper) {
+		http.DefaultTransport = transport
+	}(http.DefaultTransport)
+
+	var finished []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		finished = append(finished, req.URL.Path)
+	}))
+	// failed handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	clients := &Clients{}
+
+	// the certificate of the server is signed by an unknown CA
+	err := FinishTestRun(clients.Get("token", server.URL), "123", cloudapi.RunStatusFinished)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "certificate")
+	}
+	assert.Empty(t, finished)
+
+	assert.EqualError(t, TrustCABundle([]byte("not a certificate")), "no certificates found in CA bundle")
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	assert.NoError(t, TrustCABundle(bundle))
+
+	// existing clients trust it too
+	assert.NoError(t, FinishTestRun(clients.Get("token", server.URL), "123", cloudapi.RunStatusFinished))
+	assert.Equal(t, []string{"/v1/tests/123"}, finished)
+}
@@ -0,0 +1,77 @@
+package cloud
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/cloudapi"
+	"go.k6.io/k6/lib/consts"
+)
+
+type clientKey struct {
+	token, host string
+}
+
+// Clients is a cache of k6 Cloud clients by token and host, so that each
+// test run talks to k6 Cloud with the token of its own. The zero value is
+// ready to use.
+type Clients struct {
+	mu      sync.Mutex
+	clients map[clientKey]*cloudapi.Client
+}
+
+// Get returns the client for the token and host, creating it if needed. An
+// empty host is the default host of k6 Cloud.
+func (c *Clients) Get(token, host string) *cloudapi.Client {
+	if len(host) == 0 {
+		host = cloudapi.NewConfig().Host.String
+	}
+	key := clientKey{token: token, host: host}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if client, ok := c.clients[key]; ok {
+		return client
+	}
+	if c.clients == nil {
+		c.clients = map[clientKey]*cloudapi.Client{}
+	}
+
+	logger := &logrus.Logger{
+		Out:       os.Stdout,
+		Formatter: new(logrus.TextFormatter),
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.InfoLevel,
+	}
+	client := cloudapi.NewClient(logger, token, host, consts.Version, time.Duration(time.Minute))
+	c.clients[key] = client
+	return client
+}
+
+// ForgetToken drops the clients of the token, e.g. when it was rotated.
+func (c *Clients) ForgetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.clients {
+		if key.token == token {
+			delete(c.clients, key)
+		}
+	}
+}
+
+// Forget drops the client, e.g. when k6 Cloud has rejected its token, so
+// that a rotated token doesn't leave the old client behind.
+func (c *Clients) Forget(client *cloudapi.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, cached := range c.clients {
+		if cached == client {
+			delete(c.clients, key)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package cloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+)
+
+func Test_Clients(t *testing.T) {
+	var clients Clients
+
+	teamA := clients.Get("token-a", "")
+	assert.Same(t, teamA, clients.Get("token-a", cloudapi.NewConfig().Host.String))
+	assert.NotSame(t, teamA, clients.Get("token-b", ""))
+	assert.NotSame(t, teamA, clients.Get("token-a", "https://k6.example.com"))
+
+	clients.Forget(teamA)
+	assert.NotSame(t, teamA, clients.Get("token-a", ""))
+
+	// all clients of a rotated token are dropped, and only them
+	teamB := clients.Get("token-b", "")
+	clients.ForgetToken("token-a")
+	assert.Equal(t, map[clientKey]*cloudapi.Client{{token: "token-b", host: cloudapi.NewConfig().Host.String}: teamB}, clients.clients)
+}
@@ -1,21 +1,20 @@
 package cloud
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"time"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
 
 	"github.com/go-logr/logr"
-	"github.com/sirupsen/logrus"
 	"go.k6.io/k6/cloudapi"
-	"go.k6.io/k6/lib/consts"
 	"go.k6.io/k6/lib/types"
 	"go.k6.io/k6/metrics"
 	"gopkg.in/guregu/null.v3"
 )
 
-var client *cloudapi.Client
-
 type InspectOutput struct {
 	External struct {
 		Loadimpact struct {
@@ -36,9 +35,43 @@ type TestRun struct {
 	Duration          int64               `json:"duration"`
 	ProcessThresholds bool                `json:"process_thresholds"`
 	Instances         int32               `json:"instances"`
+	Note              string              `json:"note,omitempty"`
 }
 
-func CreateTestRun(opts InspectOutput, instances int32, host, token string, log logr.Logger) (*cloudapi.CreateTestRunResponse, error) {
+// maxNameLength is the limit for names of test runs: longer ones are truncated.
+const maxNameLength = 100
+
+// SanitizeName makes a name fit for k6 Cloud: control characters are
+// dropped, whitespace is collapsed and the name is truncated if too long.
+func SanitizeName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case !unicode.IsPrint(r):
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteRune(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+
+	sanitized := []rune(b.String())
+	if len(sanitized) > maxNameLength {
+		sanitized = sanitized[:maxNameLength]
+	}
+	return strings.TrimSpace(string(sanitized))
+}
+
+// CreateTestRun creates the test run in k6 Cloud with the client of the
+// token and host the runners use.
+func CreateTestRun(client *cloudapi.Client, opts InspectOutput, instances int32, host, note string, log logr.Logger) (*cloudapi.CreateTestRunResponse, error) {
+	opts.External.Loadimpact.Name = SanitizeName(opts.External.Loadimpact.Name)
 	if len(opts.External.Loadimpact.Name) < 1 {
 		opts.External.Loadimpact.Name = "k6-operator-test"
 	}
@@ -49,13 +82,6 @@ func CreateTestRun(opts InspectOutput, instances int32, host, token string, log
 		cloudConfig.ProjectID = null.NewInt(opts.External.Loadimpact.ProjectID, true)
 	}
 
-	logger := &logrus.Logger{
-		Out:       os.Stdout,
-		Formatter: new(logrus.TextFormatter),
-		Hooks:     make(logrus.LevelHooks),
-		Level:     logrus.InfoLevel,
-	}
-
 	thresholds := make(map[string][]string, len(opts.Thresholds))
 	for name, t := range opts.Thresholds {
 		for _, threshold := range t.Thresholds {
@@ -67,10 +93,6 @@ func CreateTestRun(opts InspectOutput, instances int32, host, token string, log
 		host = cloudConfig.Host.String
 	}
 
-	if client == nil {
-		client = cloudapi.NewClient(logger, token, host, consts.Version, time.Duration(time.Minute))
-	}
-
 	return createTestRun(client, host, &TestRun{
 		Name:              opts.External.Loadimpact.Name,
 		ProjectID:         cloudConfig.ProjectID.Int64,
@@ -79,9 +101,31 @@ func CreateTestRun(opts InspectOutput, instances int32, host, token string, log
 		Duration:          int64(opts.TotalDuration.TimeDuration().Seconds()),
 		ProcessThresholds: true,
 		Instances:         instances,
+		Note:              note,
 	})
 }
 
+// TestRunURL returns the link to the results of the test run in k6 Cloud
+// web app. webAppURL is K6_CLOUD_WEB_APP_URL of runners, if set, and override
+// is the config returned on creation of the test run, if any. Self-hosted
+// hosts without web app URL are assumed to serve the web app as well.
+func TestRunURL(refID, host, webAppURL string, override *cloudapi.Config) string {
+	config := cloudapi.NewConfig()
+	if override != nil {
+		config = config.Apply(*override)
+	}
+
+	switch {
+	case len(webAppURL) > 0:
+		config.WebAppURL = null.StringFrom(strings.TrimSuffix(webAppURL, "/"))
+	case !config.WebAppURL.Valid && len(host) > 0 && host != config.Host.String:
+		if u, err := url.Parse(host); err == nil && len(u.Host) > 0 {
+			config.WebAppURL = null.StringFrom(u.Scheme + "://" + u.Host)
+		}
+	}
+	return cloudapi.URLForResults(refID, config)
+}
+
 // We cannot use cloudapi.TestRun struct and cloudapi.Client.CreateTestRun call because they're not aware of
 // process_thresholds argument; so let's use custom struct and function instead
 func createTestRun(client *cloudapi.Client, host string, testRun *TestRun) (*cloudapi.CreateTestRunResponse, error) {
@@ -104,8 +148,92 @@ func createTestRun(client *cloudapi.Client, host string, testRun *TestRun) (*clo
 	return &ctrr, nil
 }
 
-func FinishTestRun(refID string) error {
+// FinishTestRun finalizes the test run with the run status, e.g.
+// cloudapi.RunStatusAbortedUser for test runs aborted on request.
+func FinishTestRun(client *cloudapi.Client, refID string, runStatus cloudapi.RunStatus) error {
 	return client.TestFinished(refID, cloudapi.ThresholdResult(
 		map[string]map[string]bool{},
-	), false, cloudapi.RunStatusFinished)
+	), false, runStatus)
+}
+
+// Project is a k6 Cloud project the test run belongs to.
+type Project struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	OrganizationID int64  `json:"organization_id"`
+}
+
+type testRunResponse struct {
+	K6Run struct {
+		ProjectID int64 `json:"project_id"`
+	} `json:"k6-run"`
+}
+
+type projectResponse struct {
+	Project Project `json:"project"`
+}
+
+// GetProject returns the project of the test run together with its organization.
+// projectID is used when it is known already, e.g. from options of the script;
+// otherwise, it is resolved from the test run as it is the default project of the token.
+func GetProject(client *cloudapi.Client, refID, host string, projectID int64) (*Project, error) {
+	if len(host) == 0 {
+		host = cloudapi.NewConfig().Host.String
+	}
+
+	if projectID == 0 {
+		req, err := client.NewRequest("GET", fmt.Sprintf("%s/loadtests/v2/runs/%s", host, refID), nil)
+		if err != nil {
+			return nil, err
+		}
+		trr := testRunResponse{}
+		if err = client.Do(req, &trr); err != nil {
+			return nil, err
+		}
+		projectID = trr.K6Run.ProjectID
+	}
+
+	req, err := client.NewRequest("GET", fmt.Sprintf("%s/v3/projects/%d", host, projectID), nil)
+	if err != nil {
+		return nil, err
+	}
+	pr := projectResponse{}
+	if err = client.Do(req, &pr); err != nil {
+		return nil, err
+	}
+	if pr.Project.ID == 0 {
+		pr.Project.ID = projectID
+	}
+
+	return &pr.Project, nil
+}
+
+// IsForbidden returns true if the error means that the token is not
+// permitted to do the request.
+func IsForbidden(err error) bool {
+	if errors.Is(err, cloudapi.ErrNotAuthorized) {
+		return true
+	}
+	var errResp cloudapi.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusForbidden
+}
+
+// IsUnauthenticated returns true if the error means that k6 Cloud has
+// rejected the token, e.g. because it was rotated.
+func IsUnauthenticated(err error) bool {
+	if errors.Is(err, cloudapi.ErrNotAuthenticated) {
+		return true
+	}
+	var errResp cloudapi.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized
+}
+
+// IsTestRunFinished checks whether the test run is finished already in k6 Cloud,
+// e.g. when it was aborted in the UI, and returns its run status.
+func IsTestRunFinished(client *cloudapi.Client, refID string) (bool, cloudapi.RunStatus, error) {
+	progress, err := client.GetTestProgress(refID)
+	if err != nil {
+		return false, 0, err
+	}
+	return progress.RunStatus >= cloudapi.RunStatusFinished, progress.RunStatus, nil
 }
@@ -0,0 +1,105 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/cloudapi"
+	"gopkg.in/guregu/null.v3"
+)
+
+func Test_SanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Plain", "PR-1234 checkout load test", "PR-1234 checkout load test"},
+		{"Whitespace", "  PR-1234\tcheckout\n\nload test ", "PR-1234 checkout load test"},
+		{"ControlCharacters", "PR-1234\x00 checkout\x1b", "PR-1234 checkout"},
+		{"Truncated", strings.Repeat("a", 120), strings.Repeat("a", 100)},
+		{"Empty", " \n ", ""},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, SanitizeName(test.input))
+		})
+	}
+}
+
+func Test_IsForbidden(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"NotAuthorized", cloudapi.ErrNotAuthorized, true},
+		{"Wrapped", fmt.Errorf("finalize: %w", cloudapi.ErrNotAuthorized), true},
+		{"ErrorResponse", cloudapi.ErrorResponse{Response: &http.Response{StatusCode: http.StatusForbidden}}, true},
+		{"NotFound", cloudapi.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}, false},
+		{"NotAuthenticated", cloudapi.ErrNotAuthenticated, false},
+		{"Other", errors.New("connection refused"), false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, IsForbidden(test.err))
+		})
+	}
+}
+
+func Test_IsUnauthenticated(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"NotAuthenticated", cloudapi.ErrNotAuthenticated, true},
+		{"Wrapped", fmt.Errorf("finalize: %w", cloudapi.ErrNotAuthenticated), true},
+		{"ErrorResponse", cloudapi.ErrorResponse{Response: &http.Response{StatusCode: http.StatusUnauthorized}}, true},
+		{"NotAuthorized", cloudapi.ErrNotAuthorized, false},
+		{"Other", errors.New("connection refused"), false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, IsUnauthenticated(test.err))
+		})
+	}
+}
+
+func Test_TestRunURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		webAppURL string
+		override  *cloudapi.Config
+		expected  string
+	}{
+		{"Default", "", "", nil, "https://app.k6.io/runs/123"},
+		{"DefaultHost", "https://ingest.k6.io", "", nil, "https://app.k6.io/runs/123"},
+		{"SelfHosted", "https://k6.example.com/api", "", nil, "https://k6.example.com/runs/123"},
+		{"WebAppURL", "https://ingest.example.com", "https://app.example.com/", nil, "https://app.example.com/runs/123"},
+		{"Override", "", "", &cloudapi.Config{WebAppURL: null.StringFrom("https://app.grafana.example")}, "https://app.grafana.example/runs/123"},
+		{"TestRunDetails", "https://k6.example.com", "", &cloudapi.Config{TestRunDetails: null.StringFrom("https://k6.example.com/a/runs/123")}, "https://k6.example.com/a/runs/123"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, TestRunURL("123", test.host, test.webAppURL, test.override))
+		})
+	}
+}
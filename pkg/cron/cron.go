@@ -0,0 +1,177 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression in the standard format of five
+// fields: minute, hour, day of month, month and day of week.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// with both days set, a day matches any of them as in cron
+	domStar, dowStar bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dowNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+// Parse parses the cron expression. Fields may be *, values, ranges and
+// lists of them, with steps like */15. Months and days of week may be given
+// by their three-letter names. The @hourly, @daily, @weekly, @monthly and
+// @yearly shorthands are supported too.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if standard, ok := descriptors[strings.ToLower(expr)]; ok {
+		expr = standard
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+	if s.minute, _, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute of %q: %w", expr, err)
+	}
+	if s.hour, _, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour of %q: %w", expr, err)
+	}
+	if s.dom, s.domStar, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month of %q: %w", expr, err)
+	}
+	if s.month, _, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month of %q: %w", expr, err)
+	}
+	// 7 is Sunday too
+	if s.dow, s.dowStar, err = parseField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("day of week of %q: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return &s, nil
+}
+
+// parseField returns the set of values of the field as a bit mask and
+// whether it starts with *.
+func parseField(field string, min, max int, names map[string]int) (uint64, bool, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, false, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		var first, last int
+		switch {
+		case rangePart == "*":
+			first, last = min, max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if first, err = parseValue(bounds[0], names); err != nil {
+				return 0, false, err
+			}
+			if last, err = parseValue(bounds[1], names); err != nil {
+				return 0, false, err
+			}
+		default:
+			var err error
+			if first, err = parseValue(rangePart, names); err != nil {
+				return 0, false, err
+			}
+			last = first
+			// a single value with a step runs to the end, as in 5/15
+			if step > 1 {
+				last = max
+			}
+		}
+
+		if first < min || last > max || first > last {
+			return 0, false, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := first; v <= last; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, strings.HasPrefix(field, "*"), nil
+}
+
+func parseValue(value string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	return v, nil
+}
+
+// Next returns the first time of the schedule after t, in the location of t.
+// The zero time is returned if there is none in the next five years, e.g.
+// for 30th of February.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
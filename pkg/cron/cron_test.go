@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Next(t *testing.T) {
+	// Wednesday
+	now := time.Date(2023, time.March, 15, 10, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2023, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2023, time.March, 16, 10, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2023, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2023, time.March, 16, 3, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2023, time.March, 15, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * MON-FRI", time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * FEB *", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		// with both days set, either of them matches
+		{"0 0 20 * FRI", time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.expr)
+		if assert.NoError(t, err, test.expr) {
+			assert.Equal(t, test.next, schedule.Next(now), test.expr)
+		}
+	}
+}
+
+func Test_ParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"@every 5m",
+	} {
+		_, err := Parse(expr)
+		assert.Error(t, err, expr)
+	}
+}
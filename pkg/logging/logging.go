@@ -0,0 +1,33 @@
+// Package logging builds the logger of the operator.
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// ConsoleFormat is human-readable, for development.
+	ConsoleFormat = "console"
+	// JSONFormat is one JSON object per line, for log aggregation. Values
+	// attached to the logger, like namespace and name of the resource, are
+	// top-level keys of the object.
+	JSONFormat = "json"
+)
+
+// New returns a logger writing to dest in the format.
+func New(format string, dest io.Writer) (logr.Logger, error) {
+	switch format {
+	case ConsoleFormat, "":
+		return zap.New(zap.UseDevMode(true), zap.WriteTo(dest)), nil
+	case JSONFormat:
+		return zap.New(zap.WriteTo(dest), zap.JSONEncoder(func(config *zapcore.EncoderConfig) {
+			config.EncodeTime = zapcore.ISO8601TimeEncoder
+		})), nil
+	}
+	return logr.Discard(), fmt.Errorf("unknown log format %q, expected %s or %s", format, ConsoleFormat, JSONFormat)
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log, err := New(JSONFormat, buf)
+	assert.NoError(t, err)
+
+	log.WithName("controllers").WithValues("namespace", "test", "name", "k6-sample", "stage", "started",
+		"reconcileID", "1234", "testRunId", "123").Info("Checking if all runner pods are finished")
+
+	line := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line), buf.String())
+	assert.Equal(t, "info", line["level"])
+	assert.Equal(t, "controllers", line["logger"])
+	assert.Equal(t, "Checking if all runner pods are finished", line["msg"])
+	assert.Contains(t, line, "ts")
+	for key, value := range map[string]string{
+		"namespace": "test", "name": "k6-sample", "stage": "started", "reconcileID": "1234", "testRunId": "123",
+	} {
+		assert.Equal(t, value, line[key], key)
+	}
+}
+
+func Test_NewConsole(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log, err := New(ConsoleFormat, buf)
+	assert.NoError(t, err)
+
+	log.Info("hello", "namespace", "test")
+	assert.Contains(t, buf.String(), "hello")
+	assert.Error(t, json.Unmarshal(buf.Bytes(), &map[string]interface{}{}))
+
+	_, err = New("yaml", buf)
+	assert.EqualError(t, err, `unknown log format "yaml", expected console or json`)
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Annotation is a request to Grafana HTTP API to create an annotation:
+// https://grafana.com/docs/grafana/latest/developers/http_api/annotations/
+type Annotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	Tags         []string `json:"tags,omitempty"`
+	Text         string   `json:"text"`
+}
+
+// CreateAnnotation posts the annotation to Grafana at the URL.
+func CreateAnnotation(ctx context.Context, url, apiKey string, annotation Annotation) error {
+	if annotation.Time == 0 {
+		annotation.Time = time.Now().UnixMilli()
+	}
+
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("grafana returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CreateAnnotation(t *testing.T) {
+	var (
+		received Annotation
+		auth     string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/annotations", r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	err := CreateAnnotation(context.Background(), server.URL+"/", "secret", Annotation{
+		DashboardUID: "abc",
+		Tags:         []string{"k6"},
+		Text:         "Test started",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, "abc", received.DashboardUID)
+	assert.Equal(t, []string{"k6"}, received.Tags)
+	assert.NotZero(t, received.Time)
+}
+
+func Test_CreateAnnotationError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	err := CreateAnnotation(context.Background(), server.URL, "wrong", Annotation{Text: "Test started"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TestRunResult is the payload posted to the webhook when the test run is over.
+type TestRunResult struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Stage     string     `json:"stage"`
+	Phase     string     `json:"phase"`
+	Reason    string     `json:"reason,omitempty"`
+	Passed    bool       `json:"passed"`
+	RunID     string     `json:"runId,omitempty"`
+	TestRunID string     `json:"testRunId,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   time.Time  `json:"endTime"`
+}
+
+// PostWebhook posts the result to the URL, with the Authorization header if
+// it's not empty. It makes a single attempt and tells whether it's worth
+// retrying: server errors and failed requests are, client errors are not.
+func PostWebhook(ctx context.Context, url, authorization string, result TestRunResult) (retry bool, err error) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return false, err
+	}
+	return postWebhook(ctx, url, authorization, body)
+}
+
+// postWebhook makes a single attempt and tells whether it's worth retrying.
+func postWebhook(ctx context.Context, url, authorization string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(authorization) > 0 {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return true, fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 {
+		return false, fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+	return false, nil
+}
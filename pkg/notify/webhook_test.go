@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PostWebhook(t *testing.T) {
+	var (
+		received map[string]interface{}
+		auth     string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		auth = r.Header.Get("Authorization")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	start := time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC)
+	_, err := PostWebhook(context.Background(), server.URL, "Bearer secret", TestRunResult{
+		Name:      "k6-sample",
+		Namespace: "default",
+		Stage:     "finished",
+		Phase:     "Succeeded",
+		Passed:    true,
+		TestRunID: "123",
+		StartTime: &start,
+		EndTime:   start.Add(time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, map[string]interface{}{
+		"name":      "k6-sample",
+		"namespace": "default",
+		"stage":     "finished",
+		"phase":     "Succeeded",
+		"passed":    true,
+		"testRunId": "123",
+		"startTime": "2023-03-01T12:00:00Z",
+		"endTime":   "2023-03-01T12:01:00Z",
+	}, received)
+}
+
+func Test_PostWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  int
+		retry bool
+		err   bool
+	}{
+		{"Delivered", http.StatusOK, false, false},
+		{"ServerError", http.StatusServiceUnavailable, true, true},
+		{"ClientError", http.StatusBadRequest, false, true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var hits int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.code)
+				hits++
+			}))
+			defer server.Close()
+
+			retry, err := PostWebhook(context.Background(), server.URL, "", TestRunResult{Name: "test"})
+			assert.Equal(t, test.err, err != nil, "unexpected error: %v", err)
+			assert.Equal(t, test.retry, retry)
+			// a single attempt is made: retries are up to the caller
+			assert.Equal(t, 1, hits)
+		})
+	}
+}
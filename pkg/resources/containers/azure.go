@@ -0,0 +1,120 @@
+package containers
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// AzureStorageAccountEnv and AzureStorageKeyEnv are read by the Azure CLI
+	// for account key auth.
+	AzureStorageAccountEnv = "AZURE_STORAGE_ACCOUNT"
+	AzureStorageKeyEnv     = "AZURE_STORAGE_KEY"
+
+	azureBlobHostSuffix = ".blob.core.windows.net"
+)
+
+// IsAzureBlobURI returns true for az:// URIs and URLs of Azure Blob Storage.
+func IsAzureBlobURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "az" || (u.Scheme == "https" && strings.HasSuffix(u.Host, azureBlobHostSuffix))
+}
+
+// AzureStorageAccount returns the name of the storage account from the URI
+// of a blob, or an empty string if it's not a valid one.
+func AzureStorageAccount(uri string) string {
+	blob, err := parseAzureBlobURI(uri)
+	if err != nil {
+		return ""
+	}
+	return blob.account
+}
+
+// azureBlob is a location of a blob in Azure Blob Storage.
+type azureBlob struct {
+	account, container, name string
+	// query holds a SAS token, if there is one
+	query string
+}
+
+// parseAzureBlobURI accepts both az://<account>/<container>/<blob> and
+// https://<account>.blob.core.windows.net/<container>/<blob>.
+func parseAzureBlobURI(uri string) (*azureBlob, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	blob := &azureBlob{query: u.RawQuery}
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+
+	switch u.Scheme {
+	case "az":
+		blob.account = u.Host
+	case "https":
+		blob.account = strings.TrimSuffix(u.Host, azureBlobHostSuffix)
+	default:
+		return nil, fmt.Errorf("unsupported scheme of Azure Blob Storage URI: %q", u.Scheme)
+	}
+	if len(blob.account) == 0 || len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("expected URI of a blob like az://<account>/<container>/<blob>, got %q", uri)
+	}
+	blob.container, blob.name = parts[0], parts[1]
+	return blob, nil
+}
+
+// url returns the HTTPS URL of the blob, with the SAS token if there is one.
+func (b *azureBlob) url() string {
+	u := url.URL{
+		Scheme:   "https",
+		Host:     b.account + azureBlobHostSuffix,
+		Path:     "/" + b.container + "/" + b.name,
+		RawQuery: b.query,
+	}
+	return u.String()
+}
+
+// NewAzureBlobContainer is used to download the test archive from Azure Blob
+// Storage. With AZURE_STORAGE_KEY in env, the Azure CLI downloads the blob with
+// the account key. Otherwise, it is downloaded by URL, which may contain a SAS token.
+// If sha256 is not empty, the digest of the downloaded archive is verified.
+func NewAzureBlobContainer(uri, image, sha256 string, volumeMount corev1.VolumeMount, command []string, env []corev1.EnvVar) corev1.Container {
+	archivePath := path.Join(volumeMount.MountPath, ArchiveName)
+
+	var accountKey bool
+	for _, e := range env {
+		if e.Name == AzureStorageKeyEnv {
+			accountKey = true
+			break
+		}
+	}
+
+	download := fmt.Sprintf("curl -fsSL --retry 3 %s -o %s", ShellQuote(uri), archivePath)
+	if blob, err := parseAzureBlobURI(uri); err == nil {
+		if accountKey {
+			download = fmt.Sprintf("az storage blob download --auth-mode key --only-show-errors --account-name %s --container-name %s --name %s --file %s",
+				ShellQuote(blob.account), ShellQuote(blob.container), ShellQuote(blob.name), archivePath)
+		} else {
+			download = fmt.Sprintf("curl -fsSL --retry 3 %s -o %s", ShellQuote(blob.url()), archivePath)
+		}
+	}
+
+	return corev1.Container{
+		Name:         "archive-download",
+		Image:        image,
+		Env:          env,
+		Resources:    archiveDownloadResources(),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		Command: append(
+			command,
+			downloadCommand(download, archivePath, sha256, volumeMount.MountPath),
+		),
+	}
+}
@@ -0,0 +1,92 @@
+package containers
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewAzureBlobContainer(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	keyEnv := []corev1.EnvVar{
+		{Name: AzureStorageAccountEnv, Value: "account"},
+		{Name: AzureStorageKeyEnv, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "azure"},
+			Key:                  "key",
+		}}},
+	}
+
+	tests := []struct {
+		name    string
+		uri     string
+		sha256  string
+		env     []corev1.EnvVar
+		command string
+	}{
+		{
+			"SAS",
+			"https://account.blob.core.windows.net/tests/checkout/archive.tar?sv=2021-08-06&sig=abc",
+			"",
+			nil,
+			"curl -fsSL --retry 3 'https://account.blob.core.windows.net/tests/checkout/archive.tar?sv=2021-08-06&sig=abc' -o /test/archive.tar && ls -l /test",
+		},
+		{
+			"Shorthand",
+			"az://account/tests/checkout/archive.tar",
+			"",
+			nil,
+			"curl -fsSL --retry 3 'https://account.blob.core.windows.net/tests/checkout/archive.tar' -o /test/archive.tar && ls -l /test",
+		},
+		{
+			"AccountKey",
+			"az://account/tests/checkout/archive.tar",
+			"",
+			keyEnv,
+			"az storage blob download --auth-mode key --only-show-errors --account-name 'account' --container-name 'tests' --name 'checkout/archive.tar' --file /test/archive.tar && ls -l /test",
+		},
+		{
+			"Quoted",
+			"az://account/tests/it's.tar",
+			"",
+			keyEnv,
+			`az storage blob download --auth-mode key --only-show-errors --account-name 'account' --container-name 'tests' --name 'it'\''s.tar' --file /test/archive.tar && ls -l /test`,
+		},
+		{
+			"Verified",
+			"az://account/tests/checkout/archive.tar",
+			"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			nil,
+			"curl -fsSL --retry 3 'https://account.blob.core.windows.net/tests/checkout/archive.tar' -o /test/archive.tar && " +
+				verifyArchiveCommand("/test/archive.tar", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08") + " && ls -l /test",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			container := NewAzureBlobContainer(test.uri, "mcr.microsoft.com/azure-cli", test.sha256, volumeMount, []string{"sh", "-c"}, test.env)
+
+			if diff := deep.Equal([]string{"sh", "-c", test.command}, container.Command); diff != nil {
+				t.Errorf("NewAzureBlobContainer returned unexpected command, diff: %s", diff)
+			}
+			if diff := deep.Equal(test.env, container.Env); diff != nil {
+				t.Errorf("NewAzureBlobContainer returned unexpected env, diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestIsAzureBlobURI(t *testing.T) {
+	for uri, expected := range map[string]bool{
+		"az://account/tests/archive.tar":                          true,
+		"https://account.blob.core.windows.net/tests/archive.tar": true,
+		"https://bucket.s3.amazonaws.com/archive.tar":             false,
+		"gs://bucket/archive.tar":                                 false,
+	} {
+		if IsAzureBlobURI(uri) != expected {
+			t.Errorf("IsAzureBlobURI(%q) should be %v", uri, expected)
+		}
+	}
+}
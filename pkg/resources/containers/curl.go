@@ -11,7 +11,8 @@ import (
 )
 
 // NewCurlContainer is used to get a template for a new k6 starting curl container.
-func NewCurlContainer(hostnames []string, image string, imagePullPolicy corev1.PullPolicy, command []string, env []corev1.EnvVar) corev1.Container {
+// The urls are base URLs of k6 REST API of the runners.
+func NewCurlContainer(urls []string, insecureSkipVerify bool, image string, imagePullPolicy corev1.PullPolicy, command []string, env []corev1.EnvVar) corev1.Container {
 	req, _ := json.Marshal(
 		statusAPIRequest{
 			Data: statusAPIRequestData{
@@ -23,9 +24,14 @@ func NewCurlContainer(hostnames []string, image string, imagePullPolicy corev1.P
 			},
 		})
 
+	flags := "--retry 3"
+	if insecureSkipVerify {
+		flags += " --insecure"
+	}
+
 	var parts []string
-	for _, hostname := range hostnames {
-		parts = append(parts, fmt.Sprintf("curl --retry 3 -X PATCH -H 'Content-Type: application/json' http://%s:6565/v1/status -d '%s'", hostname, req))
+	for _, url := range urls {
+		parts = append(parts, fmt.Sprintf("curl %s -X PATCH -H 'Content-Type: application/json' %s/v1/status -d '%s'", flags, url, req))
 	}
 
 	return corev1.Container{
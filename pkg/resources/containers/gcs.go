@@ -0,0 +1,42 @@
+package containers
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GoogleCredentialsEnv points to service account JSON for Google Cloud tools.
+const GoogleCredentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// NewGCSContainer is used to download the test archive from a gs:// URI of
+// Google Cloud Storage. With GOOGLE_APPLICATION_CREDENTIALS in env, the
+// service account JSON it points to is used. Otherwise, gsutil relies on
+// workload identity of the pod or accesses a public bucket anonymously.
+// If sha256 is not empty, the digest of the downloaded archive is verified.
+func NewGCSContainer(uri, image, sha256 string, volumeMount corev1.VolumeMount, command []string, env []corev1.EnvVar) corev1.Container {
+	archive := path.Join(volumeMount.MountPath, ArchiveName)
+
+	var auth string
+	for _, e := range env {
+		if e.Name == GoogleCredentialsEnv {
+			auth = fmt.Sprintf("gcloud auth activate-service-account --key-file=\"$%s\" && ", GoogleCredentialsEnv)
+			break
+		}
+	}
+
+	download := fmt.Sprintf("%sgsutil cp %s %s", auth, ShellQuote(uri), archive)
+
+	return corev1.Container{
+		Name:         "archive-download",
+		Image:        image,
+		Env:          env,
+		Resources:    archiveDownloadResources(),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		Command: append(
+			command,
+			downloadCommand(download, archive, sha256, volumeMount.MountPath),
+		),
+	}
+}
@@ -0,0 +1,69 @@
+package containers
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewGCSContainer(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+
+	tests := []struct {
+		name    string
+		uri     string
+		sha256  string
+		env     []corev1.EnvVar
+		command []string
+	}{
+		{
+			"Anonymous",
+			"gs://bucket/tests/archive.tar",
+			"",
+			nil,
+			[]string{"sh", "-c", "gsutil cp 'gs://bucket/tests/archive.tar' /test/archive.tar && ls -l /test"},
+		},
+		{
+			"Authenticated",
+			"gs://bucket/tests/archive.tar",
+			"",
+			[]corev1.EnvVar{{Name: GoogleCredentialsEnv, Value: "/var/secrets/google/key.json"}},
+			[]string{"sh", "-c", `gcloud auth activate-service-account --key-file="$GOOGLE_APPLICATION_CREDENTIALS" && ` +
+				"gsutil cp 'gs://bucket/tests/archive.tar' /test/archive.tar && ls -l /test"},
+		},
+		{
+			"Quoted",
+			"gs://bucket/tests/it's.tar",
+			"",
+			nil,
+			[]string{"sh", "-c", `gsutil cp 'gs://bucket/tests/it'\''s.tar' /test/archive.tar && ls -l /test`},
+		},
+		{
+			"Verified",
+			"gs://bucket/tests/archive.tar",
+			"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			nil,
+			[]string{"sh", "-c", "gsutil cp 'gs://bucket/tests/archive.tar' /test/archive.tar && " +
+				verifyArchiveCommand("/test/archive.tar", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08") + " && ls -l /test"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			container := NewGCSContainer(test.uri, "google/cloud-sdk:alpine", test.sha256, volumeMount, []string{"sh", "-c"}, test.env)
+
+			if diff := deep.Equal(test.command, container.Command); diff != nil {
+				t.Errorf("NewGCSContainer returned unexpected command, diff: %s", diff)
+			}
+			if diff := deep.Equal(test.env, container.Env); diff != nil {
+				t.Errorf("NewGCSContainer returned unexpected env, diff: %s", diff)
+			}
+			if diff := deep.Equal(archiveDownloadResources(), container.Resources); diff != nil {
+				t.Errorf("NewGCSContainer returned unexpected resources, diff: %s", diff)
+			}
+		})
+	}
+}
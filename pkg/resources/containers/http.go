@@ -0,0 +1,56 @@
+package containers
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// HeadersFileEnv points to a file with additional headers, one per line,
+// e.g. a mounted secret with "Authorization: Bearer <token>".
+const HeadersFileEnv = "ARCHIVE_HEADERS_FILE"
+
+// ShellQuote quotes the value for sh, so that it's passed on as is.
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// NewHTTPContainer is used to download the test archive by URL with custom
+// headers. Headers from the file in ARCHIVE_HEADERS_FILE env are read by curl
+// at runtime so that secret values never appear in the command. If sha256 is
+// not empty, the digest of the downloaded archive is verified.
+func NewHTTPContainer(uri, image string, headers map[string]string, sha256 string, volumeMount corev1.VolumeMount, command []string, env []corev1.EnvVar) corev1.Container {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{"curl", downloadRetryFlags, "-L"}
+	for _, name := range names {
+		args = append(args, "-H", ShellQuote(fmt.Sprintf("%s: %s", name, headers[name])))
+	}
+	for _, e := range env {
+		if e.Name == HeadersFileEnv {
+			args = append(args, "-H", fmt.Sprintf(`@"$%s"`, HeadersFileEnv))
+			break
+		}
+	}
+	archive := path.Join(volumeMount.MountPath, ArchiveName)
+	args = append(args, ShellQuote(uri), "-o", archive)
+
+	return corev1.Container{
+		Name:         "archive-download",
+		Image:        image,
+		Env:          env,
+		Resources:    archiveDownloadResources(),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		Command: append(
+			command,
+			downloadCommand(strings.Join(args, " "), archive, sha256, volumeMount.MountPath),
+		),
+	}
+}
@@ -0,0 +1,46 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewHTTPContainer(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	headers := map[string]string{
+		"X-Api-Key":   "public-key",
+		"Accept":      "application/x-tar",
+		"X-Requester": "k6's operator",
+	}
+
+	container := NewHTTPContainer("https://artifacts.example.com/archive.tar", "curl", headers, "", volumeMount, []string{"sh", "-c"}, nil)
+
+	expected := []string{"sh", "-c", "curl --retry 5 --retry-delay 3 --retry-max-time 120 --fail -L " +
+		"-H 'Accept: application/x-tar' -H 'X-Api-Key: public-key' -H 'X-Requester: k6'\\''s operator' " +
+		"'https://artifacts.example.com/archive.tar' -o /test/archive.tar && ls -l /test"}
+	if diff := deep.Equal(expected, container.Command); diff != nil {
+		t.Errorf("NewHTTPContainer returned unexpected command, diff: %s", diff)
+	}
+}
+
+func TestNewHTTPContainerHeadersFile(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	env := []corev1.EnvVar{{Name: HeadersFileEnv, Value: "/var/secrets/archive-headers/headers"}}
+
+	container := NewHTTPContainer("https://artifacts.example.com/archive.tar", "curl", nil, "", volumeMount, []string{"sh", "-c"}, env)
+
+	command := strings.Join(container.Command, " ")
+	if !strings.Contains(command, `-H @"$ARCHIVE_HEADERS_FILE"`) {
+		t.Errorf("NewHTTPContainer should read headers from the file, got: %s", command)
+	}
+	// the secret itself is only mounted, the command refers to it by env
+	if strings.Contains(command, "/var/secrets") {
+		t.Errorf("NewHTTPContainer should not have the path of the secret in the command, got: %s", command)
+	}
+	if diff := deep.Equal(env, container.Env); diff != nil {
+		t.Errorf("NewHTTPContainer returned unexpected env, diff: %s", diff)
+	}
+}
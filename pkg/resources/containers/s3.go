@@ -0,0 +1,110 @@
+package containers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	resource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ArchiveName is the name of the downloaded archive in the volume of the script.
+const ArchiveName = "archive.tar"
+
+// downloadRetryFlags make curl retry on transient errors and fail on HTTP
+// errors instead of saving the error page as the archive.
+const downloadRetryFlags = "--retry 5 --retry-delay 3 --retry-max-time 120 --fail"
+
+// verifyArchiveCommand checks the SHA-256 digest of the archive and prints
+// the expected and actual digests on mismatch.
+func verifyArchiveCommand(archive, sha256 string) string {
+	return fmt.Sprintf(
+		`(echo '%[2]s  %[1]s' | sha256sum -c - || { echo "sha256 mismatch of %[1]s: expected %[2]s, got $(sha256sum %[1]s | cut -d ' ' -f 1)" >&2 ; exit 1 ; })`,
+		archive, sha256)
+}
+
+// downloadCommand chains the download with the optional verification of the
+// archive, so that the container fails if any of them fails.
+func downloadCommand(download, archive, sha256, mountPath string) string {
+	steps := []string{download}
+	if len(sha256) > 0 {
+		steps = append(steps, verifyArchiveCommand(archive, sha256))
+	}
+	steps = append(steps, fmt.Sprintf("ls -l %s", mountPath))
+	return strings.Join(steps, " && ")
+}
+
+// ArchiveDownloadResources are requests and limits of archive download
+// containers: the custom quantities override the defaults one by one.
+func ArchiveDownloadResources(custom corev1.ResourceRequirements) corev1.ResourceRequirements {
+	resources := archiveDownloadResources()
+	for name, quantity := range custom.Requests {
+		resources.Requests[name] = quantity
+		// a default limit must not be below the custom request
+		if _, ok := custom.Limits[name]; !ok {
+			if limit, ok := resources.Limits[name]; ok && limit.Cmp(quantity) < 0 {
+				resources.Limits[name] = quantity
+			}
+		}
+	}
+	for name, quantity := range custom.Limits {
+		resources.Limits[name] = quantity
+	}
+	return resources
+}
+
+// archiveDownloadResources are default requests and limits of archive download containers.
+func archiveDownloadResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(50, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(2097152, resource.BinarySI),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(100, resource.DecimalSI),
+			corev1.ResourceMemory: *resource.NewQuantity(209715200, resource.BinarySI),
+		},
+	}
+}
+
+// NewS3Container is used to download the test archive by URL, e.g. a presigned URL of S3.
+// If sha256 is not empty, the digest of the downloaded archive is verified.
+func NewS3Container(uri, image, sha256 string, volumeMount corev1.VolumeMount, command []string, env []corev1.EnvVar) corev1.Container {
+	archive := path.Join(volumeMount.MountPath, ArchiveName)
+	download := fmt.Sprintf("curl %s -L %s -o %s", downloadRetryFlags, ShellQuote(uri), archive)
+
+	return corev1.Container{
+		Name:         "archive-download",
+		Image:        image,
+		Env:          env,
+		Resources:    archiveDownloadResources(),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		Command: append(
+			command,
+			downloadCommand(download, archive, sha256, volumeMount.MountPath),
+		),
+	}
+}
+
+// NewS3SDKContainer is used to download the test archive from an s3:// URI
+// with the AWS CLI. Credentials come from the pod: with IAM roles for service
+// accounts, AWS_ROLE_ARN and AWS_WEB_IDENTITY_TOKEN_FILE are injected into
+// all containers of the pod with an annotated service account.
+// If sha256 is not empty, the digest of the downloaded archive is verified.
+func NewS3SDKContainer(uri, image, sha256 string, volumeMount corev1.VolumeMount, command []string, env []corev1.EnvVar) corev1.Container {
+	archive := path.Join(volumeMount.MountPath, ArchiveName)
+	download := fmt.Sprintf("aws s3 cp --only-show-errors %s %s", ShellQuote(uri), archive)
+
+	return corev1.Container{
+		Name:         "archive-download",
+		Image:        image,
+		Env:          env,
+		Resources:    archiveDownloadResources(),
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		Command: append(
+			command,
+			downloadCommand(download, archive, sha256, volumeMount.MountPath),
+		),
+	}
+}
@@ -0,0 +1,92 @@
+package containers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNewS3Container(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	uri := "https://bucket.s3.amazonaws.com/archive.tar?X-Amz-Signature=abc"
+	digest := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	container := NewS3Container(uri, "curl", "", volumeMount, []string{"sh", "-c"}, nil)
+	expected := []string{"sh", "-c", "curl --retry 5 --retry-delay 3 --retry-max-time 120 --fail -L " +
+		"'https://bucket.s3.amazonaws.com/archive.tar?X-Amz-Signature=abc' -o /test/archive.tar && ls -l /test"}
+	if diff := deep.Equal(expected, container.Command); diff != nil {
+		t.Errorf("NewS3Container returned unexpected command, diff: %s", diff)
+	}
+	if strings.Contains(strings.Join(container.Command, " "), "sha256sum") {
+		t.Errorf("NewS3Container should not verify the archive without a digest, got: %v", container.Command)
+	}
+
+	// the URI can't break out of the quotes
+	container = NewS3Container("https://bucket.s3.amazonaws.com/it's.tar", "curl", "", volumeMount, []string{"sh", "-c"}, nil)
+	expected = []string{"sh", "-c", "curl --retry 5 --retry-delay 3 --retry-max-time 120 --fail -L " +
+		`'https://bucket.s3.amazonaws.com/it'\''s.tar' -o /test/archive.tar && ls -l /test`}
+	if diff := deep.Equal(expected, container.Command); diff != nil {
+		t.Errorf("NewS3Container returned unexpected command for quoted URI, diff: %s", diff)
+	}
+
+	container = NewS3Container(uri, "curl", digest, volumeMount, []string{"sh", "-c"}, nil)
+	command := container.Command[len(container.Command)-1]
+	verify := "(echo '" + digest + "  /test/archive.tar' | sha256sum -c - || " +
+		`{ echo "sha256 mismatch of /test/archive.tar: expected ` + digest +
+		`, got $(sha256sum /test/archive.tar | cut -d ' ' -f 1)" >&2 ; exit 1 ; })`
+	if !strings.Contains(command, "-o /test/archive.tar && "+verify+" && ls -l /test") {
+		t.Errorf("NewS3Container should verify the archive after the download, got: %s", command)
+	}
+}
+
+func TestNewS3SDKContainer(t *testing.T) {
+	volumeMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	env := []corev1.EnvVar{{Name: "AWS_REGION", Value: "eu-west-1"}}
+
+	container := NewS3SDKContainer("s3://bucket/tests/archive.tar", "amazon/aws-cli", "", volumeMount, []string{"sh", "-c"}, env)
+
+	expected := []string{"sh", "-c", "aws s3 cp --only-show-errors 's3://bucket/tests/archive.tar' /test/archive.tar && ls -l /test"}
+	if diff := deep.Equal(expected, container.Command); diff != nil {
+		t.Errorf("NewS3SDKContainer returned unexpected command, diff: %s", diff)
+	}
+	if diff := deep.Equal(env, container.Env); diff != nil {
+		t.Errorf("NewS3SDKContainer returned unexpected env, diff: %s", diff)
+	}
+
+	// the URI can't break out of the quotes
+	container = NewS3SDKContainer("s3://bucket/tests/it's.tar", "amazon/aws-cli", "", volumeMount, []string{"sh", "-c"}, env)
+	expected = []string{"sh", "-c", `aws s3 cp --only-show-errors 's3://bucket/tests/it'\''s.tar' /test/archive.tar && ls -l /test`}
+	if diff := deep.Equal(expected, container.Command); diff != nil {
+		t.Errorf("NewS3SDKContainer returned unexpected command for quoted URI, diff: %s", diff)
+	}
+}
+
+func TestArchiveDownloadResources(t *testing.T) {
+	defaults := archiveDownloadResources()
+	if diff := deep.Equal(defaults, ArchiveDownloadResources(corev1.ResourceRequirements{})); diff != nil {
+		t.Errorf("ArchiveDownloadResources should fall back to defaults, diff: %s", diff)
+	}
+
+	resources := ArchiveDownloadResources(corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	})
+
+	expected := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    defaults.Requests[corev1.ResourceCPU],
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+			// raised to the custom request
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+	}
+	if diff := deep.Equal(expected, resources); diff != nil {
+		t.Errorf("ArchiveDownloadResources returned unexpected resources, diff: %s", diff)
+	}
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/containers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	buildVolumeName   = "k6-build"
+	buildMountPath    = "/k6-build"
+	defaultBuildImage = "grafana/xk6:latest"
+)
+
+// buildHash identifies the k6 binary built with the version and the set of
+// extensions, regardless of their order.
+func buildHash(build *v1alpha1.K6Build) string {
+	extensions := append([]string{}, build.Extensions...)
+	sort.Strings(extensions)
+
+	sum := sha256.Sum256([]byte(build.Version + "\n" + strings.Join(extensions, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// buildBinary returns the path of k6 built for the pod, or an empty string
+// if the pod uses k6 of its image.
+func buildBinary(pod *v1alpha1.Pod) string {
+	if pod.Build == nil {
+		return ""
+	}
+	return path.Join(buildMountPath, buildHash(pod.Build), "k6")
+}
+
+// newBuild returns the init container which builds k6 with the extensions
+// of the pod with xk6, together with the volume it's built into and its
+// mount. Binaries are kept by the hash of the extensions, so that with a
+// cache volume, the same set is built only once. The binary is moved in
+// place once built, so that runners sharing the cache don't see a partial one.
+func newBuild(pod *v1alpha1.Pod) ([]corev1.Container, []corev1.Volume, []corev1.VolumeMount) {
+	build := pod.Build
+	if build == nil {
+		return nil, nil, nil
+	}
+
+	image := defaultBuildImage
+	if len(build.Image) > 0 {
+		image = build.Image
+	}
+
+	volume := corev1.Volume{
+		Name:         buildVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	if len(build.CacheClaimName) > 0 {
+		volume.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: build.CacheClaimName},
+		}
+	}
+	volumeMount := corev1.VolumeMount{Name: buildVolumeName, MountPath: buildMountPath}
+
+	binary := buildBinary(pod)
+	args := []string{"xk6", "build"}
+	if len(build.Version) > 0 {
+		args = append(args, containers.ShellQuote(build.Version))
+	}
+	for _, extension := range build.Extensions {
+		args = append(args, "--with", containers.ShellQuote(extension))
+	}
+	args = append(args, "--output", binary+".$HOSTNAME")
+
+	command := fmt.Sprintf("test -x %[1]s || { mkdir -p %[2]s && %[3]s && mv %[1]s.$HOSTNAME %[1]s ; }",
+		binary, path.Dir(binary), strings.Join(args, " "))
+
+	container := corev1.Container{
+		Name:            "k6-build",
+		Image:           image,
+		ImagePullPolicy: pod.ImagePullPolicy,
+		Command:         []string{"sh", "-c", command},
+		VolumeMounts:    []corev1.VolumeMount{volumeMount},
+		SecurityContext: containerSecurityContext(pod, false),
+	}
+	return []corev1.Container{container}, []corev1.Volume{volume}, []corev1.VolumeMount{volumeMount}
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewBuild(t *testing.T) {
+	pod := &v1alpha1.Pod{Build: &v1alpha1.K6Build{
+		Version:    "v0.45.0",
+		Extensions: []string{"github.com/grafana/xk6-sql@v0.2.1", "github.com/grafana/xk6-kafka"},
+	}}
+
+	containers, volumes, mounts := newBuild(pod)
+	if len(containers) != 1 || len(volumes) != 1 || len(mounts) != 1 {
+		t.Fatalf("newBuild returned %d containers, %d volumes and %d mounts", len(containers), len(volumes), len(mounts))
+	}
+	if containers[0].Image != defaultBuildImage {
+		t.Errorf("newBuild returned unexpected image: %s", containers[0].Image)
+	}
+
+	command := containers[0].Command[2]
+	for _, expected := range []string{
+		"xk6 build 'v0.45.0'",
+		"--with 'github.com/grafana/xk6-sql@v0.2.1'",
+		"--with 'github.com/grafana/xk6-kafka'",
+		"test -x " + buildBinary(pod) + " ||",
+	} {
+		if !strings.Contains(command, expected) {
+			t.Errorf("newBuild command doesn't contain %q: %s", expected, command)
+		}
+	}
+	if volumes[0].EmptyDir == nil {
+		t.Errorf("newBuild returned unexpected volume without cache: %v", volumes[0])
+	}
+
+	// the same set of extensions is the same binary
+	reordered := &v1alpha1.Pod{Build: &v1alpha1.K6Build{
+		Version:    "v0.45.0",
+		Extensions: []string{"github.com/grafana/xk6-kafka", "github.com/grafana/xk6-sql@v0.2.1"},
+	}}
+	if buildBinary(pod) != buildBinary(reordered) {
+		t.Errorf("buildBinary differs by order of extensions: %s and %s", buildBinary(pod), buildBinary(reordered))
+	}
+	other := &v1alpha1.Pod{Build: &v1alpha1.K6Build{Extensions: []string{"github.com/grafana/xk6-kafka"}}}
+	if buildBinary(pod) == buildBinary(other) {
+		t.Errorf("buildBinary is the same for different extensions: %s", buildBinary(pod))
+	}
+
+	pod.Build.CacheClaimName = "xk6-cache"
+	_, volumes, _ = newBuild(pod)
+	if claim := volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != "xk6-cache" {
+		t.Errorf("newBuild returned unexpected volume with cache: %v", volumes[0])
+	}
+}
+
+func TestNewJobsBuild(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 1,
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"},
+			},
+			Runner: v1alpha1.Pod{Build: &v1alpha1.K6Build{Extensions: []string{"github.com/grafana/xk6-sql"}}},
+		},
+	}
+	binary := buildBinary(&k6.Spec.Runner)
+
+	runner, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored: %v", err)
+	}
+	podSpec := runner.Spec.Template.Spec
+	if podSpec.InitContainers[0].Name != "k6-build" {
+		t.Errorf("NewRunnerJob doesn't build k6 first: %v", podSpec.InitContainers)
+	}
+	if command := podSpec.Containers[0].Command; command[0] != binary || command[1] != "run" {
+		t.Errorf("NewRunnerJob doesn't run the built k6: %v", command)
+	}
+	if !hasVolumeMount(podSpec.Containers[0].VolumeMounts, buildVolumeName) {
+		t.Errorf("NewRunnerJob doesn't mount the built k6: %v", podSpec.Containers[0].VolumeMounts)
+	}
+
+	// the initializer inherits the build of runners
+	initializer, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored: %v", err)
+	}
+	podSpec = initializer.Spec.Template.Spec
+	if podSpec.InitContainers[0].Name != "k6-build" {
+		t.Errorf("NewInitializerJob doesn't build k6 first: %v", podSpec.InitContainers)
+	}
+	if command := podSpec.Containers[0].Command[2]; !strings.HasPrefix(command, "export PATH="+buildMountPath) {
+		t.Errorf("NewInitializerJob doesn't use the built k6: %s", command)
+	}
+	if !hasVolumeMount(podSpec.Containers[0].VolumeMounts, buildVolumeName) {
+		t.Errorf("NewInitializerJob doesn't mount the built k6: %v", podSpec.Containers[0].VolumeMounts)
+	}
+}
+
+func hasVolumeMount(mounts []corev1.VolumeMount, name string) bool {
+	for _, mount := range mounts {
+		if mount.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"github.com/grafana/k6-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// Defaults are the operator-wide defaults of test runs, set once on start.
+// The builders apply them to the jobs of each test run, leaving the spec as is.
+var Defaults struct {
+	// NoUsageReport is the default for spec.noUsageReport.
+	NoUsageReport bool
+	// RestrictedSecurityContext makes pods without security contexts of
+	// their own comply with the restricted Pod Security Standard.
+	RestrictedSecurityContext bool
+}
+
+// podSecurityContext returns the security context of the pod, or the one
+// required by the restricted Pod Security Standard if the pod has none.
+func podSecurityContext(pod *v1alpha1.Pod) *corev1.PodSecurityContext {
+	if !Defaults.RestrictedSecurityContext || !equality.Semantic.DeepEqual(pod.SecurityContext, corev1.PodSecurityContext{}) {
+		return &pod.SecurityContext
+	}
+	runAsNonRoot := true
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot:   &runAsNonRoot,
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// containerSecurityContext returns the security context of the containers of
+// the pod, or the one required by the restricted Pod Security Standard if the
+// pod has none. Only the starter can have a read-only root filesystem: k6 may
+// write files.
+func containerSecurityContext(pod *v1alpha1.Pod, readOnlyRootFilesystem bool) *corev1.SecurityContext {
+	if !Defaults.RestrictedSecurityContext || pod.ContainerSecurityContext != nil {
+		return pod.ContainerSecurityContext
+	}
+	allowPrivilegeEscalation := false
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		ReadOnlyRootFilesystem:   &readOnlyRootFilesystem,
+	}
+}
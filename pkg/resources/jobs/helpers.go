@@ -1,19 +1,104 @@
 package jobs
 
 import (
+	"errors"
 	"fmt"
 	"github.com/grafana/k6-operator/pkg/types"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/resources/containers"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func newLabels(name string) map[string]string {
-	return map[string]string{
+// Defaults for the Jobs of initializer and starter: both are expected to be
+// quick so they shouldn't hang around for too long.
+const (
+	initializerActiveDeadlineSeconds   int64 = 600
+	initializerTTLSecondsAfterFinished int32 = 3600
+	starterActiveDeadlineSeconds       int64 = 300
+	starterTTLSecondsAfterFinished     int32 = 600
+
+	// initializerMinTTLSecondsAfterFinished keeps the finished initializer
+	// around long enough for the operator to read its result.
+	initializerMinTTLSecondsAfterFinished int32 = 60
+)
+
+// orDefault64 returns the configured value or the default one if nothing was configured.
+func orDefault64(configured *int64, def int64) *int64 {
+	if configured != nil {
+		return configured
+	}
+	return &def
+}
+
+// orDefault32 returns the configured value or the default one if nothing was configured.
+func orDefault32(configured *int32, def int32) *int32 {
+	if configured != nil {
+		return configured
+	}
+	return &def
+}
+
+// reservedLabels are set by the operator only, even if a resource doesn't
+// have some of them, e.g. before the run ID is known: the operator selects its
+// resources by them.
+var reservedLabels = map[string]bool{
+	"app":       true,
+	"k6_cr":     true,
+	"runner":    true,
+	"k6_cr_uid": true,
+	"k6_run_id": true,
+}
+
+// newMetadata returns labels and annotations of resources of the pod: the
+// labels of the operator take precedence over the ones of the pod, which in
+// turn take precedence over the ones set for all resources of the test run.
+// Reserved labels of the spec are ignored.
+func newMetadata(k6 *v1alpha1.K6, labels map[string]string, pod *v1alpha1.Pod) (map[string]string, map[string]string) {
+	annotations := make(map[string]string)
+	for _, metadata := range []v1alpha1.PodMetadata{pod.Metadata, k6.Spec.Metadata} {
+		for k, v := range metadata.Labels { // Order not specified
+			if _, ok := labels[k]; !ok && !reservedLabels[k] {
+				labels[k] = v
+			}
+		}
+		for k, v := range metadata.Annotations {
+			if _, ok := annotations[k]; !ok {
+				annotations[k] = v
+			}
+		}
+	}
+	return labels, annotations
+}
+
+// NewLabels returns the labels set on all resources created for the K6
+// resource. The operator selects its resources by these labels only, while
+// the UID label keeps selectors from matching anything that just happens to
+// share the other labels.
+func NewLabels(k6 *v1alpha1.K6) map[string]string {
+	labels := map[string]string{
 		"app":   "k6",
-		"k6_cr": name,
+		"k6_cr": k6.Name,
 	}
+	if len(k6.UID) > 0 {
+		labels["k6_cr_uid"] = string(k6.UID)
+	}
+	if len(k6.Status.RunID) > 0 {
+		labels["k6_run_id"] = k6.Status.RunID
+	}
+	return labels
+}
+
+// NewRunnerLabels returns the labels set on runner jobs, pods and services.
+func NewRunnerLabels(k6 *v1alpha1.K6) map[string]string {
+	labels := NewLabels(k6)
+	labels["runner"] = "true"
+	return labels
 }
 
 func newIstioCommand(istioEnabled string, inheritedCommands []string) ([]string, bool) {
@@ -117,8 +202,28 @@ func newIstioEnvVar(istio v1alpha1.K6Scuttle, istioEnabled bool) []corev1.EnvVar
 	return env
 }
 
-func getInitContainers(k6Spec *v1alpha1.K6Spec, script *types.Script) []corev1.Container {
-	var initContainers []corev1.Container
+// scriptVolumeMounts returns the mounts of the script in the k6 container. A
+// downloaded archive is mounted read-only: only the init container downloading
+// it writes there.
+func scriptVolumeMounts(script *types.Script) []corev1.VolumeMount {
+	mounts := script.VolumeMount()
+	if script.Type == "ArchiveDownload" {
+		for i := range mounts {
+			mounts[i].ReadOnly = true
+		}
+	}
+	return mounts
+}
+
+func getInitContainers(k6Spec *v1alpha1.K6Spec, pod *v1alpha1.Pod, script *types.Script) []corev1.Container {
+	// the archive and k6 must be in place before any of the user's init containers run
+	archiveContainers, _ := newArchiveDownload(k6Spec, script)
+	for i := range archiveContainers {
+		archiveContainers[i].VolumeMounts = append(archiveContainers[i].VolumeMounts, pod.VolumeMounts...)
+		archiveContainers[i].SecurityContext = containerSecurityContext(pod, false)
+	}
+	initContainers, _, _ := newBuild(pod)
+	initContainers = append(initContainers, archiveContainers...)
 
 	for i, k6InitContainer := range k6Spec.Runner.InitContainers {
 		initContainer := corev1.Container{
@@ -137,3 +242,496 @@ func getInitContainers(k6Spec *v1alpha1.K6Spec, script *types.Script) []corev1.C
 
 	return initContainers
 }
+
+const (
+	archiveCredentialsVolumeName = "k6-archive-credentials"
+	archiveCredentialsMountPath  = "/var/secrets/google"
+	archiveHeadersVolumeName     = "k6-archive-headers"
+	archiveHeadersMountPath      = "/var/secrets/archive-headers"
+
+	defaultGCSImage   = "google/cloud-sdk:alpine"
+	defaultAzureImage = "mcr.microsoft.com/azure-cli"
+	defaultS3Image    = "ghcr.io/grafana/operator:latest-starter"
+	defaultS3SDKImage = "amazon/aws-cli"
+)
+
+// awsEnv returns the AWS_ variables set for the runner, like AWS_REGION,
+// so that the AWS CLI downloading the archive uses the same configuration.
+func awsEnv(env []corev1.EnvVar) []corev1.EnvVar {
+	var aws []corev1.EnvVar
+	for _, e := range env {
+		if strings.HasPrefix(e.Name, "AWS_") {
+			aws = append(aws, e)
+		}
+	}
+	return aws
+}
+
+// newArchiveDownload returns the init container downloading the test archive
+// and the volumes it needs apart from the one of the script. The kind of
+// download is picked by the scheme of the archive's URI.
+func newArchiveDownload(k6Spec *v1alpha1.K6Spec, script *types.Script) ([]corev1.Container, []corev1.Volume) {
+	download := k6Spec.Script.ArchiveDownload
+	if script.Type != "ArchiveDownload" || download == nil {
+		return nil, nil
+	}
+
+	var (
+		volumeMount = script.VolumeMount()[0]
+		command     = []string{"sh", "-c"}
+		container   corev1.Container
+		volumes     []corev1.Volume
+	)
+
+	u, err := url.Parse(download.URI)
+	if err != nil {
+		u = &url.URL{}
+	}
+
+	if u.Scheme == "gs" {
+		image := defaultGCSImage
+		if download.Image != "" {
+			image = download.Image
+		}
+
+		var env []corev1.EnvVar
+		if creds := download.CredentialsSecret; creds != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  containers.GoogleCredentialsEnv,
+				Value: path.Join(archiveCredentialsMountPath, creds.Key),
+			})
+			volumes = append(volumes, corev1.Volume{
+				Name: archiveCredentialsVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: creds.Name,
+						Items:      []corev1.KeyToPath{{Key: creds.Key, Path: creds.Key}},
+					},
+				},
+			})
+		}
+
+		container = containers.NewGCSContainer(download.URI, image, download.SHA256, volumeMount, command, env)
+		if len(volumes) > 0 {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      archiveCredentialsVolumeName,
+				MountPath: archiveCredentialsMountPath,
+				ReadOnly:  true,
+			})
+		}
+	} else if containers.IsAzureBlobURI(download.URI) {
+		image := defaultAzureImage
+		if download.Image != "" {
+			image = download.Image
+		}
+
+		var env []corev1.EnvVar
+		if creds := download.CredentialsSecret; creds != nil {
+			if account := containers.AzureStorageAccount(download.URI); len(account) > 0 {
+				env = append(env, corev1.EnvVar{Name: containers.AzureStorageAccountEnv, Value: account})
+			}
+			env = append(env, corev1.EnvVar{
+				Name:      containers.AzureStorageKeyEnv,
+				ValueFrom: &corev1.EnvVarSource{SecretKeyRef: creds},
+			})
+		}
+
+		container = containers.NewAzureBlobContainer(download.URI, image, download.SHA256, volumeMount, command, env)
+	} else if download.Mode == v1alpha1.ArchiveDownloadSDK || (download.Mode == "" && u.Scheme == "s3") {
+		image := defaultS3SDKImage
+		if download.Image != "" {
+			image = download.Image
+		}
+		container = containers.NewS3SDKContainer(download.URI, image, download.SHA256, volumeMount, command, awsEnv(k6Spec.Runner.Env))
+	} else if len(download.Headers) > 0 || download.HeaderSecretRef != nil {
+		image := defaultS3Image
+		if download.Image != "" {
+			image = download.Image
+		}
+
+		var env []corev1.EnvVar
+		if ref := download.HeaderSecretRef; ref != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  containers.HeadersFileEnv,
+				Value: path.Join(archiveHeadersMountPath, ref.Key),
+			})
+			volumes = append(volumes, corev1.Volume{
+				Name: archiveHeadersVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: ref.Name,
+						Items:      []corev1.KeyToPath{{Key: ref.Key, Path: ref.Key}},
+					},
+				},
+			})
+		}
+
+		container = containers.NewHTTPContainer(download.URI, image, download.Headers, download.SHA256, volumeMount, command, env)
+		if len(volumes) > 0 {
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      archiveHeadersVolumeName,
+				MountPath: archiveHeadersMountPath,
+				ReadOnly:  true,
+			})
+		}
+	} else {
+		image := defaultS3Image
+		if download.Image != "" {
+			image = download.Image
+		}
+		container = containers.NewS3Container(download.URI, image, download.SHA256, volumeMount, command, nil)
+	}
+	container.ImagePullPolicy = k6Spec.Runner.ImagePullPolicy
+	container.Resources = containers.ArchiveDownloadResources(k6Spec.Runner.InitContainerResources)
+
+	return []corev1.Container{container}, volumes
+}
+
+const (
+	configFileVolumeName = "k6-config-volume"
+	configFileMountPath  = "/etc/k6"
+)
+
+// newConfigFile returns the volume, volume mount and k6 arguments needed to
+// use the configured k6 config file. All of them are empty if there is no config file.
+func newConfigFile(configFile *v1alpha1.K6ConfigFile) ([]corev1.Volume, []corev1.VolumeMount, []string, error) {
+	if configFile == nil {
+		return nil, nil, nil, nil
+	}
+
+	if (configFile.ConfigMap == "") == (configFile.Secret == "") {
+		return nil, nil, nil, errors.New("config file should reference exactly one of: ConfigMap, Secret")
+	}
+
+	file := "config.json"
+	if configFile.File != "" {
+		file = configFile.File
+	}
+
+	volume := corev1.Volume{Name: configFileVolumeName}
+	if configFile.ConfigMap != "" {
+		volume.VolumeSource = corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: configFile.ConfigMap,
+				},
+			},
+		}
+	} else {
+		volume.VolumeSource = corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: configFile.Secret,
+			},
+		}
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      configFileVolumeName,
+		MountPath: configFileMountPath,
+		ReadOnly:  true,
+	}
+
+	return []corev1.Volume{volume}, []corev1.VolumeMount{mount}, []string{"--config", path.Join(configFileMountPath, file)}, nil
+}
+
+const (
+	secretsSourceVolumeName = "k6-secrets-source"
+	secretsSourceMountPath  = "/k6-secrets-source"
+	secretsVolumeName       = "k6-secrets"
+	secretsMountPath        = "/k6-secrets"
+)
+
+// newSecrets returns the volumes, the init container, the volume mount and
+// k6 arguments needed to pass the entries of the Secret to k6. Each entry
+// of the Secret is a separate file once mounted so the init container gathers
+// them into a single file of k6 format, kept in memory only.
+func newSecrets(secrets *v1alpha1.K6Secrets, image string, imagePullPolicy corev1.PullPolicy) (
+	[]corev1.Volume, []corev1.Container, []corev1.VolumeMount, []string) {
+	if secrets == nil {
+		return nil, nil, nil, nil
+	}
+
+	volumes := []corev1.Volume{
+		{
+			Name: secretsSourceVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: secrets.Name,
+				},
+			},
+		},
+		{
+			Name: secretsVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium: corev1.StorageMediumMemory,
+				},
+			},
+		},
+	}
+
+	secretsFile := path.Join(secretsMountPath, "secrets")
+	initContainer := corev1.Container{
+		Name:            "k6-secrets",
+		Image:           image,
+		ImagePullPolicy: imagePullPolicy,
+		Command: []string{"sh", "-c", fmt.Sprintf(
+			`for f in %s/*; do printf '%%s=%%s\n' "$(basename "$f")" "$(cat "$f")"; done > %s`,
+			secretsSourceMountPath, secretsFile)},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      secretsSourceVolumeName,
+				MountPath: secretsSourceMountPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      secretsVolumeName,
+				MountPath: secretsMountPath,
+			},
+		},
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      secretsVolumeName,
+		MountPath: secretsMountPath,
+		ReadOnly:  true,
+	}
+
+	return volumes, []corev1.Container{initContainer}, []corev1.VolumeMount{mount}, []string{"--secret-source=file=" + secretsFile}
+}
+
+// newScheduling returns node selector, tolerations and affinity for the pod
+// of initializer or starter. Unless disabled, those not set for the pod are
+// taken from the runners, so that the whole test run stays within the same
+// nodes. Only node affinity is taken from runners' affinity as their
+// pod (anti-)affinity is meant for runner pods.
+func newScheduling(k6 *v1alpha1.K6, pod *v1alpha1.Pod) (map[string]string, []corev1.Toleration, *corev1.Affinity) {
+	nodeSelector, tolerations, affinity := pod.NodeSelector, pod.Tolerations, pod.Affinity
+
+	inherit := true
+	if k6.Spec.InheritRunnerScheduling != "" {
+		inherit, _ = strconv.ParseBool(k6.Spec.InheritRunnerScheduling)
+	}
+	if !inherit {
+		return nodeSelector, tolerations, affinity
+	}
+
+	runner := &k6.Spec.Runner
+	if len(nodeSelector) == 0 {
+		nodeSelector = runner.NodeSelector
+	}
+	if len(tolerations) == 0 {
+		tolerations = runner.Tolerations
+	}
+	if affinity == nil && runner.Affinity != nil && runner.Affinity.NodeAffinity != nil {
+		affinity = &corev1.Affinity{
+			NodeAffinity: runner.Affinity.NodeAffinity.DeepCopy(),
+		}
+	}
+	return nodeSelector, tolerations, affinity
+}
+
+// newImagePullSecrets returns the image pull secrets of the pod followed by
+// the ones set for all pods of the test run, without duplicates.
+func newImagePullSecrets(k6 *v1alpha1.K6, pod *v1alpha1.Pod) []corev1.LocalObjectReference {
+	if len(k6.Spec.ImagePullSecrets) == 0 {
+		return pod.ImagePullSecrets
+	}
+
+	var secrets []corev1.LocalObjectReference
+	seen := map[string]bool{}
+	for _, secret := range append(append([]corev1.LocalObjectReference{}, pod.ImagePullSecrets...), k6.Spec.ImagePullSecrets...) {
+		if !seen[secret.Name] {
+			seen[secret.Name] = true
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// newUsageReportEnvVar returns the env var disabling k6 usage report, if
+// requested in the spec or by default.
+func newUsageReportEnvVar(k6 *v1alpha1.K6) []corev1.EnvVar {
+	disabled := Defaults.NoUsageReport
+	if len(k6.Spec.NoUsageReport) > 0 {
+		disabled, _ = strconv.ParseBool(k6.Spec.NoUsageReport)
+	}
+	if !disabled {
+		return nil
+	}
+	return []corev1.EnvVar{{
+		Name:  "K6_NO_USAGE_REPORT",
+		Value: "true",
+	}}
+}
+
+const (
+	outputVolumeName      = "k6-output-volume"
+	outputVolumeMountPath = "/output"
+)
+
+// OutputSubPath returns the sub-path of the output volume used by the runner.
+func OutputSubPath(index int) string {
+	return fmt.Sprintf("runner-%d", index)
+}
+
+// newOutputVolume returns the volume and the volume mount for the runner.
+// Each runner writes into its own sub-path so that the files of different
+// runners never collide.
+func newOutputVolume(output *v1alpha1.OutputVolume, index int) ([]corev1.Volume, []corev1.VolumeMount) {
+	if output == nil {
+		return nil, nil
+	}
+
+	mountPath := outputVolumeMountPath
+	if len(output.MountPath) > 0 {
+		mountPath = output.MountPath
+	}
+
+	return []corev1.Volume{{
+		Name: outputVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: output.ClaimName,
+			},
+		},
+	}}, []corev1.VolumeMount{{
+		Name:      outputVolumeName,
+		MountPath: mountPath,
+		SubPath:   OutputSubPath(index),
+	}}
+}
+
+func concatVolumes(volumes []corev1.Volume, more ...[]corev1.Volume) []corev1.Volume {
+	for _, v := range more {
+		volumes = append(volumes, v...)
+	}
+	return volumes
+}
+
+func concatVolumeMounts(mounts []corev1.VolumeMount, more ...[]corev1.VolumeMount) []corev1.VolumeMount {
+	for _, m := range more {
+		mounts = append(mounts, m...)
+	}
+	return mounts
+}
+
+// newTraceContextEnvVars returns env vars with the trace header for the runner.
+func newTraceContextEnvVars(k6 *v1alpha1.K6, index int) []corev1.EnvVar {
+	traceContext := k6.Spec.Runner.TraceContext
+	if traceContext == nil {
+		return nil
+	}
+
+	value := strings.NewReplacer(
+		"{index}", strconv.Itoa(index),
+		"{name}", k6.Name,
+	).Replace(traceContext.Value)
+
+	return []corev1.EnvVar{
+		{
+			Name:  "K6_TRACE_HEADER_NAME",
+			Value: traceContext.Header,
+		},
+		{
+			Name:  "K6_TRACE_HEADER_VALUE",
+			Value: value,
+		},
+	}
+}
+
+// newOutputEnvVars translates tuning of outputs into k6 options. Only what is
+// set in the spec is passed on, k6 defaults hold for the rest.
+func newOutputEnvVars(output *v1alpha1.Output) []corev1.EnvVar {
+	if output == nil {
+		return nil
+	}
+
+	var env []corev1.EnvVar
+	add := func(name, value string) {
+		if len(value) > 0 {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	duration := func(d *metav1.Duration) string {
+		if d == nil {
+			return ""
+		}
+		return d.Duration.String()
+	}
+	count := func(n int32) string {
+		if n <= 0 {
+			return ""
+		}
+		return strconv.Itoa(int(n))
+	}
+
+	if influxdb := output.InfluxDB; influxdb != nil {
+		add("K6_INFLUXDB_PUSH_INTERVAL", duration(influxdb.PushInterval))
+		add("K6_INFLUXDB_CONCURRENT_WRITES", count(influxdb.ConcurrentWrites))
+		add("K6_INFLUXDB_PAYLOAD_SIZE", count(influxdb.PayloadSize))
+		if influxdb.Version == "v2" {
+			add("K6_INFLUXDB_ORGANIZATION", influxdb.Organization)
+			add("K6_INFLUXDB_BUCKET", influxdb.Database)
+			if influxdb.TokenSecret != nil {
+				env = append(env, newSecretEnvVar("K6_INFLUXDB_TOKEN", influxdb.TokenSecret))
+			}
+		} else if auth := influxdb.BasicAuth; auth != nil {
+			add("K6_INFLUXDB_USERNAME", auth.Username)
+			env = append(env, newSecretEnvVar("K6_INFLUXDB_PASSWORD", &auth.PasswordSecret))
+		}
+	}
+	if prw := output.PrometheusRemoteWrite; prw != nil {
+		add("K6_PROMETHEUS_RW_PUSH_INTERVAL", duration(prw.PushInterval))
+		add("K6_PROMETHEUS_RW_SERVER_URL", prw.URL)
+		if auth := prw.BasicAuth; auth != nil {
+			add("K6_PROMETHEUS_RW_USERNAME", auth.Username)
+			env = append(env, newSecretEnvVar("K6_PROMETHEUS_RW_PASSWORD", &auth.PasswordSecret))
+		}
+		if prw.BearerTokenSecret != nil {
+			env = append(env, newSecretEnvVar("K6_PROMETHEUS_RW_BEARER_TOKEN", prw.BearerTokenSecret))
+		}
+	}
+	if statsd := output.Statsd; statsd != nil {
+		add("K6_STATSD_PUSH_INTERVAL", duration(statsd.PushInterval))
+		add("K6_STATSD_BUFFER_SIZE", count(statsd.BufferSize))
+	}
+
+	return env
+}
+
+func newSecretEnvVar(name string, secret *corev1.SecretKeySelector) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name:      name,
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secret},
+	}
+}
+
+// newOutputArgs enables the outputs configured with their address in the
+// spec, unless they're already enabled in arguments, as parsed by types.ParseCLI.
+func newOutputArgs(output *v1alpha1.Output, cli *types.CLI) []string {
+	if output == nil {
+		return nil
+	}
+
+	var args []string
+	if influxdb := output.InfluxDB; influxdb != nil && len(influxdb.Address) > 0 {
+		// InfluxDB v1 takes the database in the URL, while v2 takes the
+		// bucket from environment
+		if influxdb.Version == "v2" {
+			if !cli.HasOutput("xk6-influxdb") {
+				args = append(args, "--out", "xk6-influxdb="+influxdb.Address)
+			}
+		} else if !cli.HasOutput("influxdb") {
+			address := influxdb.Address
+			if len(influxdb.Database) > 0 {
+				address = strings.TrimSuffix(address, "/") + "/" + influxdb.Database
+			}
+			args = append(args, "--out", "influxdb="+address)
+		}
+	}
+	if prw := output.PrometheusRemoteWrite; prw != nil && len(prw.URL) > 0 && !cli.HasOutput("experimental-prometheus-rw") {
+		args = append(args, "--out", "experimental-prometheus-rw")
+	}
+	return args
+}
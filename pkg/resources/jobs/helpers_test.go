@@ -3,10 +3,14 @@ package jobs
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 	"github.com/grafana/k6-operator/api/v1alpha1"
+	"github.com/grafana/k6-operator/pkg/types"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestNewLabels(t *testing.T) {
@@ -15,7 +19,37 @@ func TestNewLabels(t *testing.T) {
 		"app":   "k6",
 		"k6_cr": "test",
 	}
-	labels := newLabels("test")
+	labels := NewLabels(&v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test"}})
+	if !reflect.DeepEqual(labels, expectedOutcome) {
+		t.Errorf("new labels were incorrect, got: %v, want: %v.", labels, expectedOutcome)
+	}
+}
+
+func TestNewLabelsWithUID(t *testing.T) {
+
+	expectedOutcome := map[string]string{
+		"app":       "k6",
+		"k6_cr":     "test",
+		"k6_cr_uid": "1a2b3c",
+		"runner":    "true",
+	}
+	labels := NewRunnerLabels(&v1alpha1.K6{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "1a2b3c"}})
+	if !reflect.DeepEqual(labels, expectedOutcome) {
+		t.Errorf("new labels were incorrect, got: %v, want: %v.", labels, expectedOutcome)
+	}
+}
+
+func TestNewLabelsWithRunID(t *testing.T) {
+
+	expectedOutcome := map[string]string{
+		"app":       "k6",
+		"k6_cr":     "test",
+		"k6_run_id": "4d5e6f",
+	}
+	labels := NewLabels(&v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Status:     v1alpha1.K6Status{RunID: "4d5e6f"},
+	})
 	if !reflect.DeepEqual(labels, expectedOutcome) {
 		t.Errorf("new labels were incorrect, got: %v, want: %v.", labels, expectedOutcome)
 	}
@@ -153,3 +187,549 @@ func TestNewIstioEnvVarFalseValues(t *testing.T) {
 		t.Errorf("new envVars were incorrect, got: %v, want: %v.", envVars, expectedOutcome)
 	}
 }
+
+func TestNewConfigFile(t *testing.T) {
+	volumes, mounts, args, err := newConfigFile(&v1alpha1.K6ConfigFile{
+		Secret: "k6-defaults",
+		File:   "defaults.json",
+	})
+	if err != nil {
+		t.Fatalf("newConfigFile errored, got: %v", err)
+	}
+
+	expectedVolumes := []corev1.Volume{{
+		Name: "k6-config-volume",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: "k6-defaults",
+			},
+		},
+	}}
+	if diff := deep.Equal(expectedVolumes, volumes); diff != nil {
+		t.Errorf("newConfigFile returned unexpected volumes, diff: %s", diff)
+	}
+
+	expectedMounts := []corev1.VolumeMount{{
+		Name:      "k6-config-volume",
+		MountPath: "/etc/k6",
+		ReadOnly:  true,
+	}}
+	if diff := deep.Equal(expectedMounts, mounts); diff != nil {
+		t.Errorf("newConfigFile returned unexpected volume mounts, diff: %s", diff)
+	}
+
+	if diff := deep.Equal([]string{"--config", "/etc/k6/defaults.json"}, args); diff != nil {
+		t.Errorf("newConfigFile returned unexpected args, diff: %s", diff)
+	}
+}
+
+func TestNewConfigFileInvalid(t *testing.T) {
+	for _, configFile := range []*v1alpha1.K6ConfigFile{
+		{},
+		{ConfigMap: "a", Secret: "b"},
+	} {
+		if _, _, _, err := newConfigFile(configFile); err == nil {
+			t.Errorf("newConfigFile should have errored for %+v", configFile)
+		}
+	}
+}
+
+func TestNewScheduling(t *testing.T) {
+	runnerAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchExpressions: []corev1.NodeSelectorRequirement{{
+						Key:      "pool",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"loadtest"},
+					}},
+				}},
+			},
+		},
+		PodAntiAffinity: newAntiAffinity().PodAntiAffinity,
+	}
+	runnerTolerations := []corev1.Toleration{{Key: "loadtest", Operator: corev1.TolerationOpExists}}
+	starterNodeSelector := map[string]string{"pool": "starters"}
+
+	k6 := &v1alpha1.K6{
+		Spec: v1alpha1.K6Spec{
+			Runner: v1alpha1.Pod{
+				Affinity:     runnerAffinity,
+				NodeSelector: map[string]string{"pool": "loadtest"},
+				Tolerations:  runnerTolerations,
+			},
+			Starter: v1alpha1.Pod{
+				NodeSelector: starterNodeSelector,
+			},
+		},
+	}
+
+	nodeSelector, tolerations, affinity := newScheduling(k6, &k6.Spec.Starter)
+	if diff := deep.Equal(starterNodeSelector, nodeSelector); diff != nil {
+		t.Errorf("newScheduling should keep own node selector, diff: %s", diff)
+	}
+	if diff := deep.Equal(runnerTolerations, tolerations); diff != nil {
+		t.Errorf("newScheduling should inherit tolerations, diff: %s", diff)
+	}
+	if diff := deep.Equal(&corev1.Affinity{NodeAffinity: runnerAffinity.NodeAffinity}, affinity); diff != nil {
+		t.Errorf("newScheduling should inherit node affinity only, diff: %s", diff)
+	}
+
+	k6.Spec.InheritRunnerScheduling = "false"
+	nodeSelector, tolerations, affinity = newScheduling(k6, &k6.Spec.Starter)
+	if diff := deep.Equal(starterNodeSelector, nodeSelector); diff != nil || tolerations != nil || affinity != nil {
+		t.Errorf("newScheduling should not inherit when disabled, got: %v %v %v", nodeSelector, tolerations, affinity)
+	}
+}
+
+func TestNewTraceContextEnvVars(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout"},
+		Spec: v1alpha1.K6Spec{
+			Runner: v1alpha1.Pod{
+				TraceContext: &v1alpha1.TraceContext{
+					Header: "baggage",
+					Value:  "loadtest={name},runner={index}",
+				},
+			},
+		},
+	}
+
+	expectedOutcome := []corev1.EnvVar{
+		{Name: "K6_TRACE_HEADER_NAME", Value: "baggage"},
+		{Name: "K6_TRACE_HEADER_VALUE", Value: "loadtest=checkout,runner=3"},
+	}
+	if diff := deep.Equal(expectedOutcome, newTraceContextEnvVars(k6, 3)); diff != nil {
+		t.Errorf("newTraceContextEnvVars returned unexpected data, diff: %s", diff)
+	}
+
+	k6.Spec.Runner.TraceContext = nil
+	if env := newTraceContextEnvVars(k6, 3); env != nil {
+		t.Errorf("newTraceContextEnvVars should return nothing without trace context, got: %v", env)
+	}
+}
+
+func TestNewOutputEnvVars(t *testing.T) {
+	output := &v1alpha1.Output{
+		InfluxDB: &v1alpha1.InfluxDBOutput{
+			PushInterval:     &metav1.Duration{Duration: 5 * time.Second},
+			ConcurrentWrites: 4,
+		},
+		PrometheusRemoteWrite: &v1alpha1.PrometheusRemoteWriteOutput{
+			PushInterval: &metav1.Duration{Duration: 30 * time.Second},
+		},
+		Statsd: &v1alpha1.StatsdOutput{
+			BufferSize: 200,
+		},
+	}
+
+	expectedOutcome := []corev1.EnvVar{
+		{Name: "K6_INFLUXDB_PUSH_INTERVAL", Value: "5s"},
+		{Name: "K6_INFLUXDB_CONCURRENT_WRITES", Value: "4"},
+		{Name: "K6_PROMETHEUS_RW_PUSH_INTERVAL", Value: "30s"},
+		{Name: "K6_STATSD_BUFFER_SIZE", Value: "200"},
+	}
+	if diff := deep.Equal(expectedOutcome, newOutputEnvVars(output)); diff != nil {
+		t.Errorf("newOutputEnvVars returned unexpected data, diff: %s", diff)
+	}
+
+	if env := newOutputEnvVars(nil); env != nil {
+		t.Errorf("newOutputEnvVars should return nothing without output, got: %v", env)
+	}
+}
+
+func TestNewOutputEnvVarsPrometheusRemoteWrite(t *testing.T) {
+	password := corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "prometheus"}, Key: "password"}
+	token := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "prometheus"}, Key: "token"}
+
+	output := &v1alpha1.Output{PrometheusRemoteWrite: &v1alpha1.PrometheusRemoteWriteOutput{
+		URL:       "http://prometheus:9090/api/v1/write",
+		BasicAuth: &v1alpha1.BasicAuth{Username: "k6", PasswordSecret: password},
+	}}
+	expectedOutcome := []corev1.EnvVar{
+		{Name: "K6_PROMETHEUS_RW_SERVER_URL", Value: "http://prometheus:9090/api/v1/write"},
+		{Name: "K6_PROMETHEUS_RW_USERNAME", Value: "k6"},
+		{Name: "K6_PROMETHEUS_RW_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &password}},
+	}
+	if diff := deep.Equal(expectedOutcome, newOutputEnvVars(output)); diff != nil {
+		t.Errorf("newOutputEnvVars returned unexpected data for basic auth, diff: %s", diff)
+	}
+
+	output.PrometheusRemoteWrite.BasicAuth = nil
+	output.PrometheusRemoteWrite.BearerTokenSecret = token
+	expectedOutcome = []corev1.EnvVar{
+		{Name: "K6_PROMETHEUS_RW_SERVER_URL", Value: "http://prometheus:9090/api/v1/write"},
+		{Name: "K6_PROMETHEUS_RW_BEARER_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: token}},
+	}
+	if diff := deep.Equal(expectedOutcome, newOutputEnvVars(output)); diff != nil {
+		t.Errorf("newOutputEnvVars returned unexpected data for bearer token, diff: %s", diff)
+	}
+}
+
+func TestNewOutputEnvVarsInfluxDB(t *testing.T) {
+	password := corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "influxdb"}, Key: "password"}
+	token := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "influxdb"}, Key: "token"}
+
+	output := &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{
+		Address:   "http://influxdb:8086",
+		Database:  "k6",
+		BasicAuth: &v1alpha1.BasicAuth{Username: "k6", PasswordSecret: password},
+	}}
+	expectedOutcome := []corev1.EnvVar{
+		{Name: "K6_INFLUXDB_USERNAME", Value: "k6"},
+		{Name: "K6_INFLUXDB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &password}},
+	}
+	if diff := deep.Equal(expectedOutcome, newOutputEnvVars(output)); diff != nil {
+		t.Errorf("newOutputEnvVars returned unexpected data for InfluxDB v1, diff: %s", diff)
+	}
+
+	output.InfluxDB = &v1alpha1.InfluxDBOutput{
+		Address:      "http://influxdb:8086",
+		Database:     "k6",
+		Version:      "v2",
+		Organization: "qa",
+		TokenSecret:  token,
+	}
+	expectedOutcome = []corev1.EnvVar{
+		{Name: "K6_INFLUXDB_ORGANIZATION", Value: "qa"},
+		{Name: "K6_INFLUXDB_BUCKET", Value: "k6"},
+		{Name: "K6_INFLUXDB_TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: token}},
+	}
+	if diff := deep.Equal(expectedOutcome, newOutputEnvVars(output)); diff != nil {
+		t.Errorf("newOutputEnvVars returned unexpected data for InfluxDB v2, diff: %s", diff)
+	}
+}
+
+func TestNewOutputArgs(t *testing.T) {
+	output := &v1alpha1.Output{PrometheusRemoteWrite: &v1alpha1.PrometheusRemoteWriteOutput{
+		URL: "http://prometheus:9090/api/v1/write",
+	}}
+
+	tests := []struct {
+		name      string
+		output    *v1alpha1.Output
+		arguments string
+		args      []string
+	}{
+		{"NoOutput", nil, "", nil},
+		{"NoURL", &v1alpha1.Output{PrometheusRemoteWrite: &v1alpha1.PrometheusRemoteWriteOutput{}}, "", nil},
+		{"RemoteWrite", output, "--tag env=ci", []string{"--out", "experimental-prometheus-rw"}},
+		{"EnabledInArguments", output, "--out experimental-prometheus-rw", nil},
+		{"EnabledInArgumentsWithEquals", output, "-o=experimental-prometheus-rw", nil},
+		{"InfluxDBv1", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086/", Database: "k6"}}, "",
+			[]string{"--out", "influxdb=http://influxdb:8086/k6"}},
+		{"InfluxDBv2", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086", Database: "k6", Version: "v2"}}, "",
+			[]string{"--out", "xk6-influxdb=http://influxdb:8086"}},
+		{"InfluxDBInArguments", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086"}}, "--out influxdb=http://other:8086/k6", nil},
+		{"InfluxDBInArgumentsWithEquals", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086"}}, "--out=influxdb=http://other:8086/k6", nil},
+		{"InfluxDBv2InArguments", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086", Version: "v2"}}, "-o=xk6-influxdb", nil},
+		// another output with a similar name doesn't count
+		{"InfluxDBv1WithV2InArguments", &v1alpha1.Output{InfluxDB: &v1alpha1.InfluxDBOutput{Address: "http://influxdb:8086"}}, "-o xk6-influxdb",
+			[]string{"--out", "influxdb=http://influxdb:8086"}},
+	}
+
+	for _, test := range tests {
+		if diff := deep.Equal(test.args, newOutputArgs(test.output, types.ParseCLI(&v1alpha1.K6Spec{Arguments: test.arguments}))); diff != nil {
+			t.Errorf("newOutputArgs returned unexpected data for %s, diff: %s", test.name, diff)
+		}
+	}
+}
+
+func TestNewArchiveDownload(t *testing.T) {
+	spec := &v1alpha1.K6Spec{
+		Script: v1alpha1.K6Script{
+			ArchiveDownload: &v1alpha1.ArchiveDownload{
+				URI: "gs://bucket/archive.tar",
+				CredentialsSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "gcs-key"},
+					Key:                  "key.json",
+				},
+			},
+		},
+	}
+	script, err := types.ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript errored, got: %v", err)
+	}
+
+	initContainers, volumes := newArchiveDownload(spec, script)
+	if len(initContainers) != 1 || initContainers[0].Image != defaultGCSImage {
+		t.Fatalf("newArchiveDownload should return a GCS container, got: %v", initContainers)
+	}
+
+	expectedEnv := []corev1.EnvVar{{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: "/var/secrets/google/key.json"}}
+	if diff := deep.Equal(expectedEnv, initContainers[0].Env); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected env, diff: %s", diff)
+	}
+	expectedMounts := []corev1.VolumeMount{
+		{Name: "k6-test-volume", MountPath: "/test"},
+		{Name: "k6-archive-credentials", MountPath: "/var/secrets/google", ReadOnly: true},
+	}
+	if diff := deep.Equal(expectedMounts, initContainers[0].VolumeMounts); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected volume mounts, diff: %s", diff)
+	}
+	if len(volumes) != 1 || volumes[0].Secret == nil || volumes[0].Secret.SecretName != "gcs-key" {
+		t.Errorf("newArchiveDownload should return the credentials volume, got: %v", volumes)
+	}
+
+	spec.Script.ArchiveDownload = &v1alpha1.ArchiveDownload{URI: "https://bucket.s3.amazonaws.com/archive.tar?X-Amz-Signature=abc"}
+	initContainers, volumes = newArchiveDownload(spec, script)
+	if len(initContainers) != 1 || initContainers[0].Image != defaultS3Image || len(volumes) != 0 {
+		t.Errorf("newArchiveDownload should return an S3 container without volumes, got: %v, %v", initContainers, volumes)
+	}
+}
+
+func TestNewArchiveDownloadAzure(t *testing.T) {
+	spec := &v1alpha1.K6Spec{
+		Script: v1alpha1.K6Script{
+			ArchiveDownload: &v1alpha1.ArchiveDownload{
+				URI: "az://account/tests/archive.tar",
+				CredentialsSecret: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "azure"},
+					Key:                  "key",
+				},
+			},
+		},
+	}
+	script, err := types.ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript errored, got: %v", err)
+	}
+
+	initContainers, volumes := newArchiveDownload(spec, script)
+	if len(initContainers) != 1 || initContainers[0].Image != defaultAzureImage || len(volumes) != 0 {
+		t.Fatalf("newArchiveDownload should return an Azure container without volumes, got: %v, %v", initContainers, volumes)
+	}
+
+	expectedEnv := []corev1.EnvVar{
+		{Name: "AZURE_STORAGE_ACCOUNT", Value: "account"},
+		{Name: "AZURE_STORAGE_KEY", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: spec.Script.ArchiveDownload.CredentialsSecret}},
+	}
+	if diff := deep.Equal(expectedEnv, initContainers[0].Env); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected env, diff: %s", diff)
+	}
+}
+
+func TestNewArchiveDownloadHeaders(t *testing.T) {
+	spec := &v1alpha1.K6Spec{
+		Script: v1alpha1.K6Script{
+			ArchiveDownload: &v1alpha1.ArchiveDownload{
+				URI:     "https://artifacts.example.com/archive.tar",
+				Headers: map[string]string{"Accept": "application/x-tar"},
+				HeaderSecretRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "artifacts-token"},
+					Key:                  "headers",
+				},
+			},
+		},
+	}
+	script, err := types.ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript errored, got: %v", err)
+	}
+
+	initContainers, volumes := newArchiveDownload(spec, script)
+	if len(initContainers) != 1 || len(volumes) != 1 || volumes[0].Secret == nil || volumes[0].Secret.SecretName != "artifacts-token" {
+		t.Fatalf("newArchiveDownload should return an HTTP container with the headers volume, got: %v, %v", initContainers, volumes)
+	}
+
+	expectedEnv := []corev1.EnvVar{{Name: "ARCHIVE_HEADERS_FILE", Value: "/var/secrets/archive-headers/headers"}}
+	if diff := deep.Equal(expectedEnv, initContainers[0].Env); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected env, diff: %s", diff)
+	}
+	expectedMounts := []corev1.VolumeMount{
+		{Name: "k6-test-volume", MountPath: "/test"},
+		{Name: "k6-archive-headers", MountPath: "/var/secrets/archive-headers", ReadOnly: true},
+	}
+	if diff := deep.Equal(expectedMounts, initContainers[0].VolumeMounts); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected volume mounts, diff: %s", diff)
+	}
+}
+
+func TestNewArchiveDownloadS3SDK(t *testing.T) {
+	spec := &v1alpha1.K6Spec{
+		Script: v1alpha1.K6Script{
+			ArchiveDownload: &v1alpha1.ArchiveDownload{URI: "s3://bucket/tests/archive.tar"},
+		},
+		Runner: v1alpha1.Pod{
+			ServiceAccountName: "s3-reader",
+			Env: []corev1.EnvVar{
+				{Name: "AWS_REGION", Value: "eu-west-1"},
+				{Name: "K6_NO_USAGE_REPORT", Value: "true"},
+			},
+		},
+	}
+	script, err := types.ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript errored, got: %v", err)
+	}
+
+	initContainers, volumes := newArchiveDownload(spec, script)
+	if len(initContainers) != 1 || initContainers[0].Image != defaultS3SDKImage || len(volumes) != 0 {
+		t.Fatalf("newArchiveDownload should return an AWS CLI container without volumes, got: %v, %v", initContainers, volumes)
+	}
+
+	// only AWS configuration is passed on, credentials come from the service account
+	expectedEnv := []corev1.EnvVar{{Name: "AWS_REGION", Value: "eu-west-1"}}
+	if diff := deep.Equal(expectedEnv, initContainers[0].Env); diff != nil {
+		t.Errorf("newArchiveDownload returned unexpected env, diff: %s", diff)
+	}
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+		Spec:       *spec,
+	}
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	// the IRSA webhook injects the role into containers of pods with the annotated service account
+	if job.Spec.Template.Spec.ServiceAccountName != "s3-reader" {
+		t.Errorf("runner pod should use the service account, got: %s", job.Spec.Template.Spec.ServiceAccountName)
+	}
+	initContainers = job.Spec.Template.Spec.InitContainers
+	if len(initContainers) == 0 || initContainers[len(initContainers)-1].Name != "archive-download" {
+		t.Errorf("runner pod should download the archive in an init container, got: %v", initContainers)
+	}
+}
+
+func TestNewArchiveDownloadResources(t *testing.T) {
+	spec := &v1alpha1.K6Spec{
+		Script: v1alpha1.K6Script{
+			ArchiveDownload: &v1alpha1.ArchiveDownload{URI: "https://bucket.s3.amazonaws.com/archive.tar"},
+		},
+		Runner: v1alpha1.Pod{
+			InitContainerResources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	script, err := types.ParseScript(spec)
+	if err != nil {
+		t.Fatalf("ParseScript errored, got: %v", err)
+	}
+
+	initContainers, _ := newArchiveDownload(spec, script)
+	if len(initContainers) != 1 {
+		t.Fatalf("newArchiveDownload should return a download container, got: %v", initContainers)
+	}
+	resources := initContainers[0].Resources
+	if memory := resources.Limits[corev1.ResourceMemory]; memory.String() != "1Gi" {
+		t.Errorf("newArchiveDownload should use the custom memory limit, got: %s", memory.String())
+	}
+	if cpu := resources.Limits[corev1.ResourceCPU]; cpu.String() != "100m" {
+		t.Errorf("newArchiveDownload should use the default CPU limit, got: %s", cpu.String())
+	}
+}
+
+func TestNewImagePullSecrets(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			Runner: v1alpha1.Pod{
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "runner-registry"}, {Name: "registry"}},
+			},
+		},
+	}
+
+	runner, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	initializer, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	starter := NewStarterJob(k6, []string{"testing"})
+
+	expected := map[string][]corev1.LocalObjectReference{
+		"runner": {{Name: "runner-registry"}, {Name: "registry"}},
+		// the initializer defaults to the settings of runners
+		"initializer": {{Name: "runner-registry"}, {Name: "registry"}},
+		"starter":     {{Name: "registry"}},
+	}
+	for name, spec := range map[string]corev1.PodSpec{
+		"runner":      runner.Spec.Template.Spec,
+		"initializer": initializer.Spec.Template.Spec,
+		"starter":     starter.Spec.Template.Spec,
+	} {
+		if diff := deep.Equal(expected[name], spec.ImagePullSecrets); diff != nil {
+			t.Errorf("unexpected image pull secrets of %s, diff: %s", name, diff)
+		}
+	}
+}
+
+func TestNewMetadata(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Metadata: v1alpha1.PodMetadata{
+				Labels: map[string]string{"cost-center": "qa", "team": "platform", "k6_cr": "other",
+					"runner": "true", "k6_cr_uid": "other", "k6_run_id": "other"},
+				Annotations: map[string]string{"sidecar.istio.io/inject": "false", "owner": "platform"},
+			},
+			Runner: v1alpha1.Pod{
+				Metadata: v1alpha1.PodMetadata{
+					Labels:      map[string]string{"team": "load", "runner": "false"},
+					Annotations: map[string]string{"owner": "load"},
+				},
+			},
+			Starter: v1alpha1.Pod{},
+		},
+	}
+
+	runner, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	service, err := NewRunnerService(k6, 1)
+	if err != nil {
+		t.Fatalf("NewRunnerService errored, got: %v", err)
+	}
+	starter := NewStarterJob(k6, []string{"testing"})
+
+	expectedRunnerLabels := map[string]string{"app": "k6", "k6_cr": "test", "runner": "true", "cost-center": "qa", "team": "load"}
+	expectedRunnerAnnotations := map[string]string{"sidecar.istio.io/inject": "false", "owner": "load"}
+	for name, meta := range map[string]metav1.ObjectMeta{
+		"runner job": runner.ObjectMeta,
+		"runner pod": runner.Spec.Template.ObjectMeta,
+		"service":    service.ObjectMeta,
+	} {
+		if diff := deep.Equal(expectedRunnerLabels, meta.Labels); diff != nil {
+			t.Errorf("unexpected labels of %s, diff: %s", name, diff)
+		}
+		if diff := deep.Equal(expectedRunnerAnnotations, meta.Annotations); diff != nil {
+			t.Errorf("unexpected annotations of %s, diff: %s", name, diff)
+		}
+	}
+
+	expectedStarterLabels := map[string]string{"app": "k6", "k6_cr": "test", "cost-center": "qa", "team": "platform"}
+	if diff := deep.Equal(expectedStarterLabels, starter.Spec.Template.Labels); diff != nil {
+		t.Errorf("unexpected labels of starter, diff: %s", diff)
+	}
+
+	// the spec must stay as it was
+	if len(k6.Spec.Runner.Metadata.Annotations) != 1 {
+		t.Errorf("annotations of runner in the spec were changed: %v", k6.Spec.Runner.Metadata.Annotations)
+	}
+}
@@ -2,7 +2,9 @@ package jobs
 
 import (
 	"fmt"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/grafana/k6-operator/api/v1alpha1"
 	"github.com/grafana/k6-operator/pkg/types"
@@ -20,11 +22,9 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 
 	var (
 		image                        = "ghcr.io/grafana/operator:latest-runner"
-		annotations                  = make(map[string]string)
-		labels                       = newLabels(k6.Name)
 		serviceAccountName           = "default"
 		automountServiceAccountToken = true
-		ports                        = append([]corev1.ContainerPort{{ContainerPort: 6565}}, k6.Spec.Ports...)
+		ports                        = append([]corev1.ContainerPort{{ContainerPort: k6.Spec.RestAPIPort()}}, k6.Spec.Ports...)
 	)
 
 	if k6.Spec.Initializer == nil {
@@ -35,17 +35,7 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 		image = k6.Spec.Initializer.Image
 	}
 
-	if k6.Spec.Initializer.Metadata.Annotations != nil {
-		annotations = k6.Spec.Initializer.Metadata.Annotations
-	}
-
-	if k6.Spec.Initializer.Metadata.Labels != nil {
-		for k, v := range k6.Spec.Initializer.Metadata.Labels {
-			if _, ok := labels[k]; !ok {
-				labels[k] = v
-			}
-		}
-	}
+	labels, annotations := newMetadata(k6, NewLabels(k6), k6.Spec.Initializer)
 
 	if k6.Spec.Initializer.ServiceAccountName != "" {
 		serviceAccountName = k6.Spec.Initializer.ServiceAccountName
@@ -55,13 +45,44 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 		automountServiceAccountToken, _ = strconv.ParseBool(k6.Spec.Initializer.AutomountServiceAccountToken)
 	}
 
+	// the initializer validates the test with the same config as runners
+	// unless it has one of its own
+	configFile := k6.Spec.Initializer.ConfigFile
+	if configFile == nil {
+		configFile = k6.Spec.Runner.ConfigFile
+	}
+	configVolumes, configVolumeMounts, configArgs, err := newConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(configArgs) > 0 {
+		argLine = strings.TrimSpace(strings.Join(configArgs, " ") + " " + argLine)
+	}
+
 	var (
 		// k6 allows to run archive command on archives too so type of file here doesn't matter
 		scriptName  = script.FullName()
 		archiveName = fmt.Sprintf("/tmp/%s.archived.tar", script.Filename)
 	)
+	// For archives, report the version of k6 that created the archive
+	// together with the one in the image, so that skew can be detected before
+	// creating runners. It goes to the termination message to keep output of
+	// k6 inspect as is.
+	var versionCommand string
+	if script.IsArchive() {
+		versionCommand = fmt.Sprintf(
+			`printf '{"archive":"%%s","k6":"%%s"}' "$(tar -xOf %s metadata.json | sed -n 's/.*"k6version": *"\([^"]*\)".*/\1/p')" "$(k6 version | cut -d' ' -f2)" > /dev/termination-log ; `,
+			scriptName)
+	}
+
+	// k6 built with extensions is found first
+	var pathCommand string
+	if binary := buildBinary(k6.Spec.Initializer); len(binary) > 0 {
+		pathCommand = fmt.Sprintf("export PATH=%s:$PATH ; ", path.Dir(binary))
+	}
+
 	command, istioEnabled := newIstioCommand(k6.Spec.Scuttle.Enabled, []string{"sh", "-c"})
-	command = append(command, fmt.Sprintf(
+	command = append(command, pathCommand+script.VerifyCommand(k6.Spec.Script.RequiredFiles)+versionCommand+fmt.Sprintf(
 		// There can be several scenarios from k6 command here:
 		// a) script is correct and `k6 inspect` outputs JSON
 		// b) script is partially incorrect and `k6` outputs a warning log message and
@@ -81,7 +102,18 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 		archiveName, scriptName, archiveName, argLine,
 		archiveName))
 
-	env := append(newIstioEnvVar(k6.Spec.Scuttle, istioEnabled), k6.Spec.Initializer.Env...)
+	env := append(newIstioEnvVar(k6.Spec.Scuttle, istioEnabled), newUsageReportEnvVar(k6)...)
+	env = append(env, k6.Spec.Initializer.Env...)
+
+	nodeSelector, tolerations, affinity := newScheduling(k6, k6.Spec.Initializer)
+	_, archiveVolumes := newArchiveDownload(&k6.Spec, script)
+	_, buildVolumes, buildVolumeMounts := newBuild(k6.Spec.Initializer)
+
+	ttlSecondsAfterFinished := orDefault32(k6.Spec.Initializer.TTLSecondsAfterFinished, initializerTTLSecondsAfterFinished)
+	if *ttlSecondsAfterFinished < initializerMinTTLSecondsAfterFinished {
+		minTTLSecondsAfterFinished := initializerMinTTLSecondsAfterFinished
+		ttlSecondsAfterFinished = &minTTLSecondsAfterFinished
+	}
 
 	var zero32 int32
 	return &batchv1.Job{
@@ -92,7 +124,9 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 			Annotations: annotations,
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: &zero32,
+			BackoffLimit:            orDefault32(k6.Spec.Initializer.BackoffLimit, zero32),
+			ActiveDeadlineSeconds:   orDefault64(k6.Spec.Initializer.ActiveDeadlineSeconds, initializerActiveDeadlineSeconds),
+			TTLSecondsAfterFinished: ttlSecondsAfterFinished,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      labels,
@@ -101,13 +135,13 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 				Spec: corev1.PodSpec{
 					AutomountServiceAccountToken: &automountServiceAccountToken,
 					ServiceAccountName:           serviceAccountName,
-					Affinity:                     k6.Spec.Initializer.Affinity,
-					NodeSelector:                 k6.Spec.Initializer.NodeSelector,
-					Tolerations:                  k6.Spec.Initializer.Tolerations,
-					SecurityContext:              &k6.Spec.Initializer.SecurityContext,
+					Affinity:                     affinity,
+					NodeSelector:                 nodeSelector,
+					Tolerations:                  tolerations,
+					SecurityContext:              podSecurityContext(k6.Spec.Initializer),
 					RestartPolicy:                corev1.RestartPolicyNever,
-					ImagePullSecrets:             k6.Spec.Initializer.ImagePullSecrets,
-					InitContainers:               getInitContainers(&k6.Spec, script),
+					ImagePullSecrets:             newImagePullSecrets(k6, k6.Spec.Initializer),
+					InitContainers:               getInitContainers(&k6.Spec, k6.Spec.Initializer, script),
 					Containers: []corev1.Container{
 						{
 							Image:           image,
@@ -115,12 +149,14 @@ func NewInitializerJob(k6 *v1alpha1.K6, argLine string) (*batchv1.Job, error) {
 							Name:            "k6",
 							Command:         command,
 							Env:             env,
+							EnvFrom:         k6.Spec.Initializer.EnvFrom,
 							Resources:       k6.Spec.Initializer.Resources,
-							VolumeMounts:    script.VolumeMount(),
+							VolumeMounts:    concatVolumeMounts(scriptVolumeMounts(script), configVolumeMounts, buildVolumeMounts, k6.Spec.Initializer.VolumeMounts),
 							Ports:           ports,
+							SecurityContext: containerSecurityContext(k6.Spec.Initializer, false),
 						},
 					},
-					Volumes: script.Volume(),
+					Volumes: concatVolumes(script.Volume(), archiveVolumes, configVolumes, buildVolumes, k6.Spec.Initializer.Volumes),
 				},
 			},
 		},
@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"strings"
 	"testing"
 
 	deep "github.com/go-test/deep"
@@ -20,6 +21,8 @@ func TestNewInitializerJob(t *testing.T) {
 
 	automountServiceAccountToken := true
 	zero := int32(0)
+	activeDeadlineSeconds := int64(600)
+	ttlSecondsAfterFinished := int32(3600)
 
 	expectedOutcome := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -35,7 +38,9 @@ func TestNewInitializerJob(t *testing.T) {
 			},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit: &zero,
+			BackoffLimit:            &zero,
+			ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -61,7 +66,8 @@ func TestNewInitializerJob(t *testing.T) {
 							Name:            "k6",
 							Command: []string{
 								"sh", "-c",
-								"mkdir -p $(dirname /tmp/test.js.archived.tar) && k6 archive /test/test.js -O /tmp/test.js.archived.tar --out cloud 2> /tmp/k6logs && k6 inspect --execution-requirements /tmp/test.js.archived.tar 2> /tmp/k6logs ; ! cat /tmp/k6logs | grep 'level=error'",
+								`for f in '/test/test.js'; do if [ ! -f "$f" ]; then echo "script verification failed: $f not found" > /dev/termination-log ; ls -l "$(dirname "$f")" ; exit 1; fi; done ; ` +
+									"mkdir -p $(dirname /tmp/test.js.archived.tar) && k6 archive /test/test.js -O /tmp/test.js.archived.tar --out cloud 2> /tmp/k6logs && k6 inspect --execution-requirements /tmp/test.js.archived.tar 2> /tmp/k6logs ; ! cat /tmp/k6logs | grep 'level=error'",
 							},
 							Env:          []corev1.EnvVar{},
 							Resources:    corev1.ResourceRequirements{},
@@ -110,3 +116,196 @@ func TestNewInitializerJob(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestNewInitializerJobArchiveVersions(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "archive.tar",
+				},
+			},
+		},
+	}
+
+	job, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+
+	command := job.Spec.Template.Spec.Containers[0].Command
+	if !strings.Contains(command[2], `done ; printf '{"archive":"%s","k6":"%s"}' "$(tar -xOf /test/archive.tar metadata.json`) ||
+		!strings.Contains(command[2], "> /dev/termination-log ; mkdir -p") {
+		t.Errorf("NewInitializerJob should report versions for an archive, got: %s", command[2])
+	}
+}
+
+func TestNewInitializerJobVolumes(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				VolumeClaim: v1alpha1.K6VolumeClaim{
+					Name: "test",
+				},
+			},
+			Runner: v1alpha1.Pod{
+				Volumes:      []corev1.Volume{{Name: "data"}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/test/data"}},
+			},
+		},
+	}
+
+	// the initializer inherits volumes of runners so that the script can be
+	// inspected with its data
+	job, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+	if len(spec.Volumes) != 2 || spec.Volumes[1].Name != "data" {
+		t.Errorf("NewInitializerJob returned unexpected volumes: %v", spec.Volumes)
+	}
+	if diff := deep.Equal(k6.Spec.Runner.VolumeMounts, spec.Containers[0].VolumeMounts[1:]); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected mounts, diff: %s", diff)
+	}
+}
+
+func TestNewInitializerJobEnvFrom(t *testing.T) {
+	envFrom := []corev1.EnvFromSource{
+		{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "tuning"}}},
+		{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}}},
+	}
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Runner: v1alpha1.Pod{
+				EnvFrom: envFrom,
+			},
+		},
+	}
+
+	// the script is inspected with the same env as in runners
+	job, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	if diff := deep.Equal(envFrom, job.Spec.Template.Spec.Containers[0].EnvFrom); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected envFrom, diff: %s", diff)
+	}
+
+	k6.Spec.Initializer = &v1alpha1.Pod{EnvFrom: envFrom[:1]}
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	if diff := deep.Equal(envFrom[:1], job.Spec.Template.Spec.Containers[0].EnvFrom); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected envFrom of the initializer, diff: %s", diff)
+	}
+}
+
+func TestNewInitializerJobBackoffLimit(t *testing.T) {
+	backoffLimit, ttl, shortTTL := int32(2), int32(300), int32(0)
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Initializer: &v1alpha1.Pod{
+				BackoffLimit:            &backoffLimit,
+				TTLSecondsAfterFinished: &ttl,
+			},
+		},
+	}
+
+	job, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	if diff := deep.Equal(&backoffLimit, job.Spec.BackoffLimit); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected backoffLimit, diff: %s", diff)
+	}
+	if diff := deep.Equal(&ttl, job.Spec.TTLSecondsAfterFinished); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected ttlSecondsAfterFinished, diff: %s", diff)
+	}
+
+	// the finished initializer is kept until the operator has read its result
+	k6.Spec.Initializer.TTLSecondsAfterFinished = &shortTTL
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	if *job.Spec.TTLSecondsAfterFinished != initializerMinTTLSecondsAfterFinished {
+		t.Errorf("NewInitializerJob returned ttlSecondsAfterFinished %d, expected %d", *job.Spec.TTLSecondsAfterFinished, initializerMinTTLSecondsAfterFinished)
+	}
+}
+
+func TestNewInitializerJobConfigFile(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Runner: v1alpha1.Pod{
+				ConfigFile: &v1alpha1.K6ConfigFile{ConfigMap: "k6-defaults", File: "defaults.json"},
+			},
+			// the initializer is set without a config file of its own
+			Initializer: &v1alpha1.Pod{
+				Image: "grafana/k6:latest",
+			},
+		},
+	}
+
+	job, err := NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	command := strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !strings.Contains(command, "--config /etc/k6/defaults.json") {
+		t.Errorf("NewInitializerJob should pass the config file of runners, got: %s", command)
+	}
+	if volumes := job.Spec.Template.Spec.Volumes; len(volumes) != 2 || volumes[1].ConfigMap == nil || volumes[1].ConfigMap.Name != "k6-defaults" {
+		t.Errorf("NewInitializerJob should mount the config file of runners, got: %v", volumes)
+	}
+
+	k6.Spec.Initializer.ConfigFile = &v1alpha1.K6ConfigFile{ConfigMap: "k6-inspect", File: "inspect.json"}
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	command = strings.Join(job.Spec.Template.Spec.Containers[0].Command, " ")
+	if !strings.Contains(command, "--config /etc/k6/inspect.json") {
+		t.Errorf("NewInitializerJob should pass the config file of the initializer, got: %s", command)
+	}
+}
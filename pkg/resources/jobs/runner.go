@@ -14,13 +14,19 @@ import (
 	"github.com/grafana/k6-operator/pkg/types"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // NewRunnerJob creates a new k6 job from a CRD
 func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error) {
 	name := fmt.Sprintf("%s-%d", k6.Name, index)
 	postCommand := []string{"k6", "run"}
+	if binary := buildBinary(&k6.Spec.Runner); len(binary) > 0 {
+		postCommand[0] = binary
+	}
 
 	command, istioEnabled := newIstioCommand(k6.Spec.Scuttle.Enabled, postCommand)
 
@@ -33,13 +39,28 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 		command = append(command, "--quiet")
 	}
 
+	usageReportEnv := newUsageReportEnvVar(k6)
+	if len(usageReportEnv) > 0 {
+		command = append(command, "--no-usage-report")
+	}
+
+	// the webhook is optional: without it, runners would overlap or leave
+	// parts of the test out with the segments of arguments
+	if flag := k6.ReservedArgument(); len(flag) > 0 {
+		return nil, fmt.Errorf("arguments can't set %s: execution segments of runners are set by the operator", flag)
+	}
+
 	if k6.Spec.Parallelism > 1 {
 		var args []string
 		var err error
 
-		if args, err = segmentation.NewCommandFragments(index, int(k6.Spec.Parallelism)); err != nil {
+		if len(k6.Spec.SegmentWeights) > 0 {
+			args, err = segmentation.NewWeightedCommandFragments(index, int(k6.Spec.Parallelism), k6.Spec.SegmentWeights)
+		} else {
+			args, err = segmentation.NewCommandFragments(index, int(k6.Spec.Parallelism))
+		}
+		if err != nil {
 			return nil, err
-
 		}
 		command = append(command, args...)
 	}
@@ -49,6 +70,29 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 		return nil, err
 	}
 
+	configVolumes, configVolumeMounts, configArgs, err := newConfigFile(k6.Spec.Runner.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	command = append(command, configArgs...)
+
+	image := "ghcr.io/grafana/operator:latest-runner"
+	if k6.Spec.Runner.Image != "" {
+		image = k6.Spec.Runner.Image
+	}
+
+	secretsVolumes, secretsInitContainers, secretsVolumeMounts, secretsArgs := newSecrets(k6.Spec.Secrets, image, k6.Spec.Runner.ImagePullPolicy)
+	for i := range secretsInitContainers {
+		secretsInitContainers[i].SecurityContext = containerSecurityContext(&k6.Spec.Runner, false)
+	}
+	command = append(command, secretsArgs...)
+
+	outputVolumes, outputVolumeMounts := newOutputVolume(k6.Spec.OutputVolume, index)
+	_, archiveVolumes := newArchiveDownload(&k6.Spec, script)
+	_, buildVolumes, buildVolumeMounts := newBuild(&k6.Spec.Runner)
+
+	command = append(command, newOutputArgs(k6.Spec.Output, types.ParseCLI(&k6.Spec))...)
+
 	if k6.Spec.Arguments != "" {
 		args := strings.Split(k6.Spec.Arguments, " ")
 		command = append(command, args...)
@@ -57,7 +101,7 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 	command = append(
 		command,
 		fmt.Sprintf(script.FullName()),
-		"--address=0.0.0.0:6565")
+		fmt.Sprintf("--address=0.0.0.0:%d", k6.Spec.RestAPIPort()))
 
 	paused := true
 	if k6.Spec.Paused != "" {
@@ -74,32 +118,16 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 	// Add an job tag: in case metrics are stored, they need to be distinguished by job
 	command = append(command, "--tag", fmt.Sprintf("job_name=%s", name))
 
-	command = script.UpdateCommand(command)
-
-	var (
-		zero   int64 = 0
-		zero32 int32 = 0
-	)
-
-	image := "ghcr.io/grafana/operator:latest-runner"
-	if k6.Spec.Runner.Image != "" {
-		image = k6.Spec.Runner.Image
+	// Add a run tag: metrics of reruns of the same K6 resource can be told apart by it
+	if len(k6.Status.RunID) > 0 {
+		command = append(command, "--tag", fmt.Sprintf("run_id=%s", k6.Status.RunID))
 	}
 
-	runnerAnnotations := make(map[string]string)
-	if k6.Spec.Runner.Metadata.Annotations != nil {
-		runnerAnnotations = k6.Spec.Runner.Metadata.Annotations
-	}
+	command = script.UpdateCommand(command)
 
-	runnerLabels := newLabels(k6.Name)
-	runnerLabels["runner"] = "true"
-	if k6.Spec.Runner.Metadata.Labels != nil {
-		for k, v := range k6.Spec.Runner.Metadata.Labels { // Order not specified
-			if _, ok := runnerLabels[k]; !ok {
-				runnerLabels[k] = v
-			}
-		}
-	}
+	var zero32 int32 = 0
+
+	runnerLabels, runnerAnnotations := newMetadata(k6, NewRunnerLabels(k6), &k6.Spec.Runner)
 
 	serviceAccountName := "default"
 	if k6.Spec.Runner.ServiceAccountName != "" {
@@ -111,7 +139,7 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 		automountServiceAccountToken, _ = strconv.ParseBool(k6.Spec.Runner.AutomountServiceAccountToken)
 	}
 
-	ports := []corev1.ContainerPort{{ContainerPort: 6565}}
+	ports := []corev1.ContainerPort{{ContainerPort: k6.Spec.RestAPIPort()}}
 	ports = append(ports, k6.Spec.Ports...)
 
 	env := newIstioEnvVar(k6.Spec.Scuttle, istioEnabled)
@@ -133,6 +161,9 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 		)
 	}
 
+	env = append(env, usageReportEnv...)
+	env = append(env, newTraceContextEnvVars(k6, index)...)
+	env = append(env, newOutputEnvVars(k6.Spec.Output)...)
 	env = append(env, k6.Spec.Runner.Env...)
 
 	job := &batchv1.Job{
@@ -157,9 +188,10 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 					Affinity:                     k6.Spec.Runner.Affinity,
 					NodeSelector:                 k6.Spec.Runner.NodeSelector,
 					Tolerations:                  k6.Spec.Runner.Tolerations,
-					SecurityContext:              &k6.Spec.Runner.SecurityContext,
-					ImagePullSecrets:             k6.Spec.Runner.ImagePullSecrets,
-					InitContainers:               getInitContainers(&k6.Spec, script),
+					TopologySpreadConstraints:    k6.Spec.Runner.TopologySpreadConstraints,
+					SecurityContext:              podSecurityContext(&k6.Spec.Runner),
+					ImagePullSecrets:             newImagePullSecrets(k6, &k6.Spec.Runner),
+					InitContainers:               append(secretsInitContainers, getInitContainers(&k6.Spec, &k6.Spec.Runner, script)...),
 					Containers: []corev1.Container{{
 						Image:           image,
 						ImagePullPolicy: k6.Spec.Runner.ImagePullPolicy,
@@ -167,21 +199,26 @@ func NewRunnerJob(k6 *v1alpha1.K6, index int, token string) (*batchv1.Job, error
 						Command:         command,
 						Env:             env,
 						Resources:       k6.Spec.Runner.Resources,
-						VolumeMounts:    script.VolumeMount(),
+						VolumeMounts:    concatVolumeMounts(scriptVolumeMounts(script), configVolumeMounts, secretsVolumeMounts, outputVolumeMounts, buildVolumeMounts, k6.Spec.Runner.VolumeMounts),
 						Ports:           ports,
 						EnvFrom:         k6.Spec.Runner.EnvFrom,
-						LivenessProbe:   generateProbe(k6.Spec.Runner.LivenessProbe),
-						ReadinessProbe:  generateProbe(k6.Spec.Runner.ReadinessProbe),
+						LivenessProbe:   generateProbe(k6.Spec.Runner.LivenessProbe, &k6.Spec),
+						ReadinessProbe:  generateProbe(k6.Spec.Runner.ReadinessProbe, &k6.Spec),
+						SecurityContext: containerSecurityContext(&k6.Spec.Runner, false),
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					TerminationGracePeriodSeconds: k6.Spec.Runner.TerminationGracePeriodSeconds,
+					Volumes:                       concatVolumes(script.Volume(), archiveVolumes, configVolumes, secretsVolumes, outputVolumes, buildVolumes, k6.Spec.Runner.Volumes),
 				},
 			},
 		},
 	}
 
 	if k6.Spec.Separate {
-		job.Spec.Template.Spec.Affinity = newAntiAffinity()
+		job.Spec.Template.Spec.Affinity = withAntiAffinity(job.Spec.Template.Spec.Affinity)
+	}
+	// a single runner has nothing to be spread from
+	if k6.Spec.Runner.SpreadAcrossZones && k6.Spec.Parallelism > 1 {
+		job.Spec.Template.Spec.Affinity = withZoneAntiAffinity(job.Spec.Template.Spec.Affinity, k6)
 	}
 	return job, nil
 }
@@ -190,24 +227,11 @@ func NewRunnerService(k6 *v1alpha1.K6, index int) (*corev1.Service, error) {
 	serviceName := fmt.Sprintf("%s-%s-%d", k6.Name, "service", index)
 	runnerName := fmt.Sprintf("%s-%d", k6.Name, index)
 
-	runnerAnnotations := make(map[string]string)
-	if k6.Spec.Runner.Metadata.Annotations != nil {
-		runnerAnnotations = k6.Spec.Runner.Metadata.Annotations
-	}
-
-	runnerLabels := newLabels(k6.Name)
-	runnerLabels["runner"] = "true"
-	if k6.Spec.Runner.Metadata.Labels != nil {
-		for k, v := range k6.Spec.Runner.Metadata.Labels { // Order not specified
-			if _, ok := runnerLabels[k]; !ok {
-				runnerLabels[k] = v
-			}
-		}
-	}
+	runnerLabels, runnerAnnotations := newMetadata(k6, NewRunnerLabels(k6), &k6.Spec.Runner)
 
 	port := []corev1.ServicePort{{
 		Name:     "http-api",
-		Port:     6565,
+		Port:     k6.Spec.RestAPIPort(),
 		Protocol: "TCP",
 	}}
 
@@ -229,6 +253,104 @@ func NewRunnerService(k6 *v1alpha1.K6, index int) (*corev1.Service, error) {
 	return service, nil
 }
 
+// NewRunnersService returns the headless service of all runner pods, so that
+// they can be discovered with DNS, e.g. with SRV records of their REST API.
+// It's not labelled as a runner, so that the operator doesn't mistake it
+// for a service of one, and it leaves out the run ID to cover retries.
+func NewRunnersService(k6 *v1alpha1.K6) *corev1.Service {
+	labels := NewLabels(k6)
+	delete(labels, "k6_run_id")
+	serviceLabels, serviceAnnotations := newMetadata(k6, labels, &k6.Spec.Runner)
+
+	selector := NewRunnerLabels(k6)
+	delete(selector, "k6_run_id")
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-runners", k6.Name),
+			Namespace:   k6.Namespace,
+			Labels:      serviceLabels,
+			Annotations: serviceAnnotations,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Ports: []corev1.ServicePort{{
+				Name:     "http-api",
+				Port:     k6.Spec.RestAPIPort(),
+				Protocol: "TCP",
+			}},
+			Selector: selector,
+		},
+	}
+}
+
+// NewRunnerPDB returns the PodDisruptionBudget of runner pods which blocks
+// voluntary disruptions of any of them. Its selector leaves out the run ID
+// so that it covers the runners of retries too.
+func NewRunnerPDB(k6 *v1alpha1.K6) *policyv1.PodDisruptionBudget {
+	runnerLabels, runnerAnnotations := newMetadata(k6, NewRunnerLabels(k6), &k6.Spec.Runner)
+
+	selector := NewRunnerLabels(k6)
+	delete(selector, "k6_run_id")
+	minAvailable := intstr.FromInt(int(k6.Spec.Parallelism))
+
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-runners", k6.Name),
+			Namespace:   k6.Namespace,
+			Labels:      runnerLabels,
+			Annotations: runnerAnnotations,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: selector},
+		},
+	}
+}
+
+// ServiceMonitorGVK is the kind of ServiceMonitor of Prometheus Operator.
+// Its types are not imported, so it's built as unstructured.
+var ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+// NewRunnerServiceMonitor returns the ServiceMonitor which has Prometheus
+// scrape the metrics of each runner through its service. /v1/metrics of the
+// REST API is JSON, so the path is the one of an output serving Prometheus
+// format. As with the PodDisruptionBudget, its selector leaves out the run ID.
+func NewRunnerServiceMonitor(k6 *v1alpha1.K6) *unstructured.Unstructured {
+	runnerLabels, runnerAnnotations := newMetadata(k6, NewRunnerLabels(k6), &k6.Spec.Runner)
+
+	selector := map[string]interface{}{}
+	for k, v := range NewRunnerLabels(k6) {
+		if k != "k6_run_id" {
+			selector[k] = v
+		}
+	}
+
+	endpoint := map[string]interface{}{
+		"port":   "http-api",
+		"path":   k6.Spec.Output.ScrapePath(),
+		"scheme": k6.Spec.RestAPIScheme(),
+	}
+	if k6.Spec.RestAPIInsecureSkipVerify() {
+		endpoint["tlsConfig"] = map[string]interface{}{"insecureSkipVerify": true}
+	}
+
+	serviceMonitor := &unstructured.Unstructured{}
+	serviceMonitor.SetGroupVersionKind(ServiceMonitorGVK)
+	serviceMonitor.SetName(fmt.Sprintf("%s-runners", k6.Name))
+	serviceMonitor.SetNamespace(k6.Namespace)
+	serviceMonitor.SetLabels(runnerLabels)
+	serviceMonitor.SetAnnotations(runnerAnnotations)
+	serviceMonitor.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{"matchLabels": selector},
+		"namespaceSelector": map[string]interface{}{
+			"matchNames": []interface{}{k6.Namespace},
+		},
+		"endpoints": []interface{}{endpoint},
+	}
+	return serviceMonitor
+}
+
 func newAntiAffinity() *corev1.Affinity {
 	return &corev1.Affinity{
 		PodAntiAffinity: &corev1.PodAntiAffinity{
@@ -259,16 +381,66 @@ func newAntiAffinity() *corev1.Affinity {
 	}
 }
 
-func generateProbe(configuredProbe *corev1.Probe) *corev1.Probe {
+// withAntiAffinity adds the anti-affinity of separate runners to the
+// affinity configured for runners, keeping all of the latter.
+func withAntiAffinity(affinity *corev1.Affinity) *corev1.Affinity {
+	antiAffinity := newAntiAffinity()
+	if affinity == nil {
+		return antiAffinity
+	}
+
+	merged := affinity.DeepCopy()
+	if merged.PodAntiAffinity == nil {
+		merged.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		merged.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+		antiAffinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution...)
+	return merged
+}
+
+// withZoneAntiAffinity adds preferred anti-affinity of the runners of the
+// test run across zones to the given affinity.
+func withZoneAntiAffinity(affinity *corev1.Affinity, k6 *v1alpha1.K6) *corev1.Affinity {
+	merged := &corev1.Affinity{}
+	if affinity != nil {
+		merged = affinity.DeepCopy()
+	}
+	if merged.PodAntiAffinity == nil {
+		merged.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(
+		merged.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+		corev1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app":    "k6",
+						"k6_cr":  k6.Name,
+						"runner": "true",
+					},
+				},
+				TopologyKey: corev1.LabelTopologyZone,
+			},
+		})
+	return merged
+}
+
+func generateProbe(configuredProbe *corev1.Probe, k6Spec *v1alpha1.K6Spec) *corev1.Probe {
 	if configuredProbe != nil {
 		return configuredProbe
 	}
+	scheme := corev1.URISchemeHTTP
+	if k6Spec.RestAPIScheme() == "https" {
+		scheme = corev1.URISchemeHTTPS
+	}
 	return &corev1.Probe{
 		ProbeHandler: corev1.ProbeHandler{
 			HTTPGet: &corev1.HTTPGetAction{
 				Path:   "/v1/status",
-				Port:   intstr.IntOrString{IntVal: 6565},
-				Scheme: "HTTP",
+				Port:   intstr.IntOrString{IntVal: k6Spec.RestAPIPort()},
+				Scheme: scheme,
 			},
 		},
 	}
@@ -2,7 +2,9 @@ package jobs
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -13,6 +15,8 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 // these are default values hard-coded in k6
@@ -123,6 +127,37 @@ func TestNewScriptNoScript(t *testing.T) {
 	}
 }
 
+func TestNewScriptArchiveDownloadMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		uri   string
+		mode  v1alpha1.ArchiveDownloadMode
+		valid bool
+	}{
+		{"S3URIDefaultMode", "s3://bucket/archive.tar", "", true},
+		{"S3URISDKMode", "s3://bucket/archive.tar", v1alpha1.ArchiveDownloadSDK, true},
+		{"S3URICurlMode", "s3://bucket/archive.tar", v1alpha1.ArchiveDownloadCurl, false},
+		{"URLCurlMode", "https://bucket.s3.amazonaws.com/archive.tar", v1alpha1.ArchiveDownloadCurl, true},
+		{"URLSDKMode", "https://bucket.s3.amazonaws.com/archive.tar", v1alpha1.ArchiveDownloadSDK, false},
+	}
+
+	for _, test := range tests {
+		k6 := v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ArchiveDownload: &v1alpha1.ArchiveDownload{URI: test.uri, Mode: test.mode},
+			},
+		}
+
+		_, err := types.ParseScript(&k6)
+		if test.valid && err != nil {
+			t.Errorf("%s: NewScript with ArchiveDownload errored, got: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected error from NewScript with ArchiveDownload", test.name)
+		}
+	}
+}
+
 func TestNewVolumeSpecVolumeClaim(t *testing.T) {
 	expectedOutcome := []corev1.Volume{
 		corev1.Volume{
@@ -282,7 +317,6 @@ func TestNewRunnerJob(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -356,8 +390,7 @@ func TestNewRunnerJob(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -413,7 +446,6 @@ func TestNewRunnerJobNoisy(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -478,8 +510,7 @@ func TestNewRunnerJobNoisy(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -526,7 +557,6 @@ func TestNewRunnerJobUnpaused(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -591,8 +621,7 @@ func TestNewRunnerJobUnpaused(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -639,7 +668,6 @@ func TestNewRunnerJobArguments(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -704,8 +732,7 @@ func TestNewRunnerJobArguments(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -753,7 +780,6 @@ func TestNewRunnerJobServiceAccount(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -818,8 +844,7 @@ func TestNewRunnerJobServiceAccount(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -868,7 +893,6 @@ func TestNewRunnerJobIstio(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -946,8 +970,7 @@ func TestNewRunnerJobIstio(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -996,7 +1019,6 @@ func TestNewRunnerJobCloud(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -1069,8 +1091,7 @@ func TestNewRunnerJobCloud(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -1120,7 +1141,6 @@ func TestNewRunnerJobLocalFile(t *testing.T) {
 		Type:     "LocalFile",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -1184,8 +1204,7 @@ func TestNewRunnerJobLocalFile(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -1231,7 +1250,6 @@ func TestNewRunnerJobWithInitContainer(t *testing.T) {
 		Type:     "ConfigMap",
 	}
 
-	var zero int64 = 0
 	automountServiceAccountToken := true
 
 	expectedLabels := map[string]string{
@@ -1323,8 +1341,7 @@ func TestNewRunnerJobWithInitContainer(t *testing.T) {
 							},
 						},
 					}},
-					TerminationGracePeriodSeconds: &zero,
-					Volumes:                       script.Volume(),
+					Volumes: script.Volume(),
 				},
 			},
 		},
@@ -1387,3 +1404,962 @@ func TestNewRunnerJobWithInitContainer(t *testing.T) {
 		t.Errorf("NewRunnerJob returned unexpected data, diff: %s", diff)
 	}
 }
+
+func TestNewRunnerJobConfigFile(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Parallelism: 2,
+			Runner: v1alpha1.Pod{
+				ConfigFile: &v1alpha1.K6ConfigFile{
+					ConfigMap: "k6-defaults",
+				},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	// the config file must come before the flags set by the operator
+	expectedCommand := []string{"k6", "run", "--quiet",
+		"--execution-segment=0:1/2", "--execution-segment-sequence=0,1/2,1",
+		"--config", "/etc/k6/config.json",
+		"/test/test.js", "--address=0.0.0.0:6565", "--paused", "--tag", "instance_id=1", "--tag", "job_name=test-1"}
+	container := job.Spec.Template.Spec.Containers[0]
+	if diff := deep.Equal(expectedCommand, container.Command); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected command, diff: %s", diff)
+	}
+
+	if len(container.VolumeMounts) != 2 || container.VolumeMounts[1].MountPath != "/etc/k6" {
+		t.Errorf("NewRunnerJob should mount the config file, got: %v", container.VolumeMounts)
+	}
+
+	volumes := job.Spec.Template.Spec.Volumes
+	if len(volumes) != 2 || volumes[1].ConfigMap == nil || volumes[1].ConfigMap.Name != "k6-defaults" {
+		t.Errorf("NewRunnerJob should have a config file volume, got: %v", volumes)
+	}
+}
+
+func TestNewRunnerJobSecrets(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Parallelism: 1,
+			Secrets: &v1alpha1.K6Secrets{
+				Name: "k6-secrets",
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	expectedCommand := []string{"k6", "run", "--quiet",
+		"--secret-source=file=/k6-secrets/secrets",
+		"/test/test.js", "--address=0.0.0.0:6565", "--paused", "--tag", "instance_id=1", "--tag", "job_name=test-1"}
+	podSpec := job.Spec.Template.Spec
+	container := podSpec.Containers[0]
+	if diff := deep.Equal(expectedCommand, container.Command); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected command, diff: %s", diff)
+	}
+
+	if len(container.Env) != 0 {
+		t.Errorf("NewRunnerJob should not pass secrets via env, got: %v", container.Env)
+	}
+
+	if len(container.VolumeMounts) != 2 || container.VolumeMounts[1].MountPath != "/k6-secrets" || !container.VolumeMounts[1].ReadOnly {
+		t.Errorf("NewRunnerJob should mount the secrets read-only, got: %v", container.VolumeMounts)
+	}
+
+	if len(podSpec.InitContainers) != 1 || podSpec.InitContainers[0].Name != "k6-secrets" {
+		t.Fatalf("NewRunnerJob should have a secrets init container, got: %v", podSpec.InitContainers)
+	}
+
+	volumes := podSpec.Volumes
+	if len(volumes) != 3 ||
+		volumes[1].Secret == nil || volumes[1].Secret.SecretName != "k6-secrets" ||
+		volumes[2].EmptyDir == nil || volumes[2].EmptyDir.Medium != corev1.StorageMediumMemory {
+		t.Errorf("NewRunnerJob should have secrets volumes, got: %v", volumes)
+	}
+}
+
+func TestNewRunnerJobNoUsageReport(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Parallelism:   1,
+			NoUsageReport: "true",
+			Runner: v1alpha1.Pod{
+				Env: []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	container := job.Spec.Template.Spec.Containers[0]
+	expectedCommand := []string{"k6", "run", "--quiet", "--no-usage-report",
+		"/test/test.js", "--address=0.0.0.0:6565", "--paused", "--tag", "instance_id=1", "--tag", "job_name=test-1"}
+	if diff := deep.Equal(expectedCommand, container.Command); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected command, diff: %s", diff)
+	}
+
+	expectedEnv := []corev1.EnvVar{{Name: "K6_NO_USAGE_REPORT", Value: "true"}, {Name: "FOO", Value: "bar"}}
+	if diff := deep.Equal(expectedEnv, container.Env); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected env, diff: %s", diff)
+	}
+}
+
+func TestNewRunnerJobOutputVolume(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Parallelism: 2,
+			OutputVolume: &v1alpha1.OutputVolume{
+				ClaimName: "k6-results",
+			},
+		},
+	}
+
+	for _, index := range []int{1, 2} {
+		job, err := NewRunnerJob(k6, index, "")
+		if err != nil {
+			t.Fatalf("NewRunnerJob errored, got: %v", err)
+		}
+
+		expectedMount := corev1.VolumeMount{
+			Name:      "k6-output-volume",
+			MountPath: "/output",
+			SubPath:   fmt.Sprintf("runner-%d", index),
+		}
+		mounts := job.Spec.Template.Spec.Containers[0].VolumeMounts
+		if diff := deep.Equal(expectedMount, mounts[len(mounts)-1]); diff != nil {
+			t.Errorf("NewRunnerJob returned unexpected output volume mount, diff: %s", diff)
+		}
+
+		volumes := job.Spec.Template.Spec.Volumes
+		if claim := volumes[len(volumes)-1].PersistentVolumeClaim; claim == nil || claim.ClaimName != "k6-results" {
+			t.Errorf("NewRunnerJob should have an output volume, got: %v", volumes)
+		}
+	}
+}
+
+func TestNewRunnerJobRestAPI(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			RestAPI: &v1alpha1.RestAPI{Scheme: "https", Port: 8443},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if !strings.Contains(strings.Join(container.Command, " "), "--address=0.0.0.0:8443") {
+		t.Errorf("NewRunnerJob should serve REST API at the port, got: %v", container.Command)
+	}
+	if diff := deep.Equal([]corev1.ContainerPort{{ContainerPort: 8443}}, container.Ports); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected ports, diff: %s", diff)
+	}
+	expectedProbe := &corev1.HTTPGetAction{Path: "/v1/status", Port: intstr.IntOrString{IntVal: 8443}, Scheme: corev1.URISchemeHTTPS}
+	if diff := deep.Equal(expectedProbe, container.ReadinessProbe.HTTPGet); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected readiness probe, diff: %s", diff)
+	}
+
+	service, err := NewRunnerService(k6, 1)
+	if err != nil {
+		t.Fatalf("NewRunnerService errored, got: %v", err)
+	}
+	if len(service.Spec.Ports) != 1 || service.Spec.Ports[0].Port != 8443 {
+		t.Errorf("NewRunnerService should expose REST API at the port, got: %v", service.Spec.Ports)
+	}
+}
+
+func TestNewRunnerJobScheduling(t *testing.T) {
+	nodeAffinity := &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+				MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      "pool",
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"load-generators"},
+				}},
+			}},
+		},
+	}
+	tolerations := []corev1.Toleration{{
+		Key:      "dedicated",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "k6",
+		Effect:   corev1.TaintEffectNoSchedule,
+	}}
+	nodeSelector := map[string]string{"pool": "load-generators"}
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Runner: v1alpha1.Pod{
+				Affinity:     &corev1.Affinity{NodeAffinity: nodeAffinity},
+				Tolerations:  tolerations,
+				NodeSelector: nodeSelector,
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+	if diff := deep.Equal(&corev1.Affinity{NodeAffinity: nodeAffinity}, spec.Affinity); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected affinity, diff: %s", diff)
+	}
+	if diff := deep.Equal(tolerations, spec.Tolerations); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected tolerations, diff: %s", diff)
+	}
+	if diff := deep.Equal(nodeSelector, spec.NodeSelector); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected node selector, diff: %s", diff)
+	}
+
+	// separate runners keep the node affinity
+	k6.Spec.Separate = true
+	job, err = NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	expectedAffinity := &corev1.Affinity{
+		NodeAffinity:    nodeAffinity,
+		PodAntiAffinity: newAntiAffinity().PodAntiAffinity,
+	}
+	if diff := deep.Equal(expectedAffinity, job.Spec.Template.Spec.Affinity); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected affinity of separate runners, diff: %s", diff)
+	}
+	if k6.Spec.Runner.Affinity.PodAntiAffinity != nil {
+		t.Errorf("NewRunnerJob should not change the affinity in the spec")
+	}
+}
+
+func TestNewRunnerJobSpreadAcrossZones(t *testing.T) {
+	constraints := []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"k6_cr": "test"}},
+	}}
+	zoneAntiAffinity := &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "k6", "k6_cr": "test", "runner": "true"},
+					},
+					TopologyKey: corev1.LabelTopologyZone,
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		parallelism int32
+		affinity    *corev1.Affinity
+	}{
+		{"Single", 1, nil},
+		{"Parallel", 3, zoneAntiAffinity},
+	}
+
+	for _, test := range tests {
+		k6 := &v1alpha1.K6{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "test",
+			},
+			Spec: v1alpha1.K6Spec{
+				Parallelism: test.parallelism,
+				Script: v1alpha1.K6Script{
+					ConfigMap: v1alpha1.K6Configmap{
+						Name: "test",
+						File: "test.js",
+					},
+				},
+				Runner: v1alpha1.Pod{
+					TopologySpreadConstraints: constraints,
+					SpreadAcrossZones:         true,
+				},
+			},
+		}
+
+		job, err := NewRunnerJob(k6, 1, "")
+		if err != nil {
+			t.Fatalf("NewRunnerJob errored, got: %v", err)
+		}
+		if diff := deep.Equal(constraints, job.Spec.Template.Spec.TopologySpreadConstraints); diff != nil {
+			t.Errorf("%s: NewRunnerJob returned unexpected topology spread constraints, diff: %s", test.name, diff)
+		}
+		if diff := deep.Equal(test.affinity, job.Spec.Template.Spec.Affinity); diff != nil {
+			t.Errorf("%s: NewRunnerJob returned unexpected affinity, diff: %s", test.name, diff)
+		}
+	}
+}
+
+func TestNewRunnerJobTerminationGracePeriod(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	if job.Spec.Template.Spec.TerminationGracePeriodSeconds != nil {
+		t.Errorf("NewRunnerJob should leave the grace period to the cluster, got: %v", *job.Spec.Template.Spec.TerminationGracePeriodSeconds)
+	}
+
+	gracePeriod := int64(120)
+	k6.Spec.Runner.TerminationGracePeriodSeconds = &gracePeriod
+	job, err = NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	if diff := deep.Equal(&gracePeriod, job.Spec.Template.Spec.TerminationGracePeriodSeconds); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected grace period, diff: %s", diff)
+	}
+}
+
+func TestNewRunnerJobConfigMapEntrypoint(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "scenarios",
+					File: "main.js",
+				},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	// all keys of the configMap are mounted side by side so that the
+	// entrypoint can import them by relative path
+	expectedVolume := corev1.Volume{
+		Name: "k6-test-volume",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "scenarios"},
+			},
+		},
+	}
+	if diff := deep.Equal(expectedVolume, job.Spec.Template.Spec.Volumes[0]); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected script volume, diff: %s", diff)
+	}
+
+	container := job.Spec.Template.Spec.Containers[0]
+	expectedMount := corev1.VolumeMount{Name: "k6-test-volume", MountPath: "/test"}
+	if diff := deep.Equal(expectedMount, container.VolumeMounts[0]); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected script mount, diff: %s", diff)
+	}
+
+	if !reflect.DeepEqual(container.Command[:4], []string{"k6", "run", "--quiet", "/test/main.js"}) {
+		t.Errorf("NewRunnerJob should run the entrypoint of the configMap, got: %v", container.Command)
+	}
+}
+
+func TestNewRunnerJobVolumes(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ArchiveDownload: &v1alpha1.ArchiveDownload{
+					URI: "https://example.com/archive.tar",
+				},
+			},
+			Runner: v1alpha1.Pod{
+				Volumes: []corev1.Volume{{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "datasets"},
+					},
+				}},
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/test/data"}},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+
+	volumes := make([]string, 0, len(spec.Volumes))
+	for _, volume := range spec.Volumes {
+		volumes = append(volumes, volume.Name)
+	}
+	if diff := deep.Equal([]string{"k6-test-volume", "data"}, volumes); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected volumes, diff: %s", diff)
+	}
+
+	// downloaded archive and mounted data share /test, which only the
+	// archive download container writes to
+	expectedMounts := []corev1.VolumeMount{
+		{Name: "k6-test-volume", MountPath: "/test", ReadOnly: true},
+		{Name: "data", MountPath: "/test/data"},
+	}
+	if diff := deep.Equal(expectedMounts, spec.Containers[0].VolumeMounts); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected mounts of k6 container, diff: %s", diff)
+	}
+	expectedMounts[0].ReadOnly = false
+	if diff := deep.Equal(expectedMounts, spec.InitContainers[0].VolumeMounts); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected mounts of archive download container, diff: %s", diff)
+	}
+}
+
+func TestNewRunnerJobPrometheusRemoteWrite(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Arguments: "--tag env=ci",
+			Output: &v1alpha1.Output{
+				PrometheusRemoteWrite: &v1alpha1.PrometheusRemoteWriteOutput{
+					URL: "http://prometheus:9090/api/v1/write",
+				},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	container := job.Spec.Template.Spec.Containers[0]
+	if !reflect.DeepEqual(container.Command[:8], []string{
+		"k6", "run", "--quiet", "--out", "experimental-prometheus-rw", "--tag", "env=ci", "/test/test.js",
+	}) {
+		t.Errorf("NewRunnerJob should enable remote-write output, got: %v", container.Command)
+	}
+
+	expectedEnv := corev1.EnvVar{Name: "K6_PROMETHEUS_RW_SERVER_URL", Value: "http://prometheus:9090/api/v1/write"}
+	found := false
+	for _, env := range container.Env {
+		if reflect.DeepEqual(env, expectedEnv) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("NewRunnerJob should pass the URL of remote-write endpoint, got: %v", container.Env)
+	}
+}
+
+func TestNewRunnerJobArgumentsAfterSegment(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 2,
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Arguments: "--no-thresholds --tag env=ci --compatibility-mode=base",
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 2, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+
+	// arguments follow the execution segment of the runner
+	command := job.Spec.Template.Spec.Containers[0].Command
+	if !reflect.DeepEqual(command[:10], []string{
+		"k6", "run", "--quiet",
+		"--execution-segment=1/2:1", "--execution-segment-sequence=0,1/2,1",
+		"--no-thresholds", "--tag", "env=ci", "--compatibility-mode=base",
+		"/test/test.js",
+	}) {
+		t.Errorf("NewRunnerJob should append arguments to the command, got: %v", command)
+	}
+
+	for _, arguments := range []string{"--execution-segment=0:1/2", "--tag env=ci --execution-segment-sequence 0,1/2,1"} {
+		k6.Spec.Arguments = arguments
+		if _, err := NewRunnerJob(k6, 1, ""); err == nil {
+			t.Errorf("NewRunnerJob should reject execution segments in arguments %q", arguments)
+		}
+	}
+}
+
+func TestNewRunnerJobSegmentWeights(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Parallelism:    3,
+			SegmentWeights: []int32{2, 1, 1},
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	command := job.Spec.Template.Spec.Containers[0].Command
+	if !reflect.DeepEqual(command[3:5], []string{
+		"--execution-segment=0:2/4", "--execution-segment-sequence=0,2/4,3/4,1",
+	}) {
+		t.Errorf("NewRunnerJob should split the test by the weights, got: %v", command)
+	}
+
+	k6.Spec.SegmentWeights = []int32{2, 1}
+	if _, err := NewRunnerJob(k6, 1, ""); err == nil {
+		t.Errorf("NewRunnerJob should reject weights not matching the parallelism")
+	}
+}
+
+func TestNewRunnerPDB(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			UID:       "test-uid",
+		},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 3,
+			Script: v1alpha1.K6Script{
+				ArchiveDownload: &v1alpha1.ArchiveDownload{
+					URI: "https://example.com/archive.tar",
+				},
+			},
+			Runner: v1alpha1.Pod{PDB: true},
+		},
+		Status: v1alpha1.K6Status{RunID: "run-1"},
+	}
+
+	pdb := NewRunnerPDB(k6)
+	if pdb.Name != "test-runners" {
+		t.Errorf("NewRunnerPDB returned unexpected name: %s", pdb.Name)
+	}
+	if diff := deep.Equal(intstr.FromInt(3), *pdb.Spec.MinAvailable); diff != nil {
+		t.Errorf("NewRunnerPDB returned unexpected minAvailable, diff: %s", diff)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		t.Fatalf("NewRunnerPDB returned invalid selector: %v", err)
+	}
+
+	// runners of retries have another run ID
+	for _, runID := range []string{"run-1", "run-2"} {
+		k6.Status.RunID = runID
+		job, err := NewRunnerJob(k6, 1, "")
+		if err != nil {
+			t.Fatalf("NewRunnerJob errored, got: %v", err)
+		}
+		if !selector.Matches(labels.Set(job.Spec.Template.Labels)) {
+			t.Errorf("selector %s doesn't match runner pod labels %v", selector, job.Spec.Template.Labels)
+		}
+	}
+
+	// initializer and starter pods are not covered
+	if selector.Matches(labels.Set(NewLabels(k6))) {
+		t.Errorf("selector %s matches pods other than runners", selector)
+	}
+}
+
+func TestNewJobsNoUsageReportDefault(t *testing.T) {
+	defer func(noUsageReport bool) { Defaults.NoUsageReport = noUsageReport }(Defaults.NoUsageReport)
+	Defaults.NoUsageReport = true
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Parallelism: 1,
+		},
+	}
+	usageReportEnv := corev1.EnvVar{Name: "K6_NO_USAGE_REPORT", Value: "true"}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if len(container.Env) == 0 || container.Env[0] != usageReportEnv {
+		t.Errorf("NewRunnerJob should disable usage report by default, got env: %v", container.Env)
+	}
+
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	container = job.Spec.Template.Spec.Containers[0]
+	if len(container.Env) == 0 || container.Env[0] != usageReportEnv {
+		t.Errorf("NewInitializerJob should disable usage report by default, got env: %v", container.Env)
+	}
+	if len(k6.Spec.NoUsageReport) > 0 {
+		t.Errorf("the default should not be set in the spec, got: %q", k6.Spec.NoUsageReport)
+	}
+
+	// the spec wins over the default
+	k6.Spec.NoUsageReport = "false"
+	job, err = NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	if env := job.Spec.Template.Spec.Containers[0].Env; len(env) > 0 {
+		t.Errorf("NewRunnerJob should keep usage report enabled in the spec, got env: %v", env)
+	}
+}
+
+func TestNewJobsContainerSecurityContext(t *testing.T) {
+	notPrivileged := false
+	runnerContext := &corev1.SecurityContext{AllowPrivilegeEscalation: &notPrivileged}
+	starterContext := &corev1.SecurityContext{Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}}
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ArchiveDownload: &v1alpha1.ArchiveDownload{
+					URI: "https://example.com/archive.tar",
+				},
+			},
+			Runner:  v1alpha1.Pod{ContainerSecurityContext: runnerContext},
+			Starter: v1alpha1.Pod{ContainerSecurityContext: starterContext},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+	if spec.Containers[0].SecurityContext != runnerContext {
+		t.Errorf("NewRunnerJob returned unexpected security context of k6 container: %v", spec.Containers[0].SecurityContext)
+	}
+	if spec.InitContainers[0].SecurityContext != runnerContext {
+		t.Errorf("NewRunnerJob returned unexpected security context of archive download container: %v", spec.InitContainers[0].SecurityContext)
+	}
+
+	// the initializer inherits the one of the runners
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	spec = job.Spec.Template.Spec
+	if diff := deep.Equal(runnerContext, spec.Containers[0].SecurityContext); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected security context of k6 container, diff: %s", diff)
+	}
+	if diff := deep.Equal(runnerContext, spec.InitContainers[0].SecurityContext); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected security context of archive download container, diff: %s", diff)
+	}
+
+	spec = NewStarterJob(k6, []string{"10.0.0.1"}).Spec.Template.Spec
+	if spec.Containers[0].SecurityContext != starterContext {
+		t.Errorf("NewStarterJob returned unexpected security context: %v", spec.Containers[0].SecurityContext)
+	}
+}
+
+func TestNewJobsRestrictedSecurityContext(t *testing.T) {
+	defer func(restricted bool) { Defaults.RestrictedSecurityContext = restricted }(Defaults.RestrictedSecurityContext)
+	Defaults.RestrictedSecurityContext = true
+
+	uid := int64(1000)
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{
+					Name: "test",
+					File: "test.js",
+				},
+			},
+			Runner: v1alpha1.Pod{SecurityContext: corev1.PodSecurityContext{RunAsUser: &uid}},
+		},
+	}
+
+	job, err := NewRunnerJob(k6, 1, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored, got: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+	// pod security context of the spec is kept as is
+	if diff := deep.Equal(&corev1.PodSecurityContext{RunAsUser: &uid}, spec.SecurityContext); diff != nil {
+		t.Errorf("NewRunnerJob returned unexpected pod security context, diff: %s", diff)
+	}
+	container := spec.Containers[0].SecurityContext
+	if container == nil || *container.AllowPrivilegeEscalation || *container.ReadOnlyRootFilesystem ||
+		len(container.Capabilities.Drop) != 1 || container.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("NewRunnerJob returned unexpected security context of k6 container: %v", container)
+	}
+
+	// the initializer inherits the pod security context of the runners
+	job, err = NewInitializerJob(k6, "")
+	if err != nil {
+		t.Fatalf("NewInitializerJob errored, got: %v", err)
+	}
+	spec = job.Spec.Template.Spec
+	if diff := deep.Equal(&corev1.PodSecurityContext{RunAsUser: &uid}, spec.SecurityContext); diff != nil {
+		t.Errorf("NewInitializerJob returned unexpected pod security context, diff: %s", diff)
+	}
+	if container := spec.Containers[0].SecurityContext; container == nil || *container.ReadOnlyRootFilesystem {
+		t.Errorf("NewInitializerJob returned unexpected security context of k6 container: %v", container)
+	}
+
+	spec = NewStarterJob(k6, []string{"10.0.0.1"}).Spec.Template.Spec
+	if spec.SecurityContext.RunAsNonRoot == nil || !*spec.SecurityContext.RunAsNonRoot ||
+		spec.SecurityContext.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("NewStarterJob returned unexpected pod security context: %v", spec.SecurityContext)
+	}
+	if container := spec.Containers[0].SecurityContext; container == nil || !*container.ReadOnlyRootFilesystem {
+		t.Errorf("NewStarterJob returned unexpected security context: %v", container)
+	}
+
+	// the spec itself is left as is
+	if k6.Spec.Runner.ContainerSecurityContext != nil || k6.Spec.Starter.SecurityContext.RunAsNonRoot != nil {
+		t.Errorf("restricted security contexts should not be set in the spec, got: %v", k6.Spec)
+	}
+
+	// nothing is set without the flag
+	Defaults.RestrictedSecurityContext = false
+	spec = NewStarterJob(k6, []string{"10.0.0.1"}).Spec.Template.Spec
+	if spec.Containers[0].SecurityContext != nil || spec.SecurityContext.RunAsNonRoot != nil {
+		t.Errorf("NewStarterJob should not set security contexts, got: %v, %v", spec.SecurityContext, spec.Containers[0].SecurityContext)
+	}
+}
+
+func TestNewRunnerServiceMonitor(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			UID:       "test-uid",
+		},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 2,
+			Output:      &v1alpha1.Output{ScrapeMetrics: true},
+		},
+		Status: v1alpha1.K6Status{RunID: "run-1"},
+	}
+
+	serviceMonitor := NewRunnerServiceMonitor(k6)
+	if serviceMonitor.GetName() != "test-runners" || serviceMonitor.GetNamespace() != "test" {
+		t.Errorf("NewRunnerServiceMonitor returned unexpected name: %s/%s", serviceMonitor.GetNamespace(), serviceMonitor.GetName())
+	}
+	if serviceMonitor.GroupVersionKind() != ServiceMonitorGVK {
+		t.Errorf("NewRunnerServiceMonitor returned unexpected kind: %s", serviceMonitor.GroupVersionKind())
+	}
+
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+	expectedEndpoints := []interface{}{map[string]interface{}{"port": "http-api", "path": "/metrics", "scheme": "http"}}
+	if diff := deep.Equal(expectedEndpoints, endpoints); diff != nil {
+		t.Errorf("NewRunnerServiceMonitor returned unexpected endpoints, diff: %s", diff)
+	}
+
+	k6.Spec.Output.ScrapeMetricsPath = "/prometheus"
+	endpoints, _, _ = unstructured.NestedSlice(NewRunnerServiceMonitor(k6).Object, "spec", "endpoints")
+	expectedEndpoints = []interface{}{map[string]interface{}{"port": "http-api", "path": "/prometheus", "scheme": "http"}}
+	if diff := deep.Equal(expectedEndpoints, endpoints); diff != nil {
+		t.Errorf("NewRunnerServiceMonitor returned unexpected endpoints for a custom path, diff: %s", diff)
+	}
+
+	// the selector matches the services of all runners, also of retries
+	selector, _, _ := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+	service, err := NewRunnerService(k6, 1)
+	if err != nil {
+		t.Fatalf("NewRunnerService errored: %v", err)
+	}
+	if !labels.SelectorFromSet(selector).Matches(labels.Set(service.Labels)) {
+		t.Errorf("NewRunnerServiceMonitor selector %v doesn't match runner service labels %v", selector, service.Labels)
+	}
+	if _, ok := selector["k6_run_id"]; ok {
+		t.Errorf("NewRunnerServiceMonitor selector shouldn't include the run ID: %v", selector)
+	}
+}
+
+func TestNewRunnersService(t *testing.T) {
+	expectedOutcome := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-runners",
+			Namespace: "test",
+			Labels: map[string]string{
+				"app":       "k6",
+				"k6_cr":     "test",
+				"k6_cr_uid": "test-uid",
+				"label1":    "awesome",
+			},
+			Annotations: map[string]string{}},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Ports: []corev1.ServicePort{{
+				Name:     "http-api",
+				Port:     6565,
+				Protocol: "TCP",
+			}},
+			Selector: map[string]string{
+				"app":       "k6",
+				"k6_cr":     "test",
+				"k6_cr_uid": "test-uid",
+				"runner":    "true",
+			},
+		},
+	}
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+			UID:       "test-uid",
+		},
+		Spec: v1alpha1.K6Spec{
+			Parallelism: 2,
+			Script: v1alpha1.K6Script{
+				ConfigMap: v1alpha1.K6Configmap{Name: "test", File: "test.js"},
+			},
+			Runner: v1alpha1.Pod{
+				Metadata: v1alpha1.PodMetadata{
+					Labels: map[string]string{
+						"label1": "awesome",
+					},
+				},
+			},
+		},
+		Status: v1alpha1.K6Status{RunID: "run-1"},
+	}
+
+	service := NewRunnersService(k6)
+	if diff := deep.Equal(service, expectedOutcome); diff != nil {
+		t.Error(diff)
+	}
+
+	// the selector matches all runner pods, also of retries
+	job, err := NewRunnerJob(k6, 2, "")
+	if err != nil {
+		t.Fatalf("NewRunnerJob errored: %v", err)
+	}
+	if !labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(job.Spec.Template.Labels)) {
+		t.Errorf("NewRunnersService selector %v doesn't match runner pod labels %v", service.Spec.Selector, job.Spec.Template.Labels)
+	}
+}
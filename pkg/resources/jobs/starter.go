@@ -13,10 +13,9 @@ import (
 
 // NewStarterJob builds a template used for creating a starter job
 func NewStarterJob(k6 *v1alpha1.K6, hostname []string) *batchv1.Job {
-
-	starterAnnotations := make(map[string]string)
-	if k6.Spec.Starter.Metadata.Annotations != nil {
-		starterAnnotations = k6.Spec.Starter.Metadata.Annotations
+	urls := make([]string, len(hostname))
+	for i := range hostname {
+		urls[i] = k6.Spec.RestAPIURL(hostname[i])
 	}
 
 	starterImage := "ghcr.io/grafana/operator:latest-starter"
@@ -24,14 +23,7 @@ func NewStarterJob(k6 *v1alpha1.K6, hostname []string) *batchv1.Job {
 		starterImage = k6.Spec.Starter.Image
 	}
 
-	starterLabels := newLabels(k6.Name)
-	if k6.Spec.Starter.Metadata.Labels != nil {
-		for k, v := range k6.Spec.Starter.Metadata.Labels { // Order not specified
-			if _, ok := starterLabels[k]; !ok {
-				starterLabels[k] = v
-			}
-		}
-	}
+	starterLabels, starterAnnotations := newMetadata(k6, NewLabels(k6), &k6.Spec.Starter)
 	serviceAccountName := "default"
 	if k6.Spec.Starter.ServiceAccountName != "" {
 		serviceAccountName = k6.Spec.Starter.ServiceAccountName
@@ -43,6 +35,10 @@ func NewStarterJob(k6 *v1alpha1.K6, hostname []string) *batchv1.Job {
 
 	command, istioEnabled := newIstioCommand(k6.Spec.Scuttle.Enabled, []string{"sh", "-c"})
 	env := newIstioEnvVar(k6.Spec.Scuttle, istioEnabled)
+	nodeSelector, tolerations, affinity := newScheduling(k6, &k6.Spec.Starter)
+	starter := containers.NewCurlContainer(urls, k6.Spec.RestAPIInsecureSkipVerify(), starterImage, k6.Spec.Starter.ImagePullPolicy, command, env)
+	// the starter only runs curl, unlike k6 which may write files
+	starter.SecurityContext = containerSecurityContext(&k6.Spec.Starter, true)
 	return &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        fmt.Sprintf("%s-starter", k6.Name),
@@ -51,6 +47,8 @@ func NewStarterJob(k6 *v1alpha1.K6, hostname []string) *batchv1.Job {
 			Annotations: starterAnnotations,
 		},
 		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds:   orDefault64(k6.Spec.Starter.ActiveDeadlineSeconds, starterActiveDeadlineSeconds),
+			TTLSecondsAfterFinished: orDefault32(k6.Spec.Starter.TTLSecondsAfterFinished, starterTTLSecondsAfterFinished),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels:      starterLabels,
@@ -59,15 +57,13 @@ func NewStarterJob(k6 *v1alpha1.K6, hostname []string) *batchv1.Job {
 				Spec: corev1.PodSpec{
 					AutomountServiceAccountToken: &automountServiceAccountToken,
 					ServiceAccountName:           serviceAccountName,
-					Affinity:                     k6.Spec.Starter.Affinity,
-					NodeSelector:                 k6.Spec.Starter.NodeSelector,
-					Tolerations:                  k6.Spec.Starter.Tolerations,
+					Affinity:                     affinity,
+					NodeSelector:                 nodeSelector,
+					Tolerations:                  tolerations,
 					RestartPolicy:                corev1.RestartPolicyNever,
-					SecurityContext:              &k6.Spec.Starter.SecurityContext,
-					ImagePullSecrets:             k6.Spec.Starter.ImagePullSecrets,
-					Containers: []corev1.Container{
-						containers.NewCurlContainer(hostname, starterImage, k6.Spec.Starter.ImagePullPolicy, command, env),
-					},
+					SecurityContext:              podSecurityContext(&k6.Spec.Starter),
+					ImagePullSecrets:             newImagePullSecrets(k6, &k6.Spec.Starter),
+					Containers:                   []corev1.Container{starter},
 				},
 			},
 		},
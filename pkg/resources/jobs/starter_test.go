@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"strings"
 	"testing"
 
 	deep "github.com/go-test/deep"
@@ -14,6 +15,8 @@ import (
 func TestNewStarterJob(t *testing.T) {
 
 	automountServiceAccountToken := true
+	activeDeadlineSeconds := int64(300)
+	ttlSecondsAfterFinished := int32(600)
 
 	expectedOutcome := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -29,6 +32,8 @@ func TestNewStarterJob(t *testing.T) {
 			},
 		},
 		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -49,7 +54,7 @@ func TestNewStarterJob(t *testing.T) {
 					RestartPolicy:                corev1.RestartPolicyNever,
 					SecurityContext:              &corev1.PodSecurityContext{},
 					Containers: []corev1.Container{
-						containers.NewCurlContainer([]string{"testing"}, "image", corev1.PullNever, []string{"sh", "-c"},
+						containers.NewCurlContainer([]string{"http://testing:6565"}, false, "image", corev1.PullNever, []string{"sh", "-c"},
 							[]corev1.EnvVar{}),
 					},
 				},
@@ -94,6 +99,8 @@ func TestNewStarterJob(t *testing.T) {
 func TestNewStarterJobIstio(t *testing.T) {
 
 	automountServiceAccountToken := true
+	activeDeadlineSeconds := int64(300)
+	ttlSecondsAfterFinished := int32(600)
 
 	expectedOutcome := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -109,6 +116,8 @@ func TestNewStarterJobIstio(t *testing.T) {
 			},
 		},
 		Spec: batchv1.JobSpec{
+			ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
@@ -129,7 +138,7 @@ func TestNewStarterJobIstio(t *testing.T) {
 					RestartPolicy:                corev1.RestartPolicyNever,
 					SecurityContext:              &corev1.PodSecurityContext{},
 					Containers: []corev1.Container{
-						containers.NewCurlContainer([]string{"testing"}, "image", "", []string{"scuttle", "sh", "-c"}, []corev1.EnvVar{
+						containers.NewCurlContainer([]string{"http://testing:6565"}, false, "image", "", []string{"scuttle", "sh", "-c"}, []corev1.EnvVar{
 							{
 								Name:  "ENVOY_ADMIN_API",
 								Value: "http://127.0.0.1:15000",
@@ -183,3 +192,47 @@ func TestNewStarterJobIstio(t *testing.T) {
 	}
 
 }
+
+func TestNewStarterJobDeadlines(t *testing.T) {
+	activeDeadlineSeconds := int64(60)
+	ttlSecondsAfterFinished := int32(0)
+
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			Starter: v1alpha1.Pod{
+				ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+				TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
+			},
+		},
+	}
+
+	job := NewStarterJob(k6, []string{"testing"})
+	if diff := deep.Equal(job.Spec.ActiveDeadlineSeconds, &activeDeadlineSeconds); diff != nil {
+		t.Errorf("NewStarterJob returned unexpected active deadline, diff: %s", diff)
+	}
+	if diff := deep.Equal(job.Spec.TTLSecondsAfterFinished, &ttlSecondsAfterFinished); diff != nil {
+		t.Errorf("NewStarterJob returned unexpected TTL, diff: %s", diff)
+	}
+}
+
+func TestNewStarterJobRestAPI(t *testing.T) {
+	k6 := &v1alpha1.K6{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "test",
+		},
+		Spec: v1alpha1.K6Spec{
+			RestAPI: &v1alpha1.RestAPI{Scheme: "https", Port: 8443, InsecureSkipVerify: true},
+		},
+	}
+
+	job := NewStarterJob(k6, []string{"10.96.0.1"})
+	command := job.Spec.Template.Spec.Containers[0].Command
+	if !strings.Contains(command[len(command)-1], "curl --retry 3 --insecure -X PATCH -H 'Content-Type: application/json' https://10.96.0.1:8443/v1/status") {
+		t.Errorf("NewStarterJob should start the runner over its REST API, got: %v", command)
+	}
+}
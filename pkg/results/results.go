@@ -0,0 +1,169 @@
+package results
+
+import (
+	"fmt"
+
+	"go.k6.io/k6/errext/exitcodes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FailureClass distinguishes failures of the test itself from failures of
+// the infrastructure the test was running on.
+type FailureClass string
+
+const (
+	// NoFailure means the runner finished successfully or is not finished yet.
+	NoFailure FailureClass = ""
+	// TestFailure means k6 itself reported a failure, e.g. breached
+	// thresholds or an exception in the script. This is a real test result.
+	TestFailure FailureClass = "TestFailure"
+	// InfraFailure means the runner didn't get to finish the test, e.g. it was
+	// evicted, OOM killed or its node went away.
+	InfraFailure FailureClass = "InfraFailure"
+)
+
+// genericErrorExitCode is used by k6 for errors without a specific exit code.
+const genericErrorExitCode = 255
+
+// RunnerResult is an outcome of a single runner pod.
+type RunnerResult struct {
+	Finished bool
+	ExitCode int32
+	Class    FailureClass
+	Reason   string
+}
+
+// isK6ExitCode returns true if the exit code is one that k6 would set on its own.
+func isK6ExitCode(code int32) bool {
+	switch exitcodes.ExitCode(code) {
+	case exitcodes.CloudTestRunFailed,
+		exitcodes.CloudFailedToGetProgress,
+		exitcodes.ThresholdsHaveFailed,
+		exitcodes.SetupTimeout,
+		exitcodes.TeardownTimeout,
+		exitcodes.GenericTimeout,
+		exitcodes.ScriptStoppedFromRESTAPI,
+		exitcodes.InvalidConfig,
+		exitcodes.ExternalAbort,
+		exitcodes.CannotStartRESTAPI,
+		exitcodes.ScriptException,
+		exitcodes.ScriptAborted:
+		return true
+	}
+	return code == genericErrorExitCode
+}
+
+// ClassifyExitCode maps the exit code of k6 container to a failure class.
+func ClassifyExitCode(code int32) FailureClass {
+	switch {
+	case code == 0:
+		return NoFailure
+	case isK6ExitCode(code):
+		return TestFailure
+	default:
+		// most likely a signal, like SIGKILL on OOM
+		return InfraFailure
+	}
+}
+
+// FromPod inspects the termination state of the k6 container in the runner pod.
+func FromPod(pod *corev1.Pod) RunnerResult {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != "k6" || status.State.Terminated == nil {
+			continue
+		}
+
+		terminated := status.State.Terminated
+		result := RunnerResult{
+			Finished: true,
+			ExitCode: terminated.ExitCode,
+			Class:    ClassifyExitCode(terminated.ExitCode),
+			Reason:   terminated.Reason,
+		}
+		if result.Class == InfraFailure && terminated.Reason == "" {
+			result.Reason = fmt.Sprintf("k6 terminated with exit code %d", terminated.ExitCode)
+		}
+		return result
+	}
+
+	// The pod failed without k6 container being terminated, e.g. on eviction.
+	if pod.Status.Phase == corev1.PodFailed {
+		reason := pod.Status.Reason
+		if reason == "" {
+			reason = "PodFailed"
+		}
+		return RunnerResult{
+			Finished: true,
+			Class:    InfraFailure,
+			Reason:   reason,
+		}
+	}
+
+	return RunnerResult{}
+}
+
+// exitReasons describe the exit codes k6 sets on its own.
+var exitReasons = map[exitcodes.ExitCode]string{
+	exitcodes.CloudTestRunFailed:             "cloud test run has failed",
+	exitcodes.CloudFailedToGetProgress:       "could not get progress of cloud test run",
+	exitcodes.ThresholdsHaveFailed:           "thresholds have failed",
+	exitcodes.SetupTimeout:                   "setup has timed out",
+	exitcodes.TeardownTimeout:                "teardown has timed out",
+	exitcodes.GenericTimeout:                 "test has timed out",
+	exitcodes.ScriptStoppedFromRESTAPI:       "test was stopped via REST API",
+	exitcodes.InvalidConfig:                  "invalid configuration",
+	exitcodes.ExternalAbort:                  "test was aborted",
+	exitcodes.CannotStartRESTAPI:             "could not start REST API",
+	exitcodes.ScriptException:                "script has thrown an exception",
+	exitcodes.ScriptAborted:                  "script was aborted",
+	exitcodes.ExitCode(genericErrorExitCode): "k6 has failed",
+}
+
+// severity orders the results of runners: infrastructure failures are worse
+// than failures of the test which are worse than breached thresholds. Results
+// of the same class are ordered by exit code.
+func severity(result RunnerResult) (int, int32) {
+	switch {
+	case result.Class == InfraFailure:
+		return 3, result.ExitCode
+	case result.Class == TestFailure && exitcodes.ExitCode(result.ExitCode) != exitcodes.ThresholdsHaveFailed:
+		return 2, result.ExitCode
+	case result.Class == TestFailure:
+		return 1, result.ExitCode
+	}
+	return 0, result.ExitCode
+}
+
+// ExitCode returns the most severe exit code of k6 across the runners,
+// together with its human-readable description. Runners without k6 exit
+// code, e.g. evicted pods, are skipped. ok is false if there is no exit code.
+func ExitCode(runnerResults []RunnerResult) (code int32, reason string, ok bool) {
+	var worst RunnerResult
+	for _, result := range runnerResults {
+		if !result.Finished || (result.Class == InfraFailure && result.ExitCode == 0) {
+			continue
+		}
+		if !ok {
+			worst, ok = result, true
+			continue
+		}
+		ws, wc := severity(worst)
+		rs, rc := severity(result)
+		if rs > ws || (rs == ws && rc > wc) {
+			worst = result
+		}
+	}
+	if !ok {
+		return 0, "", false
+	}
+
+	switch {
+	case worst.ExitCode == 0:
+		reason = "test has passed"
+	case worst.Class == TestFailure:
+		reason = exitReasons[exitcodes.ExitCode(worst.ExitCode)]
+	default:
+		reason = worst.Reason
+	}
+	return worst.ExitCode, reason, true
+}
@@ -0,0 +1,109 @@
+package results
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func terminatedPod(exitCode int32, reason string) *corev1.Pod {
+	return &corev1.Pod{
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "k6",
+				State: corev1.ContainerState{
+					Terminated: &corev1.ContainerStateTerminated{
+						ExitCode: exitCode,
+						Reason:   reason,
+					},
+				},
+			}},
+		},
+	}
+}
+
+func Test_FromPod(t *testing.T) {
+	tests := []struct {
+		name   string
+		pod    *corev1.Pod
+		result RunnerResult
+	}{
+		{
+			"Running",
+			&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			RunnerResult{},
+		},
+		{
+			"Succeeded",
+			terminatedPod(0, "Completed"),
+			RunnerResult{Finished: true, ExitCode: 0, Class: NoFailure, Reason: "Completed"},
+		},
+		{
+			"ThresholdsHaveFailed",
+			terminatedPod(99, "Error"),
+			RunnerResult{Finished: true, ExitCode: 99, Class: TestFailure, Reason: "Error"},
+		},
+		{
+			"ScriptException",
+			terminatedPod(107, "Error"),
+			RunnerResult{Finished: true, ExitCode: 107, Class: TestFailure, Reason: "Error"},
+		},
+		{
+			"OOMKilled",
+			terminatedPod(137, "OOMKilled"),
+			RunnerResult{Finished: true, ExitCode: 137, Class: InfraFailure, Reason: "OOMKilled"},
+		},
+		{
+			"Signal",
+			terminatedPod(143, ""),
+			RunnerResult{Finished: true, ExitCode: 143, Class: InfraFailure, Reason: "k6 terminated with exit code 143"},
+		},
+		{
+			"Evicted",
+			&corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}},
+			RunnerResult{Finished: true, Class: InfraFailure, Reason: "Evicted"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.result, FromPod(test.pod))
+		})
+	}
+}
+
+func Test_ExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		pods   []*corev1.Pod
+		code   int32
+		reason string
+		ok     bool
+	}{
+		{"NotFinished", []*corev1.Pod{{Status: corev1.PodStatus{Phase: corev1.PodRunning}}}, 0, "", false},
+		{"Passed", []*corev1.Pod{terminatedPod(0, "Completed"), terminatedPod(0, "Completed")}, 0, "test has passed", true},
+		{"Thresholds", []*corev1.Pod{terminatedPod(0, "Completed"), terminatedPod(99, "Error")}, 99, "thresholds have failed", true},
+		{"ScriptErrorOverThresholds", []*corev1.Pod{terminatedPod(99, "Error"), terminatedPod(104, "Error")}, 104, "invalid configuration", true},
+		{"HighestOfSameClass", []*corev1.Pod{terminatedPod(108, "Error"), terminatedPod(107, "Error")}, 108, "script was aborted", true},
+		{"InfraOverTest", []*corev1.Pod{terminatedPod(107, "Error"), terminatedPod(137, "OOMKilled"), terminatedPod(99, "Error")}, 137, "OOMKilled", true},
+		{
+			"EvictedIsSkipped",
+			[]*corev1.Pod{{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}}, terminatedPod(99, "Error")},
+			99, "thresholds have failed", true,
+		},
+	}
+
+	for _, test := range tests {
+		var runnerResults []RunnerResult
+		for _, pod := range test.pods {
+			runnerResults = append(runnerResults, FromPod(pod))
+		}
+		code, reason, ok := ExitCode(runnerResults)
+		assert.Equal(t, test.code, code, test.name)
+		assert.Equal(t, test.reason, reason, test.name)
+		assert.Equal(t, test.ok, ok, test.name)
+	}
+}
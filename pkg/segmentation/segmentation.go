@@ -13,31 +13,56 @@ const (
 
 // NewCommandFragments builds command fragments for starting k6 with execution segments.
 func NewCommandFragments(index int, total int) ([]string, error) {
+	weights := make([]int32, total)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return NewWeightedCommandFragments(index, total, weights)
+}
 
-	if index > total {
+// NewWeightedCommandFragments builds command fragments for starting k6 with
+// execution segments proportional to the weights of runners, e.g. weights
+// 2, 1, 1 give the first runner half of the test. There must be a weight
+// for each of the parallel runners.
+func NewWeightedCommandFragments(index int, parallelism int, weights []int32) ([]string, error) {
+	total := len(weights)
+	if total != parallelism {
+		return nil, fmt.Errorf("expected %d weights of execution segments for the parallelism, got %d", parallelism, total)
+	}
+	if index < 1 || index > total {
 		return nil, errors.New("node index exceeds configured parallelism")
 	}
 
-	parts := []string{beginning}
-
-	for i := 1; i < total; i++ {
-		parts = append(parts, fmt.Sprintf("%d/%d", i, total))
+	var sum int32
+	for _, weight := range weights {
+		if weight < 1 {
+			return nil, fmt.Errorf("weights of execution segments must be positive, got %v", weights)
+		}
+		sum += weight
 	}
 
-	parts = append(parts, end)
+	boundaries := []int32{0}
+	for _, weight := range weights {
+		boundaries = append(boundaries, boundaries[len(boundaries)-1]+weight)
+	}
 
-	getSegmentPart := func(index int, total int) string {
-		if index == 0 {
-			return "0"
+	getSegmentPart := func(boundary int32) string {
+		if boundary == 0 {
+			return beginning
 		}
-		if index == total {
-			return "1"
+		if boundary == sum {
+			return end
 		}
-		return fmt.Sprintf("%d/%d", index, total)
+		return fmt.Sprintf("%d/%d", boundary, sum)
+	}
+
+	parts := make([]string, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		parts = append(parts, getSegmentPart(boundary))
 	}
 
-	segment := fmt.Sprintf("%s:%s", getSegmentPart(index-1, total), getSegmentPart(index, total))
-	sequence := strings.Join(parts[:], ",")
+	segment := fmt.Sprintf("%s:%s", parts[index-1], parts[index])
+	sequence := strings.Join(parts, ",")
 
 	return []string{
 		fmt.Sprintf("--execution-segment=%s", segment),
@@ -27,4 +27,51 @@ var _ = Describe("the execution segmentation string generator", func() {
 			}))
 		})
 	})
+
+	When("given weights of runners", func() {
+		It("should split the test in proportion to the weights", func() {
+			weights := []int32{2, 1, 1}
+			var segments []string
+			for index := 1; index <= len(weights); index++ {
+				output, err := segmentation.NewWeightedCommandFragments(index, len(weights), weights)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output[1]).To(Equal("--execution-segment-sequence=0,2/4,3/4,1"))
+				segments = append(segments, output[0])
+			}
+			Expect(segments).To(Equal([]string{
+				"--execution-segment=0:2/4",
+				"--execution-segment=2/4:3/4",
+				"--execution-segment=3/4:1",
+			}))
+		})
+
+		It("should give the same segments as equal split for equal weights", func() {
+			weighted, err := segmentation.NewWeightedCommandFragments(2, 3, []int32{3, 3, 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(weighted).To(Equal([]string{
+				"--execution-segment=3/9:6/9",
+				"--execution-segment-sequence=0,3/9,6/9,1",
+			}))
+
+			output, err := segmentation.NewWeightedCommandFragments(2, 3, []int32{1, 1, 1})
+			Expect(err).NotTo(HaveOccurred())
+			equal, err := segmentation.NewCommandFragments(2, 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal(equal))
+		})
+
+		It("should reject invalid weights and indexes", func() {
+			_, err := segmentation.NewWeightedCommandFragments(1, 2, []int32{1, 0})
+			Expect(err).To(HaveOccurred())
+			_, err = segmentation.NewWeightedCommandFragments(3, 2, []int32{1, 1})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject weights not matching the parallelism", func() {
+			_, err := segmentation.NewWeightedCommandFragments(1, 3, []int32{2, 1})
+			Expect(err).To(HaveOccurred())
+			_, err = segmentation.NewWeightedCommandFragments(1, 2, []int32{1, 1, 1})
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -46,6 +46,24 @@ func ParseScript(spec *v1alpha1.K6Spec) (*Script, error) {
 		return s, nil
 	}
 
+	if spec.Script.ArchiveDownload != nil && spec.Script.ArchiveDownload.URI != "" {
+		isS3 := strings.HasPrefix(spec.Script.ArchiveDownload.URI, "s3://")
+		switch spec.Script.ArchiveDownload.Mode {
+		case v1alpha1.ArchiveDownloadSDK:
+			if !isS3 {
+				return nil, fmt.Errorf("ArchiveDownload in sdk mode requires an s3:// URI, got %s", spec.Script.ArchiveDownload.URI)
+			}
+		case v1alpha1.ArchiveDownloadCurl:
+			if isS3 {
+				return nil, fmt.Errorf("ArchiveDownload in curl mode requires a URL, got %s", spec.Script.ArchiveDownload.URI)
+			}
+		}
+		s.Name = spec.Script.ArchiveDownload.URI
+		s.Filename = "archive.tar"
+		s.Type = "ArchiveDownload"
+		return s, nil
+	}
+
 	if spec.Script.LocalFile != "" {
 		s.Name = "LocalFile"
 		s.Type = "LocalFile"
@@ -53,13 +71,34 @@ func ParseScript(spec *v1alpha1.K6Spec) (*Script, error) {
 		return s, nil
 	}
 
-	return nil, errors.New("Script definition should contain one of: ConfigMap, VolumeClaim, LocalFile")
+	return nil, errors.New("Script definition should contain one of: ConfigMap, VolumeClaim, LocalFile, ArchiveDownload")
 }
 
 func (s *Script) FullName() string {
 	return s.Path + s.Filename
 }
 
+// VerificationFailed starts the termination message of the initializer when
+// the script or the required files are missing.
+const VerificationFailed = "script verification failed"
+
+// VerifyCommand returns a shell command checking that the script itself and
+// the required files exist. Otherwise, the command fails with a message in the
+// termination log and lists what is there instead.
+func (s *Script) VerifyCommand(requiredFiles []string) string {
+	files := []string{fmt.Sprintf("'%s'", s.FullName())}
+	for _, file := range requiredFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(s.FullName()), file)
+		}
+		files = append(files, fmt.Sprintf("'%s'", file))
+	}
+
+	return fmt.Sprintf(
+		`for f in %s; do if [ ! -f "$f" ]; then echo "%s: $f not found" > /dev/termination-log ; ls -l "$(dirname "$f")" ; exit 1; fi; done ; `,
+		strings.Join(files, " "), VerificationFailed)
+}
+
 // Volume creates a Volume spec for the script
 func (s *Script) Volume() []corev1.Volume {
 	switch s.Type {
@@ -88,6 +127,16 @@ func (s *Script) Volume() []corev1.Volume {
 				},
 			},
 		}
+	case "ArchiveDownload":
+		// the archive is downloaded by an init container
+		return []corev1.Volume{
+			corev1.Volume{
+				Name: "k6-test-volume",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+		}
 	default:
 		return []corev1.Volume{}
 	}
@@ -125,6 +174,19 @@ type CLI struct {
 	ArchiveArgs string
 	// k6-operator doesn't care for most values of CLI arguments to k6, with an exception of cloud output
 	HasCloudOut bool
+	// Outputs are the names of outputs enabled with -o or --out, without
+	// their configuration, e.g. influxdb for --out influxdb=http://localhost:8086/k6.
+	Outputs []string
+}
+
+// HasOutput returns true if the output is enabled in arguments.
+func (cli *CLI) HasOutput(name string) bool {
+	for _, output := range cli.Outputs {
+		if output == name {
+			return true
+		}
+	}
+	return false
 }
 
 func ParseCLI(spec *v1alpha1.K6Spec) *CLI {
@@ -155,10 +217,21 @@ func ParseCLI(spec *v1alpha1.K6Spec) *CLI {
 		if args[i][0] == '-' {
 			end := lastArgV(i+1, args)
 
-			switch args[i] {
+			// the value of the flag may be passed as -o=cloud too
+			flag, value, hasValue := strings.Cut(args[i], "=")
+			switch flag {
 			case "-o", "--out":
-				for j := 0; j < end; j++ {
-					if args[j] == "cloud" {
+				values := args[i+1 : end]
+				if hasValue {
+					values = append([]string{value}, values...)
+				}
+				for _, value := range values {
+					if len(value) == 0 {
+						continue
+					}
+					name, _, _ := strings.Cut(value, "=")
+					cli.Outputs = append(cli.Outputs, name)
+					if name == "cloud" {
 						cli.HasCloudOut = true
 					}
 				}
@@ -176,6 +249,9 @@ func ParseCLI(spec *v1alpha1.K6Spec) *CLI {
 				cli.ArchiveArgs += strings.Join(args[i:end], " ")
 			}
 			i = end
+		} else {
+			// a value without a flag, which k6 doesn't take either
+			i++
 		}
 	}
 
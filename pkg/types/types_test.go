@@ -52,6 +52,7 @@ func Test_ParseCLI(t *testing.T) {
 			CLI{
 				ArchiveArgs: "--vus 10",
 				HasCloudOut: false,
+				Outputs:     []string{"json", "csv"},
 			},
 		},
 		{
@@ -60,6 +61,7 @@ func Test_ParseCLI(t *testing.T) {
 			CLI{
 				ArchiveArgs: "--vus 10",
 				HasCloudOut: true,
+				Outputs:     []string{"json", "csv", "cloud"},
 			},
 		},
 		{
@@ -68,6 +70,22 @@ func Test_ParseCLI(t *testing.T) {
 			CLI{
 				ArchiveArgs: "--vus 10",
 				HasCloudOut: true,
+				Outputs:     []string{"json", "csv", "cloud"},
+			},
+		},
+		{
+			"OutWithEquals",
+			"--vus 10 -o=experimental-prometheus-rw --out=json=results.json",
+			CLI{
+				ArchiveArgs: "--vus 10",
+				Outputs:     []string{"experimental-prometheus-rw", "json"},
+			},
+		},
+		{
+			"ValueWithoutFlag",
+			"cloud --vus 10",
+			CLI{
+				ArchiveArgs: "--vus 10",
 			},
 		},
 	}
@@ -83,6 +101,21 @@ func Test_ParseCLI(t *testing.T) {
 
 			assert.Equal(t, test.cli.ArchiveArgs, cli.ArchiveArgs)
 			assert.Equal(t, test.cli.HasCloudOut, cli.HasCloudOut)
+			assert.Equal(t, test.cli.Outputs, cli.Outputs)
 		})
 	}
 }
+
+func Test_VerifyCommand(t *testing.T) {
+	script := &Script{
+		Filename: "tests/main.js",
+		Path:     "/test/",
+	}
+
+	expected := `for f in '/test/tests/main.js' '/test/tests/lib/utils.js' '/data/users.csv'; do ` +
+		`if [ ! -f "$f" ]; then echo "script verification failed: $f not found" > /dev/termination-log ; ls -l "$(dirname "$f")" ; exit 1; fi; done ; `
+
+	if command := script.VerifyCommand([]string{"lib/utils.js", "/data/users.csv"}); command != expected {
+		t.Errorf("VerifyCommand returned unexpected command:\n%s\nexpected:\n%s", command, expected)
+	}
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Versions is written by the initializer into the termination message of
+// its container when the script is a k6 archive.
+type Versions struct {
+	// Archive is the version of k6 that created the archive.
+	Archive string `json:"archive"`
+	// K6 is the version of k6 in the image.
+	K6 string `json:"k6"`
+}
+
+// IsArchive returns true if the script is a k6 archive rather than a JS file.
+func (s *Script) IsArchive() bool {
+	return strings.HasSuffix(s.Filename, ".tar")
+}
+
+// parseVersion parses major, minor and patch numbers from version like
+// v0.45.1 or 0.45.1-rc1. Missing or invalid parts are treated as 0.
+func parseVersion(version string) [3]int {
+	var parsed [3]int
+
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+
+	for i, part := range strings.SplitN(version, ".", 3) {
+		parsed[i], _ = strconv.Atoi(part)
+	}
+	return parsed
+}
+
+// IsNewerVersion returns true if version a is newer than version b.
+// Pre-release and build suffixes are ignored.
+func IsNewerVersion(a, b string) bool {
+	va, vb := parseVersion(a), parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsNewerVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		newer bool
+	}{
+		{"Same", "v0.45.0", "0.45.0", false},
+		{"NewerMinor", "0.50.0", "v0.45.1", true},
+		{"OlderMinor", "v0.45.1", "v0.50.0", false},
+		{"NewerPatch", "v0.45.2", "v0.45.1", true},
+		{"NewerMajor", "v1.0.0", "v0.99.0", true},
+		{"PreRelease", "v0.50.0-rc1", "v0.50.0", false},
+		{"Empty", "", "v0.45.0", false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.newer, IsNewerVersion(test.a, test.b))
+		})
+	}
+}